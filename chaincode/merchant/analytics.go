@@ -0,0 +1,237 @@
+/*
+  Merchant Smart Contract
+  Adrian Pareja
+*/
+package merchant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//Cantidad de clientes por defecto en gettopcustomers
+const defaultTopCustomers = 50
+
+//PeriodTotal - Structure for the canje totals of a day, week or month in a currency
+type PeriodTotal struct {
+	Start         int64   `json:"start"`
+	Currency      string  `json:"currency"`
+	Minor         int64   `json:"minor"`
+	Redeemed      float64 `json:"redeemed"`
+	Earned        float64 `json:"earned"`
+	Redemptions   int     `json:"redemptions"`
+	Earnings      int     `json:"earnings"`
+	AverageRedeem float64 `json:"averageredeem"`
+}
+
+//CustomerTotal - Structure for the canje totals of a customer wallet in a currency.
+//Soles es el monto comprado en unidades de la moneda
+type CustomerTotal struct {
+	Wallet      string  `json:"wallet"`
+	Currency    string  `json:"currency"`
+	Soles       float64 `json:"soles"`
+	Minor       int64   `json:"minor"`
+	Redeemed    float64 `json:"redeemed"`
+	Earned      float64 `json:"earned"`
+	Redemptions int     `json:"redemptions"`
+	Earnings    int     `json:"earnings"`
+}
+
+//bucketStart - Inicio en milisegundos (UTC) del dia, semana (lunes) o mes que contiene al tiempo
+func bucketStart(bucket string, ms int64) (int64, error) {
+	t := time.Unix(0, ms*int64(time.Millisecond)).UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch bucket {
+	case "day":
+	case "week":
+		day = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case "month":
+		day = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return 0, errors.New("Periodo invalido: " + bucket)
+	}
+	return day.UnixNano() / int64(time.Millisecond), nil
+}
+
+//canjesInPeriod - Canjes (C) y acumulaciones (D) del negocio en el periodo [from, to).
+//Las filas sin moneda quedan en la moneda por defecto del comercio
+func canjesInPeriod(stub shim.ChaincodeStubInterface, fromArg string, toArg string) ([]Movement, error) {
+	from, err := strconv.ParseInt(fromArg, 10, 64)
+	if err != nil {
+		return nil, errors.New("Inicio de periodo invalido: " + fromArg)
+	}
+	to, err := strconv.ParseInt(toArg, 10, 64)
+	if err != nil {
+		return nil, errors.New("Fin de periodo invalido: " + toArg)
+	}
+	if to <= from {
+		return nil, errors.New("El fin del periodo debe ser mayor al inicio")
+	}
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	canjes, err := scanCanjes(stub, config.Business)
+	if err != nil {
+		return nil, err
+	}
+
+	movimientos := []Movement{}
+	for _, movimiento := range canjes {
+		if movimiento.Time < from || movimiento.Time >= to {
+			continue
+		}
+		if movimiento.Type != "C" && movimiento.Type != "D" {
+			continue
+		}
+		if movimiento.Currency == "" {
+			movimiento.Currency = config.currency()
+		}
+		movimientos = append(movimientos, movimiento)
+	}
+	return movimientos, nil
+}
+
+//getPeriodTotals - Coins canjeados y entregados por dia, semana o mes y por moneda, sin mezclar montos de
+//monedas distintas. args: day|week|month, desde y hasta en milisegundos
+func (t *SimpleChaincode) getPeriodTotals(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getPeriodTotals() is running----")
+
+	if len(args) != 3 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 3")
+	}
+	if _, err := bucketStart(args[0], 0); err != nil {
+		return nil, err
+	}
+
+	movimientos, err := canjesInPeriod(stub, args[1], args[2])
+	if err != nil {
+		return nil, err
+	}
+
+	totals := map[string]*PeriodTotal{}
+	for _, movimiento := range movimientos {
+		start, _ := bucketStart(args[0], movimiento.Time)
+		key := fmt.Sprintf("%d:%s", start, movimiento.Currency)
+		total, found := totals[key]
+		if !found {
+			total = &PeriodTotal{Start: start, Currency: movimiento.Currency}
+			totals[key] = total
+		}
+		total.Minor = total.Minor + movimiento.Minor
+		if movimiento.Type == "C" {
+			total.Redeemed = total.Redeemed + movimiento.Amount
+			total.Redemptions = total.Redemptions + 1
+		} else if movimiento.Amount > 0 {
+			total.Earned = total.Earned + movimiento.Amount
+			total.Earnings = total.Earnings + 1
+		}
+	}
+
+	result := []PeriodTotal{}
+	for _, total := range totals {
+		if total.Redemptions > 0 {
+			total.AverageRedeem = total.Redeemed / float64(total.Redemptions)
+		}
+		result = append(result, *total)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Start != result[j].Start {
+			return result[i].Start < result[j].Start
+		}
+		return result[i].Currency < result[j].Currency
+	})
+
+	jsonRows, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("getPeriodTotals operation failed. Error marshaling JSON: %s", err)
+	}
+
+	return jsonRows, nil
+}
+
+//getTopCustomers - Clientes del periodo por moneda, ordenados por el monto comprado en esa moneda.
+//args: desde, hasta en milisegundos y opcionalmente la cantidad de clientes por moneda (50 por defecto)
+func (t *SimpleChaincode) getTopCustomers(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getTopCustomers() is running----")
+
+	if len(args) != 2 && len(args) != 3 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 2 o 3")
+	}
+
+	limit := defaultTopCustomers
+	if len(args) == 3 {
+		var err error
+		limit, err = strconv.Atoi(args[2])
+		if err != nil || limit <= 0 {
+			return nil, errors.New("Cantidad de clientes invalida: " + args[2])
+		}
+	}
+
+	movimientos, err := canjesInPeriod(stub, args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	//Los canjes anteriores a la wallet en la fila no se pueden atribuir a un cliente
+	customers := map[string]*CustomerTotal{}
+	for _, movimiento := range movimientos {
+		if movimiento.Wallet == "" {
+			continue
+		}
+		key := movimiento.Currency + ":" + movimiento.Wallet
+		customer, found := customers[key]
+		if !found {
+			customer = &CustomerTotal{Wallet: movimiento.Wallet, Currency: movimiento.Currency}
+			customers[key] = customer
+		}
+		customer.Soles = customer.Soles + movimiento.Soles
+		customer.Minor = customer.Minor + movimiento.Minor
+		if movimiento.Type == "C" {
+			customer.Redeemed = customer.Redeemed + movimiento.Amount
+			customer.Redemptions = customer.Redemptions + 1
+		} else if movimiento.Amount > 0 {
+			customer.Earned = customer.Earned + movimiento.Amount
+			customer.Earnings = customer.Earnings + 1
+		}
+	}
+
+	sorted := []CustomerTotal{}
+	for _, customer := range customers {
+		sorted = append(sorted, *customer)
+	}
+	//Se desempata por wallet para que todos los peers generen el mismo resultado
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Currency != sorted[j].Currency {
+			return sorted[i].Currency < sorted[j].Currency
+		}
+		if sorted[i].Soles != sorted[j].Soles {
+			return sorted[i].Soles > sorted[j].Soles
+		}
+		return sorted[i].Wallet < sorted[j].Wallet
+	})
+
+	result := []CustomerTotal{}
+	ranked := map[string]int{}
+	for _, customer := range sorted {
+		if ranked[customer.Currency] < limit {
+			ranked[customer.Currency] = ranked[customer.Currency] + 1
+			result = append(result, customer)
+		}
+	}
+
+	jsonRows, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("getTopCustomers operation failed. Error marshaling JSON: %s", err)
+	}
+
+	return jsonRows, nil
+}
@@ -0,0 +1,180 @@
+/*
+  Merchant Smart Contract
+  Adrian Pareja
+*/
+package merchant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	columnStore    = "Store"
+	columnTerminal = "Terminal"
+	columnCashier  = "Cashier"
+)
+
+//Attribution - Structure for the store, terminal and cashier of a canje
+type Attribution struct {
+	Store    string `json:"store"`
+	Terminal string `json:"terminal"`
+	Cashier  string `json:"cashier"`
+}
+
+//AttributionTotal - Structure for canje totals grouped by store, terminal or cashier
+type AttributionTotal struct {
+	Key      string  `json:"key"`
+	Redeemed float64 `json:"redeemed"`
+	Earned   float64 `json:"earned"`
+	Count    int     `json:"count"`
+}
+
+//scanCanjes - Obtiene todos los canjes del negocio
+func scanCanjes(stub shim.ChaincodeStubInterface, business string) ([]Movement, error) {
+	var columns []shim.Column
+	col1 := shim.Column{Value: &shim.Column_String_{String_: business}}
+	columns = append(columns, col1)
+
+	rowChannel, err := stub.GetRows(tableColumn, columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRowTableOne operation failed. %s", err)
+	}
+
+	movimientos := []Movement{}
+	for {
+		select {
+		case row, ok := <-rowChannel:
+			if !ok {
+				rowChannel = nil
+			} else {
+				columnas := row.GetColumns()
+				amountRow, _ := strconv.ParseFloat(columnas[2].GetString_(), 64)
+				movimiento := Movement{Time: columnas[1].GetInt64(), WalletId: columnas[0].GetString_(), Amount: amountRow, Type: columnas[3].GetString_()}
+				if len(columnas) > 4 {
+					movimiento.Detail = columnas[4].GetString_()
+				}
+				if len(columnas) > 7 {
+					movimiento.Store = columnas[5].GetString_()
+					movimiento.Terminal = columnas[6].GetString_()
+					movimiento.Cashier = columnas[7].GetString_()
+				}
+				if len(columnas) > 10 {
+					movimiento.Wallet = columnas[8].GetString_()
+					movimiento.Soles, _ = strconv.ParseFloat(columnas[9].GetString_(), 64)
+					movimiento.Reference = columnas[10].GetString_()
+				}
+				if len(columnas) > 12 {
+					movimiento.Currency = columnas[11].GetString_()
+					movimiento.Minor = columnas[12].GetInt64()
+				}
+
+				movimientos = append(movimientos, movimiento)
+			}
+		}
+		if rowChannel == nil {
+			break
+		}
+	}
+
+	return movimientos, nil
+}
+
+//matches - Indica si el canje corresponde al filtro, un campo vacio no filtra
+func (a Attribution) matches(movimiento Movement) bool {
+	return (a.Store == "" || a.Store == movimiento.Store) &&
+		(a.Terminal == "" || a.Terminal == movimiento.Terminal) &&
+		(a.Cashier == "" || a.Cashier == movimiento.Cashier)
+}
+
+//getTotalsBy - Totales de canje y acumulacion por tienda, terminal o cajero.
+//args: store|terminal|cashier y opcionalmente desde y hasta en milisegundos
+func (t *SimpleChaincode) getTotalsBy(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getTotalsBy() is running----")
+
+	if len(args) != 1 && len(args) != 3 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1 o 3")
+	}
+
+	var key func(Movement) string
+	switch args[0] {
+	case "store":
+		key = func(m Movement) string { return m.Store }
+	case "terminal":
+		key = func(m Movement) string { return m.Terminal }
+	case "cashier":
+		key = func(m Movement) string { return m.Cashier }
+	default:
+		return nil, errors.New("Agrupacion invalida: " + args[0])
+	}
+
+	var from, to int64
+	if len(args) == 3 {
+		var err error
+		from, err = strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return nil, errors.New("Inicio de periodo invalido: " + args[1])
+		}
+		to, err = strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return nil, errors.New("Fin de periodo invalido: " + args[2])
+		}
+	}
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	movimientos, err := scanCanjes(stub, config.Business)
+	if err != nil {
+		return nil, err
+	}
+
+	totals := map[string]*AttributionTotal{}
+	for _, movimiento := range movimientos {
+		if len(args) == 3 && (movimiento.Time < from || movimiento.Time >= to) {
+			continue
+		}
+		//Las reposiciones, devoluciones, lotes y premios no son compras del POS
+		if movimiento.Type != "C" && movimiento.Type != "D" {
+			continue
+		}
+		k := key(movimiento)
+		total, found := totals[k]
+		if !found {
+			total = &AttributionTotal{Key: k}
+			totals[k] = total
+		}
+		//C son coins canjeados por el cliente, D coins entregados
+		if movimiento.Type == "C" {
+			total.Redeemed = total.Redeemed + movimiento.Amount
+		} else {
+			total.Earned = total.Earned + movimiento.Amount
+		}
+		total.Count = total.Count + 1
+	}
+
+	var keys []string
+	for k := range totals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := []AttributionTotal{}
+	for _, k := range keys {
+		result = append(result, *totals[k])
+	}
+
+	jsonRows, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("getTotalsBy operation failed. Error marshaling JSON: %s", err)
+	}
+
+	return jsonRows, nil
+}
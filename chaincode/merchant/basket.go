@@ -0,0 +1,286 @@
+/*
+  Merchant Smart Contract
+  Adrian Pareja
+*/
+package merchant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	tableCategory    = "Categorias"
+	columnCategory   = "Category"
+	columnMultiplier = "Multiplier"
+	columnRedeemable = "Redeemable"
+)
+
+//CategoryRule - Structure for the earn and redeem rules of a product category
+type CategoryRule struct {
+	Category   string  `json:"category"`
+	Multiplier float64 `json:"multiplier"`
+	Redeemable bool    `json:"redeemable"`
+}
+
+//Line - Structure for a basket line sent by the POS
+type Line struct {
+	Sku      string  `json:"sku"`
+	Category string  `json:"category"`
+	Quantity float64 `json:"quantity"`
+	Price    float64 `json:"price"`
+}
+
+//Basket - Structure for a basket with currency. Los precios de las lineas van en unidades menores
+type Basket struct {
+	Currency string `json:"currency"`
+	Lines    []Line `json:"lines"`
+}
+
+//LineResult - Structure for the computed breakdown of a basket line.
+//Earned es antes del bonus del tier del cliente
+type LineResult struct {
+	Sku        string  `json:"sku"`
+	Category   string  `json:"category"`
+	Quantity   float64 `json:"quantity"`
+	Price      float64 `json:"price"`
+	Subtotal   float64 `json:"subtotal"`
+	Coins      float64 `json:"coins"`
+	Multiplier float64 `json:"multiplier"`
+	Redeemable bool    `json:"redeemable"`
+	Redeemed   float64 `json:"redeemed"`
+	Earned     float64 `json:"earned"`
+}
+
+//maxAmount - Monto maximo de una compra, y de los coins que canjea, en unidades de la moneda. Acota los montos
+//antes de pasarlos a unidades menores
+const maxAmount float64 = 1e9
+
+//defaultRule - Regla para categorias sin configurar
+func defaultRule(category string) CategoryRule {
+	return CategoryRule{Category: category, Multiplier: 1, Redeemable: true}
+}
+
+//parseBasket - Acepta un monto, un monto con moneda en unidades menores (BOB:1050), una canasta JSON de lineas
+//o una canasta con moneda {"currency":"BOB","lines":[...]}. Los precios de las canastas van siempre en unidades
+//menores. Los montos y canastas sin moneda estan en la moneda por defecto del comercio.
+//Devuelve las lineas con precios en unidades de la moneda
+func parseBasket(arg string, currency string) ([]Line, string, error) {
+	soles, err := strconv.ParseFloat(arg, 64)
+	if err == nil {
+		if !(soles > 0) || math.IsInf(soles, 0) || soles > maxAmount {
+			return nil, "", errors.New("Monto invalido: " + arg)
+		}
+		return []Line{{Quantity: 1, Price: soles}}, currency, nil
+	}
+
+	if !strings.HasPrefix(strings.TrimSpace(arg), "[") && !strings.HasPrefix(strings.TrimSpace(arg), "{") {
+		money, err := parseMoney(arg)
+		if err != nil {
+			return nil, "", err
+		}
+		return []Line{{Quantity: 1, Price: money.Major()}}, money.Currency, nil
+	}
+
+	basket := Basket{Currency: currency}
+	if strings.HasPrefix(strings.TrimSpace(arg), "{") {
+		err = json.Unmarshal([]byte(arg), &basket)
+		if err != nil {
+			return nil, "", fmt.Errorf("Canasta invalida. %s", err)
+		}
+		basket.Currency = strings.ToUpper(basket.Currency)
+		err = checkCurrency(basket.Currency)
+		if err != nil {
+			return nil, "", err
+		}
+	} else {
+		err = json.Unmarshal([]byte(arg), &basket.Lines)
+		if err != nil {
+			return nil, "", fmt.Errorf("Canasta invalida. %s", err)
+		}
+	}
+	if len(basket.Lines) == 0 {
+		return nil, "", errors.New("La canasta esta vacia")
+	}
+
+	lines := []Line{}
+	for _, line := range basket.Lines {
+		if !(line.Quantity > 0) || !(line.Price >= 0) || math.IsInf(line.Quantity, 0) || math.IsInf(line.Price, 0) {
+			return nil, "", errors.New("Cantidad o precio invalido en el sku " + line.Sku)
+		}
+		if line.Price != math.Trunc(line.Price) {
+			return nil, "", errors.New("El precio debe estar en unidades menores en el sku " + line.Sku)
+		}
+		if line.Quantity > maxAmount || line.Price > maxAmount*minorFactor(basket.Currency) {
+			return nil, "", errors.New("Cantidad o precio invalido en el sku " + line.Sku)
+		}
+		line.Price = Money{Currency: basket.Currency, Minor: int64(line.Price)}.Major()
+		lines = append(lines, line)
+	}
+	return lines, basket.Currency, nil
+}
+
+//getCategoryRule - Obtiene la regla de una categoria o la regla por defecto
+func getCategoryRule(stub shim.ChaincodeStubInterface, category string) (CategoryRule, error) {
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Category"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: category}}
+	columns = append(columns, col0)
+	columns = append(columns, col1)
+
+	row, err := stub.GetRow(tableCategory, columns)
+	if err != nil {
+		return CategoryRule{}, fmt.Errorf("getRow Categorias operation failed. %s", err)
+	}
+	if len(row.Columns) == 0 {
+		return defaultRule(category), nil
+	}
+
+	return categoryRuleFromRow(row), nil
+}
+
+func categoryRuleFromRow(row shim.Row) CategoryRule {
+	columnas := row.GetColumns()
+	multiplier, _ := strconv.ParseFloat(columnas[2].GetString_(), 64)
+	return CategoryRule{Category: columnas[1].GetString_(), Multiplier: multiplier, Redeemable: columnas[3].GetBool()}
+}
+
+//priceBasket - Calcula por linea el valor en coins, el canje y la acumulacion.
+//El canje se asigna en el orden de la canasta a las lineas canjeables
+func priceBasket(stub shim.ChaincodeStubInterface, lines []Line, change float64, coins float64) ([]LineResult, error) {
+	rules := map[string]CategoryRule{}
+	results := []LineResult{}
+	var redeemable float64
+	for _, line := range lines {
+		rule, found := rules[line.Category]
+		if !found {
+			var err error
+			rule, err = getCategoryRule(stub, line.Category)
+			if err != nil {
+				return nil, err
+			}
+			rules[line.Category] = rule
+		}
+
+		subtotal := line.Quantity * line.Price
+		result := LineResult{
+			Sku:        line.Sku,
+			Category:   line.Category,
+			Quantity:   line.Quantity,
+			Price:      line.Price,
+			Subtotal:   subtotal,
+			Coins:      subtotal * change,
+			Multiplier: rule.Multiplier,
+			Redeemable: rule.Redeemable,
+		}
+		if rule.Redeemable {
+			redeemable = redeemable + result.Coins
+		}
+		results = append(results, result)
+	}
+
+	if coins > redeemable {
+		return nil, fmt.Errorf("Los coins a canjear exceden el monto canjeable de la compra (%s)", strconv.FormatFloat(redeemable, 'f', 6, 64))
+	}
+
+	pending := coins
+	for i := range results {
+		if results[i].Redeemable && pending > 0 {
+			results[i].Redeemed = results[i].Coins
+			if pending < results[i].Coins {
+				results[i].Redeemed = pending
+			}
+			pending = pending - results[i].Redeemed
+		}
+		results[i].Earned = (results[i].Coins - results[i].Redeemed) * results[i].Multiplier
+	}
+
+	return results, nil
+}
+
+//setCategoryRule - Configura la regla de una categoria: categoria, multiplicador y si es canjeable
+func (t *SimpleChaincode) setCategoryRule(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----setCategoryRule() is running----")
+
+	if len(args) != 3 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 3")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede configurar categorias")
+	}
+
+	multiplier, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || multiplier < 0 {
+		return nil, errors.New("Multiplicador invalido: " + args[1])
+	}
+	redeemable, err := strconv.ParseBool(args[2])
+	if err != nil {
+		return nil, errors.New("Valor canjeable invalido: " + args[2])
+	}
+
+	var columns []*shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Category"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: args[0]}}
+	col2 := shim.Column{Value: &shim.Column_String_{String_: strconv.FormatFloat(multiplier, 'f', 6, 64)}}
+	col3 := shim.Column{Value: &shim.Column_Bool{Bool: redeemable}}
+	columns = append(columns, &col0)
+	columns = append(columns, &col1)
+	columns = append(columns, &col2)
+	columns = append(columns, &col3)
+
+	row := shim.Row{Columns: columns}
+	ok, err := stub.ReplaceRow(tableCategory, row)
+	if err != nil {
+		return nil, fmt.Errorf("Replace Row Categorias operation failed. %s", err)
+	}
+	if !ok {
+		ok, err = stub.InsertRow(tableCategory, row)
+		if err != nil || !ok {
+			return nil, errors.New("Fallo guardar la regla de la categoria")
+		}
+	}
+
+	return []byte(`{"code":0,"response":null}`), nil
+}
+
+//getCategoryRules - Obtiene las reglas de categorias del comercio
+func (t *SimpleChaincode) getCategoryRules(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getCategoryRules() is running----")
+
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Category"}}
+	columns = append(columns, col0)
+
+	rowChannel, err := stub.GetRows(tableCategory, columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRows Categorias operation failed. %s", err)
+	}
+
+	rules := []CategoryRule{}
+	for {
+		select {
+		case row, ok := <-rowChannel:
+			if !ok {
+				rowChannel = nil
+			} else {
+				rules = append(rules, categoryRuleFromRow(row))
+			}
+		}
+		if rowChannel == nil {
+			break
+		}
+	}
+
+	jsonRows, err := json.Marshal(rules)
+	if err != nil {
+		return nil, fmt.Errorf("getRows Categorias operation failed. Error marshaling JSON: %s", err)
+	}
+
+	return jsonRows, nil
+}
@@ -0,0 +1,83 @@
+/*
+  Merchant Smart Contract
+  Adrian Pareja
+*/
+package merchant
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+//Moneda de los montos sin codigo, como los buy anteriores en soles
+const defaultCurrency = "PEN"
+
+//Decimales de las monedas aceptadas segun ISO 4217
+var minorDigits = map[string]int{
+	"PEN": 2,
+	"BOB": 2,
+	"USD": 2,
+}
+
+//Money - Structure for an ISO 4217 currency amount in integer minor units
+type Money struct {
+	Currency string `json:"currency"`
+	Minor    int64  `json:"minor"`
+}
+
+//checkCurrency - Valida que la moneda sea un codigo ISO 4217 aceptado
+func checkCurrency(currency string) error {
+	if _, found := minorDigits[currency]; !found {
+		return errors.New("Moneda no soportada: " + currency)
+	}
+	return nil
+}
+
+//minorFactor - Unidades menores por unidad de la moneda, 100 para PEN
+func minorFactor(currency string) float64 {
+	return math.Pow10(minorDigits[currency])
+}
+
+//parseMoney - Lee un monto con moneda en unidades menores, por ejemplo BOB:1050 son 10.50 bolivianos
+func parseMoney(arg string) (Money, error) {
+	parts := strings.Split(arg, ":")
+	if len(parts) != 2 {
+		return Money{}, errors.New("Monto invalido: " + arg)
+	}
+	currency := strings.ToUpper(parts[0])
+	err := checkCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	minor, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || minor < 0 {
+		return Money{}, errors.New("Monto invalido: " + arg)
+	}
+	return Money{Currency: currency, Minor: minor}, nil
+}
+
+//toMinor - Convierte un monto en unidades de la moneda a unidades menores redondeando. Falla si el monto no es
+//finito o no entra en int64
+func toMinor(currency string, amount float64) (int64, error) {
+	minor := math.Floor(amount*minorFactor(currency) + 0.5)
+	//-2^63 es exacto en float64 y 2^63 ya no entra en int64
+	if math.IsNaN(minor) || minor < math.MinInt64 || minor >= math.MaxInt64 {
+		return 0, errors.New("Monto invalido en unidades menores: " + strconv.FormatFloat(amount, 'g', -1, 64) + " " + currency)
+	}
+	return int64(minor), nil
+}
+
+//currency - Moneda por defecto del comercio, PEN para contratos anteriores a las monedas
+func (c *Config) currency() string {
+	if c.Currency == "" {
+		return defaultCurrency
+	}
+	return c.Currency
+}
+
+//Major - Monto en unidades de la moneda
+func (m Money) Major() float64 {
+	return float64(m.Minor) / minorFactor(m.Currency)
+}
@@ -0,0 +1,1135 @@
+/*
+  Merchant Smart Contract
+  Adrian Pareja
+*/
+
+//Package merchant es el chaincode de un comercio: tasas, compras, canjes y recompensas contra el wallet.
+//El directorio merchant lo despliega con shim.Start
+package merchant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/util"
+
+	"crypto/rand"
+	"encoding/hex"
+)
+
+//Clave del estado con la configuracion del comercio recibida en Init
+const configKey string = "config"
+
+const (
+	tableColumn     = "Canjes"
+	columnTime      = "Time"
+	columnAccountID = "Account"
+	columnAmount    = "Amount"
+	columnType      = "Type"
+	columnDetail    = "Detail"
+	columnWallet    = "Wallet"
+	columnSoles     = "Soles"
+	columnReference = "Reference"
+	columnMinor     = "Minor"
+)
+
+// UUID layout variants.
+const (
+	VariantNCS = iota
+	VariantRFC4122
+	VariantMicrosoft
+	VariantFuture
+)
+
+// Used in string method conversion
+const dash byte = '-'
+
+// UUID representation compliant with specification
+// described in RFC 4122.
+type UUID [16]byte
+
+//Config - Structure for the merchant configuration
+type Config struct {
+	Business       string          `json:"business"`
+	MerchantId     string          `json:"merchantid"`
+	WalletContract string          `json:"walletcontract"`
+	LowWater       float64         `json:"lowwater"`
+	Target         float64         `json:"target"`
+	Currency       string          `json:"currency"`
+	DailyCap       float64         `json:"dailycap"`
+	Rules          RedemptionRules `json:"rules"`
+}
+
+//BuyResult - Structure for the buy response with the basket breakdown
+type BuyResult struct {
+	Code     int32        `json:"code"`
+	Balance  string       `json:"balance"`
+	Limit    string       `json:"limit"`
+	Tier     string       `json:"tier"`
+	Redeemed string       `json:"redeemed"`
+	Earned   string       `json:"earned"`
+	Currency string       `json:"currency"`
+	Minor    int64        `json:"minor"`
+	Lines    []LineResult `json:"lines"`
+}
+
+//WalletHealth - Structure for the wallet contract health check
+type WalletHealth struct {
+	Code           int32  `json:"code"`
+	WalletContract string `json:"walletcontract"`
+	Healthy        bool   `json:"healthy"`
+	Response       string `json:"response"`
+}
+
+//Wallet - Structure for products used in buy goods
+type Wallet struct {
+	Id       string  `json:"id"`
+	Email    string  `json:"email"`
+	Phone    string  `json:"phone"`
+	Document string  `json:"document"`
+	Password string  `json:"password"`
+	Amount   float64 `json:"amount"`
+}
+
+//Movimiento - Structure for movements
+type Movement struct {
+	Time      int64   `json:"time"`
+	WalletId  string  `json:"walletid"`
+	Amount    float64 `json:"amount"`
+	Type      string  `json:"type"`
+	Detail    string  `json:"detail,omitempty"`
+	Store     string  `json:"store,omitempty"`
+	Terminal  string  `json:"terminal,omitempty"`
+	Cashier   string  `json:"cashier,omitempty"`
+	Wallet    string  `json:"wallet,omitempty"`
+	Soles     float64 `json:"soles,omitempty"`
+	Reference string  `json:"reference,omitempty"`
+	Currency  string  `json:"currency,omitempty"`
+	Minor     int64   `json:"minor,omitempty"`
+}
+
+//Balance - Structure for balance
+type Balance struct{
+	Business string `json:"business"`
+	Total float64 `json:"total"`
+	Exchange float64 `json:"exchange"`
+	Send float64 `json:"send"`   
+}
+
+//Response - Structure for response
+type ResponseContract struct {
+	Code     int32  `json:"code"`
+	Balance string `json:"balance"`
+	Limit string `json:"limit"`
+}
+
+//PurchaseResponse - Structure for the wallet purchase response
+type PurchaseResponse struct {
+	Code      int32  `json:"code"`
+	Balance   string `json:"balance"`
+	Limit     string `json:"limit"`
+	Tier      string `json:"tier"`
+	Redeemed  string `json:"redeemed"`
+	Earned    string `json:"earned"`
+	RedeemRef string `json:"redeemref"`
+	EarnRef   string `json:"earnref"`
+}
+
+// SimpleChaincode example simple Chaincode implementation
+type SimpleChaincode struct {
+}
+
+// Init reinicia los estados del ledger
+// args: coins iniciales, nombre del negocio, id de comercio en el wallet, tipo de cambio inicial, contrato wallet
+// y opcionalmente la moneda ISO 4217 del tipo de cambio inicial (PEN por defecto)
+func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	if len(args) != 5 && len(args) != 6 {
+		return nil, errors.New("Número de Argumentos incorrecto. Se esperaba 5 o 6 argumentos")
+	}
+	
+	amt, err := strconv.ParseFloat(args[0], 64)
+
+	if err != nil {
+		fmt.Println("Error Float parsing")
+		return nil, errors.New("Error marshaling wallet")
+	}
+
+	change, err := strconv.ParseFloat(args[3], 64)
+	if err != nil || !(change > 0) || math.IsInf(change, 0) {
+		return nil, errors.New("Tipo de cambio invalido: " + args[3])
+	}
+
+	config := Config{
+		Business:       args[1],
+		MerchantId:     args[2],
+		WalletContract: args[4],
+		Currency:       defaultCurrency,
+	}
+	if len(args) == 6 {
+		config.Currency = strings.ToUpper(args[5])
+		err = checkCurrency(config.Currency)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = putConfig(stub, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Iniciando Contrato %s con tipo de cambio %v\n", config.Business, change)
+	
+	//Adquirir coins iniciales
+	f := "debittotalcoin"
+	invokeArgs := util.ToChaincodeArgs(f, args[0])
+	response, err := stub.InvokeChaincode(config.WalletContract, invokeArgs)
+	
+	if err != nil {
+		errStr := fmt.Sprintf("Failed to invoke chaincode. Got error: %s", err.Error())
+		fmt.Println(errStr)
+		return nil, errors.New(errStr)
+	}
+
+	fmt.Printf("Invoke chaincode successful. Got response %s", string(response))
+	
+	balance := Balance{
+		Business: config.Business,
+		Total:    amt,
+		Exchange: 0,
+		Send:     0,
+	}
+
+	bytes, err1 := json.Marshal(balance)
+	if err1 != nil {
+		fmt.Println("Error marshaling wallet")
+		return nil, errors.New("Error marshaling wallet")
+	}
+
+	err = stub.PutState("coinBalance", bytes)
+	if err != nil {
+		fmt.Println("Error creando el balance inicial del negocio")
+		return nil, err
+	}
+	
+	stub.CreateTable(tableColumn, []*shim.ColumnDefinition{
+		&shim.ColumnDefinition{Name: columnAccountID, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnTime, Type: shim.ColumnDefinition_INT64, Key: true},
+		&shim.ColumnDefinition{Name: columnAmount, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnType, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnDetail, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnStore, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnTerminal, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnCashier, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnWallet, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnSoles, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnReference, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnCurrency, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnMinor, Type: shim.ColumnDefinition_INT64, Key: false},
+	})
+
+	stub.CreateTable(tableRate, []*shim.ColumnDefinition{
+		&shim.ColumnDefinition{Name: columnAccountID, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnCurrency, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnFrom, Type: shim.ColumnDefinition_INT64, Key: true},
+		&shim.ColumnDefinition{Name: columnRate, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnSetTime, Type: shim.ColumnDefinition_INT64, Key: false},
+	})
+
+	stub.CreateTable(tableCategory, []*shim.ColumnDefinition{
+		&shim.ColumnDefinition{Name: columnAccountID, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnCategory, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnMultiplier, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnRedeemable, Type: shim.ColumnDefinition_BOOL, Key: false},
+	})
+
+	stub.CreateTable(tableReceipt, []*shim.ColumnDefinition{
+		&shim.ColumnDefinition{Name: columnAccountID, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnReceipt, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnTime, Type: shim.ColumnDefinition_INT64, Key: false},
+		&shim.ColumnDefinition{Name: columnFingerprint, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnResult, Type: shim.ColumnDefinition_STRING, Key: false},
+	})
+
+	stub.CreateTable(tableReward, []*shim.ColumnDefinition{
+		&shim.ColumnDefinition{Name: columnAccountID, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnRewardId, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnRewardName, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnPrice, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnStock, Type: shim.ColumnDefinition_INT64, Key: false},
+		&shim.ColumnDefinition{Name: columnValidFrom, Type: shim.ColumnDefinition_INT64, Key: false},
+		&shim.ColumnDefinition{Name: columnValidTo, Type: shim.ColumnDefinition_INT64, Key: false},
+	})
+
+	stub.CreateTable(tableRedemption, []*shim.ColumnDefinition{
+		&shim.ColumnDefinition{Name: columnAccountID, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnCode, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnRewardId, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnWallet, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnPrice, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnTime, Type: shim.ColumnDefinition_INT64, Key: false},
+		&shim.ColumnDefinition{Name: columnUsed, Type: shim.ColumnDefinition_INT64, Key: false},
+	})
+
+	//La tasa inicial rige desde siempre
+	err = insertRate(stub, config.Currency, change, 0, makeTimestamp(stub))
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// Invoke Punto de entrada a cualquier función del ledger
+func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	fmt.Println("Merchant invoke is running..FUNCTION:" + function)
+
+	if function == "createwallet" {
+		return t.createWallet(stub, args)
+	} else {
+		if function == "buy" {
+			return t.buy(stub, args)
+		} else if function == "getcoins" {
+			return t.getCoins(stub, args)
+		} else if function == "returncoins" {
+			return t.returnCoins(stub, args)
+		} else if function == "setrate" {
+			return t.setRate(stub, args)
+		} else if function == "setwalletcontract" {
+			return t.setWalletContract(stub, args)
+		} else if function == "setcategoryrule" {
+			return t.setCategoryRule(stub, args)
+		} else if function == "setreplenish" {
+			return t.setReplenish(stub, args)
+		} else if function == "setreward" {
+			return t.setReward(stub, args)
+		} else if function == "redeemreward" {
+			return t.redeemReward(stub, args)
+		} else if function == "useredemption" {
+			return t.useRedemption(stub, args)
+		} else if function == "setredemptionrules" {
+			return t.setRedemptionRules(stub, args)
+		} else if function == "issuevouchers" {
+			return t.issueVouchers(stub, args)
+		} else if function == "reclaimvouchers" {
+			return t.reclaimVouchers(stub, args)
+		}
+	}
+	fmt.Println("invoke no encuentra la funcion: " + function)
+
+	return nil, errors.New("Funcion invocada desconocida: " + function)
+}
+
+// Query es nuestro punto de entrada de querys
+func (t *SimpleChaincode) Query(stub shim.ChaincodeStubInterface, function string, args []string) ([]byte, error) {
+	fmt.Println("Merchant query is running FUNCTION:" + function)
+
+	// Manejar diferentes funciones
+	if function == "getbalance" {
+		return t.getBalance(stub, args)
+	} else{ 
+		if function == "gettotalcoin" {
+			return t.getTotalCoin(stub, args)
+		} else if function == "getmovimientos" {
+			return t.getMovimientos(stub, args)
+		} else if function == "getrates" {
+			return t.getRates(stub, args)
+		} else if function == "checkwallet" {
+			return t.checkWallet(stub, args)
+		} else if function == "getcategoryrules" {
+			return t.getCategoryRules(stub, args)
+		} else if function == "gettotalsby" {
+			return t.getTotalsBy(stub, args)
+		} else if function == "getreceipt" {
+			return t.getReceipt(stub, args)
+		} else if function == "getcanjes" {
+			return t.getCanjes(stub, args)
+		} else if function == "getperiodtotals" {
+			return t.getPeriodTotals(stub, args)
+		} else if function == "gettopcustomers" {
+			return t.getTopCustomers(stub, args)
+		} else if function == "getrewards" {
+			return t.getRewards(stub, args)
+		} else if function == "getredemption" {
+			return t.getRedemption(stub, args)
+		} else if function == "getredemptionrules" {
+			return t.getRedemptionRules(stub, args)
+		}
+	}
+	
+	fmt.Println("query no encuentra la funcion: " + function)
+
+	return nil, errors.New("Funcion invocada desconocida: " + function)
+}
+
+// createWallet - invocar esta funcion para crear un wallet con saldo inicial
+func (t *SimpleChaincode) createWallet(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Merchant Call---Funcion createWallet---")
+
+	if len(args) != 5 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 5 para createWallet")
+	}
+
+	config, err0 := getConfig(stub)
+	if err0 != nil {
+		return nil, err0
+	}
+
+	f := "createwallet"
+	invokeArgs := util.ToChaincodeArgs(f, args[0], args[1], args[2], args[3], "123456", args[4])
+	response, err := stub.InvokeChaincode(config.WalletContract, invokeArgs)
+	if err != nil {
+		errStr := fmt.Sprintf("Failed to invoke chaincode. Got error: %s", err.Error())
+		fmt.Println(errStr)
+		return nil, errors.New(errStr)
+	}
+
+	fmt.Printf("Invoke chaincode successful. Got response %s", string(response))
+
+	return nil, nil
+}
+
+// buy - invocar esta funcion para compras y canjes de coins.
+// args: wallet, monto en soles o canasta JSON [{sku, category, quantity, price}] con precios en unidades menores, coins a canjear,
+// opcionalmente tienda, terminal y cajero, y al final opcionalmente el numero de recibo del POS.
+// El canje y la acumulacion se aplican en el wallet con una sola llamada a purchase
+func (t *SimpleChaincode) buy(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Merchant Call---Funcion Buy---")
+
+	if len(args) != 3 && len(args) != 4 && len(args) != 6 && len(args) != 7 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 3, 4, 6 o 7 para buy")
+	}
+
+	attribution := Attribution{}
+	if len(args) >= 6 {
+		attribution = Attribution{Store: args[3], Terminal: args[4], Cashier: args[5]}
+	}
+
+	//Un reenvio del mismo recibo devuelve el resultado original sin volver a acreditar
+	receipt := ""
+	fingerprint := ""
+	if len(args) == 4 || len(args) == 7 {
+		receipt = args[len(args)-1]
+		if receipt == "" {
+			return nil, errors.New("Numero de recibo invalido")
+		}
+		fingerprint = receiptFingerprint(args[0], args[1], args[2])
+		original, err := checkReceipt(stub, receipt, fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		if original != nil {
+			return original, nil
+		}
+	}
+
+	config, err0 := getConfig(stub)
+	if err0 != nil {
+		return nil, err0
+	}
+
+	a := makeTimestamp(stub)
+
+	lines, currency, err := parseBasket(args[1], config.currency())
+	if err != nil {
+		return nil, err
+	}
+
+	//Tasa de cambio de la moneda vigente al momento de la compra
+	change, err0 := rateAt(stub, currency, a)
+	if err0 != nil {
+		return nil, err0
+	}
+
+	coins, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || !(coins >= 0) || math.IsInf(coins, 0) || coins > maxAmount {
+		return nil, errors.New("Coins a canjear invalidos: " + args[2])
+	}
+
+	//Los coins pagan las lineas canjeables y cada linea acumula segun su categoria
+	breakdown, err := priceBasket(stub, lines, change, coins)
+	if err != nil {
+		return nil, err
+	}
+	var earn, soles, total float64
+	for _, line := range breakdown {
+		earn = earn + line.Earned
+		soles = soles + line.Subtotal
+		total = total + line.Coins
+	}
+
+	//Reglas de canje del comercio: porcentaje maximo, canje minimo, multiplo y redondeo
+	err = checkRedemption(config.Rules, coins, total)
+	if err != nil {
+		return nil, err
+	}
+
+	detail, err := json.Marshal(breakdown)
+	if err != nil {
+		return nil, errors.New("Error marshaling breakdown")
+	}
+
+	//El monto de la compra se reparte en unidades menores entre lo pagado con coins y en efectivo
+	totalMinor, err := toMinor(currency, soles)
+	if err != nil {
+		return nil, err
+	}
+	redeemMinor, err := toMinor(currency, coins/change)
+	if err != nil {
+		return nil, err
+	}
+	if redeemMinor > totalMinor {
+		redeemMinor = totalMinor
+	}
+	paidMinor := totalMinor - redeemMinor
+
+	//El wallet redondea la acumulacion despues del bonus del tier
+	f := "purchase"
+	invokeArgs := util.ToChaincodeArgs(f, args[0], config.MerchantId, strconv.FormatFloat(coins, 'f', 6, 64), strconv.FormatFloat(earn, 'f', 6, 64), attribution.Store, attribution.Terminal, attribution.Cashier, currency, strconv.FormatInt(redeemMinor, 10), strconv.FormatInt(paidMinor, 10), roundingMode(config.Rules))
+	response, err := stub.InvokeChaincode(config.WalletContract, invokeArgs)
+	if err != nil {
+		errStr := fmt.Sprintf("Failed to invoke chaincode. Got error: %s", err.Error())
+		fmt.Println(errStr)
+		return nil, errors.New(errStr)
+	}
+
+	fmt.Printf("Invoke chaincode successful. Got response %s", string(response))
+
+	result := PurchaseResponse{}
+	err = json.Unmarshal(response, &result)
+	if err != nil {
+		return nil, fmt.Errorf("Respuesta invalida del contrato wallet. %s", err)
+	}
+
+	redeemed, _ := strconv.ParseFloat(result.Redeemed, 64)
+	earned, _ := strconv.ParseFloat(result.Earned, 64)
+
+	//Los soles de la compra se reparten entre lo pagado con coins y lo pagado en efectivo
+	canje := Movement{
+		Detail:   string(detail),
+		Store:    attribution.Store,
+		Terminal: attribution.Terminal,
+		Cashier:  attribution.Cashier,
+		Wallet:   args[0],
+		Currency: currency,
+	}
+
+	//Cualquier falla devuelve error para que la transaccion completa se descarte
+	if redeemed > 0 {
+		canje.Time = a
+		canje.Amount = redeemed
+		canje.Type = "C"
+		canje.Soles = redeemed / change
+		canje.Minor = redeemMinor
+		canje.Reference = result.RedeemRef
+		if !insertRow(stub, config.Business, canje) {
+			return nil, errors.New("Fallo registrar el canje")
+		}
+	}
+	//La fila D registra lo pagado aunque la compra no acumule, por una categoria sin multiplicador o por el redondeo
+	if paidMinor > 0 || earned > 0 {
+		canje.Time = a + 1
+		canje.Amount = earned
+		canje.Type = "D"
+		canje.Soles = soles - redeemed/change
+		canje.Minor = paidMinor
+		canje.Reference = result.EarnRef
+		if !insertRow(stub, config.Business, canje) {
+			return nil, errors.New("Fallo registrar la acumulacion")
+		}
+	}
+
+	if !updateBalance(stub, redeemed, earned) {
+		return nil, errors.New("Failed update balance")
+	}
+
+	//Reposicion automatica si el balance quedo bajo el minimo
+	_, err = replenish(stub, config, a+2)
+	if err != nil {
+		return nil, err
+	}
+
+	//La acumulacion sale del balance del negocio; si ni con la reposicion alcanza, la compra se rechaza
+	bytesBalance, err := stub.GetState("coinBalance")
+	if err != nil {
+		return nil, errors.New("Error retrieving coinBalance")
+	}
+	balance := Balance{}
+	err = json.Unmarshal(bytesBalance, &balance)
+	if err != nil {
+		return nil, errors.New("Error unmarshaling coinBalance")
+	}
+	if balance.Total < 0 {
+		return nil, errors.New("El negocio no cuenta con coins suficientes para acumular")
+	}
+
+	buyResult := BuyResult{
+		Code:     result.Code,
+		Balance:  result.Balance,
+		Limit:    result.Limit,
+		Tier:     result.Tier,
+		Redeemed: result.Redeemed,
+		Earned:   result.Earned,
+		Currency: currency,
+		Minor:    totalMinor,
+		Lines:    breakdown,
+	}
+
+	resultBytes, err := json.Marshal(buyResult)
+	if err != nil {
+		return nil, errors.New("Error marshaling buy result")
+	}
+
+	if receipt != "" {
+		err = insertReceipt(stub, receipt, a, fingerprint, resultBytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resultBytes, nil
+}
+
+func (t *SimpleChaincode) getBalance(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Merchant----getBalance() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 1 para getBalance")
+	}
+
+	config, err0 := getConfig(stub)
+	if err0 != nil {
+		return nil, err0
+	}
+
+	f := "getbalance"
+	invokeArgs := util.ToChaincodeArgs(f, args[0])
+	response, err := stub.QueryChaincode(config.WalletContract, invokeArgs)
+	if err != nil {
+		errStr := fmt.Sprintf("Failed to query chaincode. Got error: %s", err.Error())
+		fmt.Println(errStr)
+		return nil, errors.New(errStr)
+	}
+
+	fmt.Printf("Invoke chaincode successful. Got response %s", string(response))
+
+	responseContract := ResponseContract{}
+	err1 := json.Unmarshal(response, &responseContract)
+
+	fmt.Println(responseContract.Balance)
+	if err1 != nil {
+		fmt.Println("Error parseando a Json" + args[0])
+		return nil, errors.New("Error retrieving Balance" + args[0])
+	}
+
+	return []byte(fmt.Sprintf(`{"code":0,"balance":"%s","limit":"%s"}`, responseContract.Balance,responseContract.Limit)), nil
+}
+
+func (t *SimpleChaincode) getTotalCoin(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getTotalCoin() is running----")
+
+	bytesWallet1, err1 := stub.GetState("coinBalance")
+
+	balance := Balance{}
+	err := json.Unmarshal(bytesWallet1, &balance)
+	if err != nil {
+		fmt.Println("Error TotalCoin parsing")
+		return nil, errors.New("Error marshaling totalBalance")
+	}
+	
+
+	fmt.Println(balance)
+	if err1 != nil {
+		fmt.Println("Error retrieving balance")
+		return nil, errors.New("Error retrieving coinBalance")
+	}
+
+	return []byte(fmt.Sprintf(`{"business":"%s","balance":%v,"spend":%v,"sents":%v}`, balance.Business,balance.Total,balance.Exchange,balance.Send)), nil
+}
+
+//Obtener los movimientos de los coins en el negocio
+//args: negocio y opcionalmente tienda, terminal y cajero para filtrar
+func (t *SimpleChaincode) getMovimientos(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getMovimientos() is running----")
+
+	if len(args) < 1 || len(args) > 4 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba de 1 a 4")
+	}
+
+	walletId := args[0] // wallet id
+	fmt.Println("Business id is ")
+	fmt.Println(walletId)
+
+	filter := Attribution{}
+	if len(args) > 1 {
+		filter.Store = args[1]
+	}
+	if len(args) > 2 {
+		filter.Terminal = args[2]
+	}
+	if len(args) > 3 {
+		filter.Cashier = args[3]
+	}
+
+	canjes, err := scanCanjes(stub, walletId)
+	if err != nil {
+		return nil, err
+	}
+
+	movimientos := []Movement{}
+	for _, movimiento := range canjes {
+		if filter.matches(movimiento) {
+			movimientos = append(movimientos, movimiento)
+		}
+	}
+
+	jsonRows, err := json.Marshal(movimientos)
+	if err != nil {
+		return nil, fmt.Errorf("getRows Movimientos operation failed. Error marshaling JSON: %s", err)
+	}
+
+	return jsonRows, nil
+}
+
+//Obtener los canjes de un cliente y/o de un periodo
+//args: wallet (vacio para todos) y opcionalmente desde y hasta en milisegundos
+func (t *SimpleChaincode) getCanjes(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getCanjes() is running----")
+
+	if len(args) != 1 && len(args) != 3 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1 o 3")
+	}
+
+	var from, to int64
+	if len(args) == 3 {
+		var err error
+		from, err = strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return nil, errors.New("Inicio de periodo invalido: " + args[1])
+		}
+		to, err = strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return nil, errors.New("Fin de periodo invalido: " + args[2])
+		}
+	}
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	canjes, err := scanCanjes(stub, config.Business)
+	if err != nil {
+		return nil, err
+	}
+
+	movimientos := []Movement{}
+	for _, movimiento := range canjes {
+		if args[0] != "" && movimiento.Wallet != args[0] {
+			continue
+		}
+		if len(args) == 3 && (movimiento.Time < from || movimiento.Time >= to) {
+			continue
+		}
+		movimientos = append(movimientos, movimiento)
+	}
+
+	jsonRows, err := json.Marshal(movimientos)
+	if err != nil {
+		return nil, fmt.Errorf("getCanjes operation failed. Error marshaling JSON: %s", err)
+	}
+
+	return jsonRows, nil
+}
+
+//Insertar Row de Retorno y Entrega de Coins al Usuario
+func insertRow(stub shim.ChaincodeStubInterface, business string, movimiento Movement) bool {
+	
+	//Insertar Row de Retorno de Coins al Negocio
+		fmt.Printf("Time: %d \n", movimiento.Time)
+	
+		var columns []*shim.Column
+		col0 := shim.Column{Value: &shim.Column_String_{String_: business}}
+		col1 := shim.Column{Value: &shim.Column_Int64{Int64: movimiento.Time}}
+		col2 := shim.Column{Value: &shim.Column_String_{String_: strconv.FormatFloat(movimiento.Amount, 'f', 6, 64)}}
+		col3 := shim.Column{Value: &shim.Column_String_{String_: movimiento.Type}}
+		col4 := shim.Column{Value: &shim.Column_String_{String_: movimiento.Detail}}
+		col5 := shim.Column{Value: &shim.Column_String_{String_: movimiento.Store}}
+		col6 := shim.Column{Value: &shim.Column_String_{String_: movimiento.Terminal}}
+		col7 := shim.Column{Value: &shim.Column_String_{String_: movimiento.Cashier}}
+		col8 := shim.Column{Value: &shim.Column_String_{String_: movimiento.Wallet}}
+		col9 := shim.Column{Value: &shim.Column_String_{String_: strconv.FormatFloat(movimiento.Soles, 'f', 6, 64)}}
+		col10 := shim.Column{Value: &shim.Column_String_{String_: movimiento.Reference}}
+		col11 := shim.Column{Value: &shim.Column_String_{String_: movimiento.Currency}}
+		col12 := shim.Column{Value: &shim.Column_Int64{Int64: movimiento.Minor}}
+		
+		columns = append(columns, &col0)
+		columns = append(columns, &col1)
+		columns = append(columns, &col2)
+		columns = append(columns, &col3)
+		columns = append(columns, &col4)
+		columns = append(columns, &col5)
+		columns = append(columns, &col6)
+		columns = append(columns, &col7)
+		columns = append(columns, &col8)
+		columns = append(columns, &col9)
+		columns = append(columns, &col10)
+		columns = append(columns, &col11)
+		columns = append(columns, &col12)
+	
+		row := shim.Row{Columns: columns}
+		ok, err := stub.InsertRow(tableColumn, row)
+		if err != nil {
+			return false
+		}
+		if !ok {
+			return false
+		}
+	return true;
+}
+
+//Cambiar el balance de coins del negocio
+func updateBalance(stub shim.ChaincodeStubInterface, coins float64, subtotalSoles float64) bool {
+	bytesWallet1, err1 := stub.GetState("coinBalance")
+
+	balance := Balance{}
+	err := json.Unmarshal(bytesWallet1, &balance)
+
+	fmt.Println(balance)
+	if err1 != nil || err != nil {
+		fmt.Println("Error retrieving balance")
+		return false
+	}
+
+	//amt, err := strconv.ParseFloat(args[2], 64)
+
+	balance.Exchange = balance.Exchange + coins
+	balance.Send = balance.Send + subtotalSoles 
+	balance.Total = balance.Total - subtotalSoles
+
+	balanceJSONasBytes, _ := json.Marshal(balance)
+	err = stub.PutState("coinBalance", balanceJSONasBytes) //rewrite the wallet
+
+	if err != nil {
+		return false
+	}
+
+	return true
+}
+
+//Adquirir coins adicionales del pool central
+func (t *SimpleChaincode) getCoins(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getCoins() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
+	}
+
+	config, err0 := getConfig(stub)
+	if err0 != nil {
+		return nil, err0
+	}
+
+	amt, err := strconv.ParseFloat(args[0], 64)
+
+	if err != nil {
+		fmt.Println("Error Float parsing")
+		return nil, errors.New("Error marshaling wallet")
+	}
+	
+	//Adquirir coins adicionales
+	f := "debittotalcoin"
+	invokeArgs := util.ToChaincodeArgs(f, args[0])
+	response, err1 := stub.InvokeChaincode(config.WalletContract, invokeArgs)
+	
+	if err1 != nil {
+		errStr := fmt.Sprintf("Failed to invoke chaincode. Got error: %s", err.Error())
+		fmt.Println(errStr)
+		return nil, errors.New(errStr)
+	}
+
+	fmt.Printf("Invoke chaincode successful. Got response %s", string(response))
+	
+	//Cambiar el balance
+	bytesWallet1, err2 := stub.GetState("coinBalance")
+
+	balance := Balance{}
+	err3 := json.Unmarshal(bytesWallet1, &balance)
+	if err3 != nil {
+		fmt.Println("Error parsing json")
+		return nil, errors.New("Error unmarshaling wallet")
+	}
+	
+	fmt.Println(balance)
+	if err2 != nil {
+		fmt.Println("Error retrieving balance")
+		return nil, errors.New("Error")
+	}
+ 
+	balance.Total = balance.Total + amt
+
+	balanceJSONasBytes, _ := json.Marshal(balance)
+	err = stub.PutState("coinBalance", balanceJSONasBytes) //rewrite the wallet
+
+	if err != nil {
+		fmt.Printf("Error actualizando el balance del negocio")
+		return nil, errors.New("Error")
+	}
+	
+	return nil, nil
+}
+
+//Devolver coins no usados al pool central
+func (t *SimpleChaincode) returnCoins(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----returnCoins() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede devolver coins al pool central")
+	}
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	amt, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || amt <= 0 {
+		return nil, errors.New("Monto invalido: " + args[0])
+	}
+
+	bytesBalance, err := stub.GetState("coinBalance")
+	if err != nil {
+		fmt.Println("Error retrieving balance")
+		return nil, errors.New("Error retrieving coinBalance")
+	}
+
+	balance := Balance{}
+	err = json.Unmarshal(bytesBalance, &balance)
+	if err != nil {
+		fmt.Println("Error parsing json")
+		return nil, errors.New("Error unmarshaling coinBalance")
+	}
+
+	if amt > balance.Total {
+		return nil, errors.New("El negocio no cuenta con coins suficientes para devolver")
+	}
+
+	balance.Total = balance.Total - amt
+
+	balanceJSONasBytes, _ := json.Marshal(balance)
+	err = stub.PutState("coinBalance", balanceJSONasBytes)
+	if err != nil {
+		fmt.Printf("Error actualizando el balance del negocio")
+		return nil, errors.New("Error actualizando el balance del negocio")
+	}
+
+	//Devolver coins al pool central
+	f := "puttotalcoin"
+	invokeArgs := util.ToChaincodeArgs(f, strconv.FormatFloat(amt, 'f', 6, 64))
+	response, err := stub.InvokeChaincode(config.WalletContract, invokeArgs)
+	if err != nil {
+		errStr := fmt.Sprintf("Failed to invoke chaincode. Got error: %s", err.Error())
+		fmt.Println(errStr)
+		return nil, errors.New(errStr)
+	}
+
+	fmt.Printf("Invoke chaincode successful. Got response %s", string(response))
+
+	if !insertRow(stub, config.Business, Movement{Time: makeTimestamp(stub), Amount: amt, Type: "V"}) {
+		return nil, errors.New("Fallo registrar la devolucion de coins")
+	}
+
+	return []byte(fmt.Sprintf(`{"code":0,"response":"%s"}`, strconv.FormatFloat(balance.Total, 'f', 6, 64))), nil
+}
+
+//getConfig - Obtiene la configuracion del comercio
+func getConfig(stub shim.ChaincodeStubInterface) (*Config, error) {
+	bytes, err := stub.GetState(configKey)
+	if err != nil || bytes == nil {
+		fmt.Println("Error retrieving config")
+		return nil, errors.New("Error retrieving config")
+	}
+
+	config := Config{}
+	err = json.Unmarshal(bytes, &config)
+	if err != nil {
+		fmt.Println("Error parsing config")
+		return nil, errors.New("Error unmarshaling config")
+	}
+
+	return &config, nil
+}
+
+//putConfig - Guarda la configuracion del comercio
+func putConfig(stub shim.ChaincodeStubInterface, config *Config) error {
+	bytes, err := json.Marshal(config)
+	if err != nil {
+		return errors.New("Error marshaling config")
+	}
+
+	err = stub.PutState(configKey, bytes)
+	if err != nil {
+		fmt.Println("Error guardando la configuracion del negocio")
+		return err
+	}
+	return nil
+}
+
+//pingWallet - Verifica que el contrato wallet responda gettotalcoin
+func pingWallet(stub shim.ChaincodeStubInterface, walletContract string) ([]byte, error) {
+	queryArgs := util.ToChaincodeArgs("gettotalcoin")
+	response, err := stub.QueryChaincode(walletContract, queryArgs)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to query chaincode. Got error: %s", err.Error())
+	}
+	return response, nil
+}
+
+//setWalletContract - Cambia el contrato wallet al que apunta el comercio
+func (t *SimpleChaincode) setWalletContract(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----setWalletContract() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede cambiar el contrato wallet")
+	}
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	//Solo se enlaza un contrato que responde
+	_, err = pingWallet(stub, args[0])
+	if err != nil {
+		return nil, fmt.Errorf("El contrato wallet %s no responde. %s", args[0], err)
+	}
+
+	fmt.Printf("Contrato wallet cambia de %s a %s\n", config.WalletContract, args[0])
+	config.WalletContract = args[0]
+
+	err = putConfig(stub, config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = stub.SetEvent("walletContract", []byte("walletContract:"+args[0]))
+	if err != nil {
+		return nil, errors.New("Fallo enviar el evento de cambio de contrato wallet")
+	}
+
+	return []byte(`{"code":0,"response":null}`), nil
+}
+
+//checkWallet - Verifica que el contrato wallet enlazado responda
+func (t *SimpleChaincode) checkWallet(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----checkWallet() is running----")
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	health := WalletHealth{Code: 0, WalletContract: config.WalletContract, Healthy: true}
+	response, err := pingWallet(stub, config.WalletContract)
+	if err != nil {
+		health.Code = 1
+		health.Healthy = false
+		health.Response = err.Error()
+	} else {
+		health.Response = string(response)
+	}
+
+	return json.Marshal(health)
+}
+
+//isAdmin - Valida que quien invoca tenga el rol de administrador
+func isAdmin(stub shim.ChaincodeStubInterface) bool {
+	role, err := stub.ReadCertAttribute("role")
+	if err != nil {
+		fmt.Printf("Error leyendo el atributo role: %s\n", err)
+		return false
+	}
+	return string(role) == "admin"
+}
+
+func safeRandom(dest []byte) {
+	if _, err := rand.Read(dest); err != nil {
+		panic(err)
+	}
+}
+
+// SetVersion sets version bits.
+func (u *UUID) SetVersion(v byte) {
+	u[6] = (u[6] & 0x0f) | (v << 4)
+}
+
+// SetVariant sets variant bits as described in RFC 4122.
+func (u *UUID) SetVariant() {
+	u[8] = (u[8] & 0xbf) | 0x80
+}
+
+func (u UUID) Version() uint {
+	return uint(u[6] >> 4)
+}
+
+// Variant returns UUID layout variant.
+func (u UUID) Variant() uint {
+	switch {
+	case (u[8] & 0x80) == 0x00:
+		return VariantNCS
+	case (u[8]&0xc0)|0x80 == 0x80:
+		return VariantRFC4122
+	case (u[8]&0xe0)|0xc0 == 0xc0:
+		return VariantMicrosoft
+	}
+	return VariantFuture
+}
+
+// Returns canonical string representation of UUID:
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx.
+func (u UUID) String() string {
+	buf := make([]byte, 36)
+
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = dash
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = dash
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = dash
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = dash
+	hex.Encode(buf[24:], u[10:])
+
+	return string(buf)
+}
+
+func NewV4() UUID {
+	u := UUID{}
+	safeRandom(u[:])
+	u.SetVersion(4)
+	u.SetVariant()
+
+	return u
+}
+
+//makeTimestamp - Tiempo de la transaccion en milisegundos. Es el mismo en todos los peers, y en el simulador
+//lo controla Network.Now
+func makeTimestamp(stub shim.ChaincodeStubInterface) int64 {
+	txTime, err := stub.GetTxTimestamp()
+	if err != nil || txTime == nil {
+		return time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	return txTime.Seconds*1000 + int64(txTime.Nanos)/int64(time.Millisecond)
+}
@@ -0,0 +1,251 @@
+/*
+  Merchant Smart Contract
+  Adrian Pareja
+*/
+package merchant_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ccamaleon5/blockchain/chaincode/merchant"
+	"github.com/ccamaleon5/blockchain/chaincode/wallet"
+	"github.com/ccamaleon5/blockchain/simulator"
+)
+
+var admin = map[string]string{"role": "admin", "merchant": "cineplanet"}
+
+//newNetwork - Red con el wallet, el comercio cineplanet desplegado como cine y el wallet w1
+func newNetwork(t *testing.T) *simulator.Network {
+	n := simulator.NewNetwork()
+	n.SetTime(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC))
+	n.Attributes = admin
+	must(t)(n.Deploy("wallet", new(wallet.SimpleChaincode), "init", "1000000"))
+	must(t)(n.Invoke("wallet", "registermerchant", "cineplanet", "Cineplanet", "cineplanet"))
+	must(t)(n.Deploy("cine", new(merchant.SimpleChaincode), "init", "10000", "Cineplanet", "cineplanet", "1", "wallet"))
+	must(t)(n.Invoke("wallet", "createwallet", "w1", "w1@mail.com", "999", "123", "pw", "0"))
+	return n
+}
+
+func must(t *testing.T) func([]byte, error) string {
+	return func(response []byte, err error) string {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(response)
+	}
+}
+
+//mustFail - El error debe contener fragment
+func mustFail(t *testing.T, fragment string) func([]byte, error) {
+	return func(response []byte, err error) {
+		t.Helper()
+		if err == nil || !strings.Contains(err.Error(), fragment) {
+			t.Fatalf("se esperaba el error %q y se obtuvo %v (%s)", fragment, err, response)
+		}
+	}
+}
+
+func TestReturnCoinsAdmin(t *testing.T) {
+	n := newNetwork(t)
+
+	n.Attributes = map[string]string{"role": "cashier"}
+	mustFail(t, "Solo el administrador")(n.Invoke("cine", "returncoins", "100"))
+
+	n.Attributes = admin
+	must(t)(n.Invoke("cine", "returncoins", "100"))
+	if balance := must(t)(n.Query("cine", "gettotalcoin")); !strings.Contains(balance, `"balance":9900`) {
+		t.Fatalf("balance incorrecto: %s", balance)
+	}
+}
+
+func TestRedemptionCode(t *testing.T) {
+	n := newNetwork(t)
+	must(t)(n.Invoke("cine", "setreward", "entrada", "Entrada 2D", "50", "10", "0", "0"))
+	must(t)(n.Invoke("cine", "buy", "w1", "100", "0"))
+
+	//El codigo lo genera el cliente; al chaincode solo llega su hash
+	code := "9F3A61C07B2E4D18"
+	sum := sha256.Sum256([]byte(code))
+	hash := hex.EncodeToString(sum[:])
+	mustFail(t, "Hash de codigo de premio invalido")(n.Invoke("cine", "redeemreward", "w1", "entrada", code))
+	response := must(t)(n.Invoke("cine", "redeemreward", "w1", "entrada", hash))
+	if strings.Contains(response, code) || !strings.Contains(response, `"hash":"`+hash+`"`) {
+		t.Fatalf("canje incorrecto: %s", response)
+	}
+	mustFail(t, "ya existe")(n.Invoke("cine", "redeemreward", "w1", "entrada", hash))
+
+	//El codigo no queda en el ledger, ni en la fila P ni como clave
+	if canjes := must(t)(n.Query("cine", "getcanjes", "w1")); strings.Contains(canjes, code) {
+		t.Fatalf("el codigo quedo en los canjes: %s", canjes)
+	}
+
+	n.Attributes = map[string]string{"role": "cashier", "merchant": "promart"}
+	mustFail(t, "No autorizado")(n.Invoke("cine", "useredemption", code))
+
+	n.Attributes = map[string]string{"role": "cashier", "merchant": "cineplanet"}
+	mustFail(t, "desconocido")(n.Invoke("cine", "useredemption", hash))
+	must(t)(n.Invoke("cine", "useredemption", strings.ToLower(code)))
+	mustFail(t, "ya fue usado")(n.Invoke("cine", "useredemption", code))
+	if used := must(t)(n.Query("cine", "getredemption", code)); !strings.Contains(used, `"reward":"entrada"`) || strings.Contains(used, `"used":0`) {
+		t.Fatalf("codigo no entregado: %s", used)
+	}
+}
+
+func TestVoucherEscrow(t *testing.T) {
+	n := newNetwork(t)
+	hashes := []string{}
+	for _, code := range []string{"CUPON-1", "CUPON-2"} {
+		sum := sha256.Sum256([]byte(code))
+		hashes = append(hashes, hex.EncodeToString(sum[:]))
+	}
+	expiry := n.Now().Add(24*time.Hour).UnixNano() / int64(time.Millisecond)
+
+	args := append([]string{"estreno", "100", strconv.FormatInt(expiry, 10), "1"}, hashes...)
+	mustFail(t, "no cuenta con coins suficientes")(n.Invoke("cine", "issuevouchers", append([]string{"caro", "6000", strconv.FormatInt(expiry, 10), "1"}, hashes...)...))
+	must(t)(n.Invoke("cine", "issuevouchers", args...))
+	if balance := must(t)(n.Query("cine", "gettotalcoin")); !strings.Contains(balance, `"balance":9800`) {
+		t.Fatalf("el comercio no pago el lote: %s", balance)
+	}
+	if batch := must(t)(n.Query("wallet", "getvoucherbatch", "estreno")); !strings.Contains(batch, `"escrow":200`) {
+		t.Fatalf("el lote no retuvo los coins: %s", batch)
+	}
+
+	must(t)(n.Invoke("wallet", "redeemvoucher", "w1", "CUPON-1"))
+	if batch := must(t)(n.Query("wallet", "getvoucherbatch", "estreno")); !strings.Contains(batch, `"escrow":100`) {
+		t.Fatalf("el canje no desconto la retencion: %s", batch)
+	}
+
+	mustFail(t, "aun esta vigente")(n.Invoke("cine", "reclaimvouchers", "estreno"))
+	n.Advance(48 * time.Hour)
+	must(t)(n.Invoke("cine", "reclaimvouchers", "estreno"))
+	if balance := must(t)(n.Query("cine", "gettotalcoin")); !strings.Contains(balance, `"balance":9900`) {
+		t.Fatalf("el comercio no recupero lo retenido: %s", balance)
+	}
+	if batch := must(t)(n.Query("wallet", "getvoucherbatch", "estreno")); !strings.Contains(batch, `"escrow":0`) {
+		t.Fatalf("el lote sigue reteniendo coins: %s", batch)
+	}
+}
+
+func TestEarnRoundingAfterBonus(t *testing.T) {
+	n := newNetwork(t)
+	//Silver acumula con 1.25 de bonus
+	must(t)(n.Invoke("wallet", "putbalance", "w1", "cineplanet", "1000"))
+	must(t)(n.Invoke("cine", "setredemptionrules", "0", "0", "0", "down"))
+
+	//7 coins con el bonus son 8.75, se redondean hacia abajo despues del bonus
+	response := must(t)(n.Invoke("cine", "buy", "w1", "7", "0"))
+	if !strings.Contains(response, `"earned":"8.000000"`) || !strings.Contains(response, `"balance":"1008.000000"`) {
+		t.Fatalf("la acumulacion no se redondeo despues del bonus: %s", response)
+	}
+}
+
+func TestBasketMinorUnits(t *testing.T) {
+	n := newNetwork(t)
+	must(t)(n.Invoke("cine", "setrate", "2", "", "BOB"))
+
+	//Las dos formas de canasta usan unidades menores
+	response := must(t)(n.Invoke("cine", "buy", "w1", `[{"sku":"popcorn","quantity":1,"price":1050}]`, "0"))
+	if !strings.Contains(response, `"currency":"PEN","minor":1050`) {
+		t.Fatalf("la canasta sin moneda no se leyo en unidades menores: %s", response)
+	}
+	mustFail(t, "unidades menores")(n.Invoke("cine", "buy", "w1", `[{"sku":"popcorn","quantity":1,"price":10.5}]`, "0"))
+	response = must(t)(n.Invoke("cine", "buy", "w1", `{"currency":"BOB","lines":[{"sku":"popcorn","quantity":1,"price":1050}]}`, "0"))
+	if !strings.Contains(response, `"currency":"BOB","minor":1050`) {
+		t.Fatalf("la canasta con moneda no se leyo en unidades menores: %s", response)
+	}
+
+	//Los totales no mezclan monedas
+	from := strconv.FormatInt(n.Now().Add(-time.Hour).UnixNano()/int64(time.Millisecond), 10)
+	to := strconv.FormatInt(n.Now().Add(time.Hour).UnixNano()/int64(time.Millisecond), 10)
+	customers := []struct {
+		Wallet   string `json:"wallet"`
+		Currency string `json:"currency"`
+		Minor    int64  `json:"minor"`
+	}{}
+	if err := json.Unmarshal([]byte(must(t)(n.Query("cine", "gettopcustomers", from, to))), &customers); err != nil {
+		t.Fatal(err)
+	}
+	if len(customers) != 2 || customers[0].Currency != "BOB" || customers[0].Minor != 1050 || customers[1].Currency != "PEN" || customers[1].Minor != 1050 {
+		t.Fatalf("clientes mal agrupados por moneda: %+v", customers)
+	}
+	totals := must(t)(n.Query("cine", "getperiodtotals", "day", from, to))
+	if !strings.Contains(totals, `"currency":"BOB","minor":1050`) || !strings.Contains(totals, `"currency":"PEN","minor":1050`) {
+		t.Fatalf("totales mal agrupados por moneda: %s", totals)
+	}
+}
+
+func TestBuyReplenishCap(t *testing.T) {
+	n := newNetwork(t)
+	must(t)(n.Invoke("cine", "returncoins", "9950"))
+	must(t)(n.Invoke("cine", "setreplenish", "100", "200", "50"))
+
+	//La reposicion del dia cubre los 50 coins que faltan
+	must(t)(n.Invoke("cine", "buy", "w1", "100", "0"))
+	if balance := must(t)(n.Query("cine", "gettotalcoin")); !strings.Contains(balance, `"balance":0`) {
+		t.Fatalf("la reposicion no cubrio la acumulacion: %s", balance)
+	}
+
+	//Con el tope diario consumido el comercio no puede acumular mas
+	mustFail(t, "no cuenta con coins suficientes para acumular")(n.Invoke("cine", "buy", "w1", "100", "0"))
+	if balance := must(t)(n.Query("wallet", "getbalance", "w1")); !strings.Contains(balance, `"balance":"100.000000"`) {
+		t.Fatalf("la compra rechazada acredito coins: %s", balance)
+	}
+}
+
+func TestBuyAmountBounds(t *testing.T) {
+	n := newNetwork(t)
+	for _, amount := range []string{"NaN", "Inf", "-Inf", "0", "-1", "1e300"} {
+		mustFail(t, "Monto invalido")(n.Invoke("cine", "buy", "w1", amount, "0"))
+	}
+	for _, coins := range []string{"NaN", "Inf", "-1", "1e300"} {
+		mustFail(t, "Coins a canjear invalidos")(n.Invoke("cine", "buy", "w1", "100", coins))
+	}
+	must(t)(n.Invoke("cine", "buy", "w1", "100", "0"))
+}
+
+func TestBuyMinorOverflow(t *testing.T) {
+	n := newNetwork(t)
+	mustFail(t, "Cantidad o precio invalido")(n.Invoke("cine", "buy", "w1", `[{"sku":"popcorn","quantity":1,"price":1e300}]`, "0"))
+	//Cantidad y precio dentro del tope, pero el subtotal en unidades menores no entra en int64
+	mustFail(t, "Monto invalido en unidades menores")(n.Invoke("cine", "buy", "w1", `[{"sku":"popcorn","quantity":1e9,"price":1e11}]`, "0"))
+}
+
+func TestSetRateFinite(t *testing.T) {
+	n := newNetwork(t)
+	for _, rate := range []string{"NaN", "Inf", "+Inf", "-Inf", "0", "-1"} {
+		mustFail(t, "Tasa de cambio invalida")(n.Invoke("cine", "setrate", rate))
+	}
+	must(t)(n.Invoke("cine", "buy", "w1", "100", "0"))
+}
+
+func TestPaidRowWithoutEarn(t *testing.T) {
+	n := newNetwork(t)
+	must(t)(n.Invoke("cine", "setcategoryrule", "dulceria", "0", "true"))
+	must(t)(n.Invoke("cine", "buy", "w1", `[{"sku":"popcorn","category":"dulceria","quantity":1,"price":1050}]`, "0"))
+
+	from := strconv.FormatInt(n.Now().Add(-time.Hour).UnixNano()/int64(time.Millisecond), 10)
+	to := strconv.FormatInt(n.Now().Add(time.Hour).UnixNano()/int64(time.Millisecond), 10)
+	customers := must(t)(n.Query("cine", "gettopcustomers", from, to))
+	if !strings.Contains(customers, `"wallet":"w1"`) || !strings.Contains(customers, `"minor":1050`) || !strings.Contains(customers, `"earnings":0`) {
+		t.Fatalf("la compra sin acumulacion no quedo registrada: %s", customers)
+	}
+}
+
+func TestTotalsByOnlyPurchases(t *testing.T) {
+	n := newNetwork(t)
+	must(t)(n.Invoke("cine", "buy", "w1", "100", "0", "s1", "t1", "c1"))
+	//La devolucion queda como fila V sin tienda
+	must(t)(n.Invoke("cine", "returncoins", "100"))
+
+	totals := must(t)(n.Query("cine", "gettotalsby", "store"))
+	if strings.Contains(totals, `"key":""`) || !strings.Contains(totals, `"key":"s1"`) {
+		t.Fatalf("los totales sumaron filas que no son compras: %s", totals)
+	}
+}
@@ -0,0 +1,194 @@
+/*
+  Merchant Smart Contract
+  Adrian Pareja
+*/
+package merchant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	tableRate      = "Tasas"
+	columnCurrency = "Currency"
+	columnFrom     = "From"
+	columnRate     = "Rate"
+	columnSetTime  = "SetTime"
+)
+
+//Rate - Structure for the exchange rate history of a currency
+type Rate struct {
+	Currency string  `json:"currency"`
+	From     int64   `json:"from"`
+	Rate     float64 `json:"rate"`
+	SetTime  int64   `json:"settime"`
+}
+
+//insertRate - Registra una tasa de la moneda vigente desde from
+func insertRate(stub shim.ChaincodeStubInterface, currency string, rate float64, from int64, setTime int64) error {
+	var columns []*shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Rate"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: currency}}
+	col2 := shim.Column{Value: &shim.Column_Int64{Int64: from}}
+	col3 := shim.Column{Value: &shim.Column_String_{String_: strconv.FormatFloat(rate, 'f', 6, 64)}}
+	col4 := shim.Column{Value: &shim.Column_Int64{Int64: setTime}}
+	columns = append(columns, &col0)
+	columns = append(columns, &col1)
+	columns = append(columns, &col2)
+	columns = append(columns, &col3)
+	columns = append(columns, &col4)
+
+	row := shim.Row{Columns: columns}
+	ok, err := stub.InsertRow(tableRate, row)
+	if err != nil {
+		return fmt.Errorf("Insert Row Tasas operation failed. %s", err)
+	}
+	if !ok {
+		return errors.New("Ya existe una tasa vigente desde ese momento")
+	}
+	return nil
+}
+
+//getRateHistory - Obtiene las tasas de la moneda ordenadas por inicio de vigencia, todas si la moneda es vacia
+func getRateHistory(stub shim.ChaincodeStubInterface, currency string) ([]Rate, error) {
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Rate"}}
+	columns = append(columns, col0)
+	if currency != "" {
+		col1 := shim.Column{Value: &shim.Column_String_{String_: currency}}
+		columns = append(columns, col1)
+	}
+
+	rowChannel, err := stub.GetRows(tableRate, columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRows Tasas operation failed. %s", err)
+	}
+
+	rates := []Rate{}
+	for {
+		select {
+		case row, ok := <-rowChannel:
+			if !ok {
+				rowChannel = nil
+			} else {
+				columnas := row.GetColumns()
+				rateRow, _ := strconv.ParseFloat(columnas[3].GetString_(), 64)
+				rates = append(rates, Rate{Currency: columnas[1].GetString_(), From: columnas[2].GetInt64(), Rate: rateRow, SetTime: columnas[4].GetInt64()})
+			}
+		}
+		if rowChannel == nil {
+			break
+		}
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].Currency != rates[j].Currency {
+			return rates[i].Currency < rates[j].Currency
+		}
+		return rates[i].From < rates[j].From
+	})
+
+	return rates, nil
+}
+
+//rateAt - Obtiene la tasa de la moneda vigente en el momento indicado
+func rateAt(stub shim.ChaincodeStubInterface, currency string, time int64) (float64, error) {
+	rates, err := getRateHistory(stub, currency)
+	if err != nil {
+		return 0, err
+	}
+
+	var rate float64
+	found := false
+	for _, r := range rates {
+		if r.From <= time {
+			rate = r.Rate
+			found = true
+		}
+	}
+	if !found {
+		return 0, errors.New("No hay una tasa de cambio vigente para " + currency)
+	}
+	return rate, nil
+}
+
+//setRate - Registra una nueva tasa de cambio: tasa, opcionalmente inicio de vigencia en milisegundos
+//(vacio para ahora) y moneda ISO 4217 (la del comercio por defecto)
+func (t *SimpleChaincode) setRate(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----setRate() is running----")
+
+	if len(args) < 1 || len(args) > 3 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1, 2 o 3")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede cambiar la tasa")
+	}
+
+	rate, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || !(rate > 0) || math.IsInf(rate, 0) {
+		return nil, errors.New("Tasa de cambio invalida: " + args[0])
+	}
+
+	a := makeTimestamp(stub)
+	from := a
+	if len(args) >= 2 && args[1] != "" {
+		from, err = strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return nil, errors.New("Inicio de vigencia invalido: " + args[1])
+		}
+		//No se reescribe la historia de movimientos ya registrados
+		if from < a {
+			return nil, errors.New("El inicio de vigencia no puede estar en el pasado")
+		}
+	}
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+	currency := config.currency()
+	if len(args) == 3 {
+		currency = strings.ToUpper(args[2])
+		err = checkCurrency(currency)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = insertRate(stub, currency, rate, from, a)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(`{"code":0,"response":null}`), nil
+}
+
+//getRates - Obtiene la historia de tasas de cambio, opcionalmente de una moneda
+func (t *SimpleChaincode) getRates(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getRates() is running----")
+
+	currency := ""
+	if len(args) > 0 {
+		currency = strings.ToUpper(args[0])
+	}
+
+	rates, err := getRateHistory(stub, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonRows, err := json.Marshal(rates)
+	if err != nil {
+		return nil, fmt.Errorf("getRows Tasas operation failed. Error marshaling JSON: %s", err)
+	}
+
+	return jsonRows, nil
+}
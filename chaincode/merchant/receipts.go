@@ -0,0 +1,127 @@
+/*
+  Merchant Smart Contract
+  Adrian Pareja
+*/
+package merchant
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	tableReceipt      = "Recibos"
+	columnReceipt     = "Receipt"
+	columnFingerprint = "Fingerprint"
+	columnResult      = "Result"
+)
+
+//Receipt - Structure for a processed POS receipt
+type Receipt struct {
+	Receipt     string `json:"receipt"`
+	Time        int64  `json:"time"`
+	Fingerprint string `json:"fingerprint"`
+	Result      string `json:"result"`
+}
+
+//ReceiptResponse - Structure for the getreceipt response
+type ReceiptResponse struct {
+	Code    int32           `json:"code"`
+	Receipt string          `json:"receipt"`
+	Time    int64           `json:"time"`
+	Result  json.RawMessage `json:"result"`
+}
+
+//receiptFingerprint - Huella de la compra para reconocer reenvios del mismo POS
+func receiptFingerprint(wallet string, basket string, coins string) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{wallet, basket, coins}, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+//getReceiptRow - Obtiene un recibo procesado, nil si no existe
+func getReceiptRow(stub shim.ChaincodeStubInterface, receipt string) (*Receipt, error) {
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Receipt"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: receipt}}
+	columns = append(columns, col0)
+	columns = append(columns, col1)
+
+	row, err := stub.GetRow(tableReceipt, columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRow Recibos operation failed. %s", err)
+	}
+	if len(row.Columns) == 0 {
+		return nil, nil
+	}
+
+	columnas := row.GetColumns()
+	return &Receipt{Receipt: columnas[1].GetString_(), Time: columnas[2].GetInt64(), Fingerprint: columnas[3].GetString_(), Result: columnas[4].GetString_()}, nil
+}
+
+//checkReceipt - Devuelve el resultado original si el recibo es un reenvio de la misma compra
+//y error si el recibo ya se uso para otra compra
+func checkReceipt(stub shim.ChaincodeStubInterface, receipt string, fingerprint string) ([]byte, error) {
+	existing, err := getReceiptRow(stub, receipt)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+	if existing.Fingerprint != fingerprint {
+		return nil, errors.New("El recibo " + receipt + " ya fue usado en otra compra")
+	}
+
+	fmt.Printf("Recibo %s ya procesado, se devuelve el resultado original\n", receipt)
+	return []byte(existing.Result), nil
+}
+
+//insertReceipt - Guarda el resultado de la compra asociado al recibo
+func insertReceipt(stub shim.ChaincodeStubInterface, receipt string, time int64, fingerprint string, result []byte) error {
+	var columns []*shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Receipt"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: receipt}}
+	col2 := shim.Column{Value: &shim.Column_Int64{Int64: time}}
+	col3 := shim.Column{Value: &shim.Column_String_{String_: fingerprint}}
+	col4 := shim.Column{Value: &shim.Column_String_{String_: string(result)}}
+	columns = append(columns, &col0)
+	columns = append(columns, &col1)
+	columns = append(columns, &col2)
+	columns = append(columns, &col3)
+	columns = append(columns, &col4)
+
+	row := shim.Row{Columns: columns}
+	ok, err := stub.InsertRow(tableReceipt, row)
+	if err != nil {
+		return fmt.Errorf("Insert Row Recibos operation failed. %s", err)
+	}
+	if !ok {
+		return errors.New("El recibo " + receipt + " ya fue registrado")
+	}
+	return nil
+}
+
+//getReceipt - Obtiene el resultado guardado de un recibo
+func (t *SimpleChaincode) getReceipt(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getReceipt() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
+	}
+
+	receipt, err := getReceiptRow(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if receipt == nil {
+		return nil, errors.New("Recibo desconocido: " + args[0])
+	}
+
+	return json.Marshal(ReceiptResponse{Code: 0, Receipt: receipt.Receipt, Time: receipt.Time, Result: json.RawMessage(receipt.Result)})
+}
@@ -0,0 +1,142 @@
+/*
+  Merchant Smart Contract
+  Adrian Pareja
+*/
+package merchant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/util"
+)
+
+//Clave del estado con los coins adquiridos automaticamente en el dia
+const replenishKey string = "replenishDay"
+
+const dayMillis int64 = 24 * 60 * 60 * 1000
+
+//ReplenishDay - Structure for the coins drawn automatically in a day
+type ReplenishDay struct {
+	Day   int64   `json:"day"`
+	Drawn float64 `json:"drawn"`
+}
+
+//replenish - Si el balance del negocio queda bajo el minimo adquiere coins del pool central
+//hasta el objetivo, sin pasar el tope diario. Devuelve los coins adquiridos
+func replenish(stub shim.ChaincodeStubInterface, config *Config, a int64) (float64, error) {
+	if config.Target <= 0 {
+		return 0, nil
+	}
+
+	bytesBalance, err := stub.GetState("coinBalance")
+	if err != nil {
+		return 0, errors.New("Error retrieving coinBalance")
+	}
+	balance := Balance{}
+	err = json.Unmarshal(bytesBalance, &balance)
+	if err != nil {
+		return 0, errors.New("Error unmarshaling coinBalance")
+	}
+
+	if balance.Total >= config.LowWater {
+		return 0, nil
+	}
+
+	day := ReplenishDay{Day: a / dayMillis}
+	bytesDay, err := stub.GetState(replenishKey)
+	if err != nil {
+		return 0, errors.New("Error retrieving " + replenishKey)
+	}
+	if bytesDay != nil {
+		previous := ReplenishDay{}
+		err = json.Unmarshal(bytesDay, &previous)
+		if err != nil {
+			return 0, errors.New("Error unmarshaling " + replenishKey)
+		}
+		if previous.Day == day.Day {
+			day = previous
+		}
+	}
+
+	amount := config.Target - balance.Total
+	if config.DailyCap > 0 && day.Drawn+amount > config.DailyCap {
+		amount = config.DailyCap - day.Drawn
+	}
+	if amount <= 0 {
+		fmt.Println("Tope diario de reposicion alcanzado")
+		return 0, nil
+	}
+
+	f := "debittotalcoin"
+	invokeArgs := util.ToChaincodeArgs(f, strconv.FormatFloat(amount, 'f', 6, 64))
+	response, err := stub.InvokeChaincode(config.WalletContract, invokeArgs)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to invoke chaincode. Got error: %s", err.Error())
+	}
+
+	fmt.Printf("Reposicion automatica de %v coins. Got response %s\n", amount, string(response))
+
+	balance.Total = balance.Total + amount
+	balanceJSONasBytes, _ := json.Marshal(balance)
+	err = stub.PutState("coinBalance", balanceJSONasBytes)
+	if err != nil {
+		return 0, err
+	}
+
+	day.Drawn = day.Drawn + amount
+	dayJSONasBytes, _ := json.Marshal(day)
+	err = stub.PutState(replenishKey, dayJSONasBytes)
+	if err != nil {
+		return 0, err
+	}
+
+	if !insertRow(stub, config.Business, Movement{Time: a, Amount: amount, Type: "R"}) {
+		return 0, errors.New("Fallo registrar la reposicion de coins")
+	}
+
+	return amount, nil
+}
+
+//setReplenish - Configura la reposicion automatica: minimo, objetivo y tope diario
+func (t *SimpleChaincode) setReplenish(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----setReplenish() is running----")
+
+	if len(args) != 3 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 3")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede configurar la reposicion")
+	}
+
+	var values [3]float64
+	for i, arg := range args {
+		value, err := strconv.ParseFloat(arg, 64)
+		if err != nil || value < 0 {
+			return nil, errors.New("Valor invalido: " + arg)
+		}
+		values[i] = value
+	}
+	if values[1] < values[0] {
+		return nil, errors.New("El objetivo debe ser mayor o igual al minimo")
+	}
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	config.LowWater = values[0]
+	config.Target = values[1]
+	config.DailyCap = values[2]
+
+	err = putConfig(stub, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(`{"code":0,"response":null}`), nil
+}
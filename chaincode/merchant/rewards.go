@@ -0,0 +1,433 @@
+/*
+  Merchant Smart Contract
+  Adrian Pareja
+*/
+package merchant
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/util"
+)
+
+const (
+	tableReward      = "Premios"
+	columnRewardId   = "RewardId"
+	columnRewardName = "Name"
+	columnPrice      = "Price"
+	columnStock      = "Stock"
+	columnValidFrom  = "ValidFrom"
+	columnValidTo    = "ValidTo"
+
+	tableRedemption = "CodigosPremio"
+	columnCode      = "Code"
+	columnUsed      = "Used"
+)
+
+//Reward - Structure for a catalog item that customers buy with coins.
+//ValidTo en 0 indica que el premio no vence
+type Reward struct {
+	Id        string  `json:"id"`
+	Name      string  `json:"name"`
+	Price     float64 `json:"price"`
+	Stock     int64   `json:"stock"`
+	ValidFrom int64   `json:"validfrom"`
+	ValidTo   int64   `json:"validto"`
+}
+
+//Redemption - Structure for the code issued when a customer redeems a reward
+type Redemption struct {
+	Code   string  `json:"code,omitempty"`
+	Hash   string  `json:"hash"`
+	Reward string  `json:"reward"`
+	Wallet string  `json:"wallet"`
+	Price  float64 `json:"price"`
+	Time   int64   `json:"time"`
+	Used   int64   `json:"used"`
+}
+
+//getReward - Obtiene un premio del catalogo, nil si no existe
+func getReward(stub shim.ChaincodeStubInterface, rewardId string) (*Reward, error) {
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Reward"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: rewardId}}
+	columns = append(columns, col0)
+	columns = append(columns, col1)
+
+	row, err := stub.GetRow(tableReward, columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRow Premios operation failed. %s", err)
+	}
+	if len(row.Columns) == 0 {
+		return nil, nil
+	}
+
+	return rewardFromRow(row), nil
+}
+
+func rewardFromRow(row shim.Row) *Reward {
+	columnas := row.GetColumns()
+	price, _ := strconv.ParseFloat(columnas[3].GetString_(), 64)
+	return &Reward{Id: columnas[1].GetString_(), Name: columnas[2].GetString_(), Price: price, Stock: columnas[4].GetInt64(), ValidFrom: columnas[5].GetInt64(), ValidTo: columnas[6].GetInt64()}
+}
+
+//putReward - Inserta o reemplaza la fila del premio
+func putReward(stub shim.ChaincodeStubInterface, reward *Reward, replace bool) error {
+	var columns []*shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Reward"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: reward.Id}}
+	col2 := shim.Column{Value: &shim.Column_String_{String_: reward.Name}}
+	col3 := shim.Column{Value: &shim.Column_String_{String_: strconv.FormatFloat(reward.Price, 'f', 6, 64)}}
+	col4 := shim.Column{Value: &shim.Column_Int64{Int64: reward.Stock}}
+	col5 := shim.Column{Value: &shim.Column_Int64{Int64: reward.ValidFrom}}
+	col6 := shim.Column{Value: &shim.Column_Int64{Int64: reward.ValidTo}}
+	columns = append(columns, &col0)
+	columns = append(columns, &col1)
+	columns = append(columns, &col2)
+	columns = append(columns, &col3)
+	columns = append(columns, &col4)
+	columns = append(columns, &col5)
+	columns = append(columns, &col6)
+
+	row := shim.Row{Columns: columns}
+	var ok bool
+	var err error
+	if replace {
+		ok, err = stub.ReplaceRow(tableReward, row)
+	} else {
+		ok, err = stub.InsertRow(tableReward, row)
+	}
+	if err != nil {
+		return fmt.Errorf("Insert Row Premios operation failed. %s", err)
+	}
+	if !ok {
+		return errors.New("Fallo insertar Row Premios")
+	}
+	return nil
+}
+
+//setReward - Crea o actualiza un premio: id, nombre, precio en coins, stock, vigente desde y hasta
+func (t *SimpleChaincode) setReward(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----setReward() is running----")
+
+	if len(args) != 6 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 6")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede configurar premios")
+	}
+	if args[0] == "" {
+		return nil, errors.New("El id del premio es obligatorio")
+	}
+
+	price, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || price <= 0 {
+		return nil, errors.New("Precio invalido: " + args[2])
+	}
+	stock, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil || stock < 0 {
+		return nil, errors.New("Stock invalido: " + args[3])
+	}
+	validFrom, err := strconv.ParseInt(args[4], 10, 64)
+	if err != nil {
+		return nil, errors.New("Inicio de vigencia invalido: " + args[4])
+	}
+	validTo, err := strconv.ParseInt(args[5], 10, 64)
+	if err != nil || (validTo != 0 && validTo <= validFrom) {
+		return nil, errors.New("Fin de vigencia invalido: " + args[5])
+	}
+
+	existing, err := getReward(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	reward := Reward{
+		Id:        args[0],
+		Name:      args[1],
+		Price:     price,
+		Stock:     stock,
+		ValidFrom: validFrom,
+		ValidTo:   validTo,
+	}
+
+	err = putReward(stub, &reward, existing != nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(`{"code":0,"response":null}`), nil
+}
+
+//getRewards - Obtiene el catalogo de premios
+func (t *SimpleChaincode) getRewards(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getRewards() is running----")
+
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Reward"}}
+	columns = append(columns, col0)
+
+	rowChannel, err := stub.GetRows(tableReward, columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRows Premios operation failed. %s", err)
+	}
+
+	rewards := []Reward{}
+	for {
+		select {
+		case row, ok := <-rowChannel:
+			if !ok {
+				rowChannel = nil
+			} else {
+				rewards = append(rewards, *rewardFromRow(row))
+			}
+		}
+		if rowChannel == nil {
+			break
+		}
+	}
+
+	jsonRows, err := json.Marshal(rewards)
+	if err != nil {
+		return nil, fmt.Errorf("getRows Premios operation failed. Error marshaling JSON: %s", err)
+	}
+
+	return jsonRows, nil
+}
+
+//redemptionHash - El ledger solo guarda el hash del codigo; el codigo lo genera el cliente y nunca llega al chaincode
+func redemptionHash(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToUpper(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
+
+//canUseRedemption - Entregan premios el administrador y quien opera como el comercio (atributo merchant)
+func canUseRedemption(stub shim.ChaincodeStubInterface, config *Config) bool {
+	if isAdmin(stub) {
+		return true
+	}
+	caller, err := stub.ReadCertAttribute("merchant")
+	if err != nil {
+		fmt.Printf("Error leyendo el atributo merchant: %s\n", err)
+		return false
+	}
+	return string(caller) == config.MerchantId
+}
+
+//getRedemptionRow - Obtiene un codigo de premio emitido por el hash del codigo, nil si no existe
+func getRedemptionRow(stub shim.ChaincodeStubInterface, hash string) (*Redemption, error) {
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Code"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: hash}}
+	columns = append(columns, col0)
+	columns = append(columns, col1)
+
+	row, err := stub.GetRow(tableRedemption, columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRow CodigosPremio operation failed. %s", err)
+	}
+	if len(row.Columns) == 0 {
+		return nil, nil
+	}
+
+	columnas := row.GetColumns()
+	price, _ := strconv.ParseFloat(columnas[4].GetString_(), 64)
+	return &Redemption{Hash: columnas[1].GetString_(), Reward: columnas[2].GetString_(), Wallet: columnas[3].GetString_(), Price: price, Time: columnas[5].GetInt64(), Used: columnas[6].GetInt64()}, nil
+}
+
+//putRedemption - Inserta o reemplaza la fila del codigo de premio, con el hash del codigo como clave
+func putRedemption(stub shim.ChaincodeStubInterface, redemption *Redemption, replace bool) error {
+	var columns []*shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Code"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: redemption.Hash}}
+	col2 := shim.Column{Value: &shim.Column_String_{String_: redemption.Reward}}
+	col3 := shim.Column{Value: &shim.Column_String_{String_: redemption.Wallet}}
+	col4 := shim.Column{Value: &shim.Column_String_{String_: strconv.FormatFloat(redemption.Price, 'f', 6, 64)}}
+	col5 := shim.Column{Value: &shim.Column_Int64{Int64: redemption.Time}}
+	col6 := shim.Column{Value: &shim.Column_Int64{Int64: redemption.Used}}
+	columns = append(columns, &col0)
+	columns = append(columns, &col1)
+	columns = append(columns, &col2)
+	columns = append(columns, &col3)
+	columns = append(columns, &col4)
+	columns = append(columns, &col5)
+	columns = append(columns, &col6)
+
+	row := shim.Row{Columns: columns}
+	var ok bool
+	var err error
+	if replace {
+		ok, err = stub.ReplaceRow(tableRedemption, row)
+	} else {
+		ok, err = stub.InsertRow(tableRedemption, row)
+	}
+	if err != nil {
+		return fmt.Errorf("Insert Row CodigosPremio operation failed. %s", err)
+	}
+	if !ok {
+		return errors.New("Fallo insertar Row CodigosPremio")
+	}
+	return nil
+}
+
+//redeemReward - Canjea un premio del catalogo: debita los coins de la wallet, descuenta stock
+//y registra el codigo de canje. args: wallet, premio, hash sha256 del codigo y opcionalmente tienda, terminal y cajero.
+//El codigo lo genera el cliente; con los datos del ledger no se puede reconstruir
+func (t *SimpleChaincode) redeemReward(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----redeemReward() is running----")
+
+	if len(args) != 3 && len(args) != 6 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 3 o 6")
+	}
+
+	attribution := Attribution{}
+	if len(args) == 6 {
+		attribution = Attribution{Store: args[3], Terminal: args[4], Cashier: args[5]}
+	}
+
+	hash := strings.ToLower(args[2])
+	if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+		return nil, errors.New("Hash de codigo de premio invalido: " + args[2])
+	}
+	existing, err := getRedemptionRow(stub, hash)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("El codigo de premio ya existe: " + hash)
+	}
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	reward, err := getReward(stub, args[1])
+	if err != nil {
+		return nil, err
+	}
+	if reward == nil {
+		return nil, errors.New("Premio desconocido: " + args[1])
+	}
+
+	a := makeTimestamp(stub)
+	if a < reward.ValidFrom || (reward.ValidTo != 0 && a >= reward.ValidTo) {
+		return nil, errors.New("El premio " + reward.Id + " no esta vigente")
+	}
+	if reward.Stock <= 0 {
+		return nil, errors.New("El premio " + reward.Id + " no tiene stock")
+	}
+
+	f := "debitbalance"
+	invokeArgs := util.ToChaincodeArgs(f, args[0], config.MerchantId, strconv.FormatFloat(reward.Price, 'f', 6, 64))
+	response, err := stub.InvokeChaincode(config.WalletContract, invokeArgs)
+	if err != nil {
+		errStr := fmt.Sprintf("Failed to invoke chaincode. Got error: %s", err.Error())
+		fmt.Println(errStr)
+		return nil, errors.New(errStr)
+	}
+
+	fmt.Printf("Invoke chaincode successful. Got response %s", string(response))
+
+	reward.Stock = reward.Stock - 1
+	err = putReward(stub, reward, true)
+	if err != nil {
+		return nil, err
+	}
+
+	redemption := Redemption{
+		Hash:   hash,
+		Reward: reward.Id,
+		Wallet: args[0],
+		Price:  reward.Price,
+		Time:   a,
+	}
+	err = putRedemption(stub, &redemption, false)
+	if err != nil {
+		return nil, err
+	}
+
+	//Los coins del premio los recibe el negocio como en un canje de buy. La fila es publica, guarda el hash del codigo
+	canje := Movement{
+		Time:      a,
+		Amount:    reward.Price,
+		Type:      "P",
+		Detail:    reward.Id,
+		Store:     attribution.Store,
+		Terminal:  attribution.Terminal,
+		Cashier:   attribution.Cashier,
+		Wallet:    args[0],
+		Reference: hash,
+	}
+	if !insertRow(stub, config.Business, canje) {
+		return nil, errors.New("Fallo registrar el canje del premio")
+	}
+
+	if !updateBalance(stub, reward.Price, 0) {
+		return nil, errors.New("Failed update balance")
+	}
+
+	return json.Marshal(redemption)
+}
+
+//useRedemption - Marca un codigo de premio como entregado, no se puede usar dos veces. Solo lo usa el comercio
+func (t *SimpleChaincode) useRedemption(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----useRedemption() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
+	}
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+	if !canUseRedemption(stub, config) {
+		return nil, errors.New("No autorizado para entregar premios del comercio " + config.MerchantId)
+	}
+
+	redemption, err := getRedemptionRow(stub, redemptionHash(args[0]))
+	if err != nil {
+		return nil, err
+	}
+	if redemption == nil {
+		return nil, errors.New("Codigo de premio desconocido: " + args[0])
+	}
+	if redemption.Used != 0 {
+		return nil, errors.New("El codigo de premio " + args[0] + " ya fue usado")
+	}
+
+	redemption.Used = makeTimestamp(stub)
+	err = putRedemption(stub, redemption, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(redemption)
+}
+
+//getRedemption - Consulta un codigo de premio emitido
+func (t *SimpleChaincode) getRedemption(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getRedemption() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
+	}
+
+	redemption, err := getRedemptionRow(stub, redemptionHash(args[0]))
+	if err != nil {
+		return nil, err
+	}
+	if redemption == nil {
+		return nil, errors.New("Codigo de premio desconocido: " + args[0])
+	}
+
+	return json.Marshal(redemption)
+}
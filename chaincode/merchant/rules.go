@@ -0,0 +1,121 @@
+/*
+  Merchant Smart Contract
+  Adrian Pareja
+*/
+package merchant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//Modos de redondeo de la acumulacion
+const (
+	roundNone    = "none"
+	roundDown    = "down"
+	roundUp      = "up"
+	roundNearest = "nearest"
+)
+
+//Tolerancia para comparar coins calculados con float64
+const coinEpsilon = 1e-6
+
+//RedemptionRules - Structure for the redemption rules of the merchant.
+//MaxPercent en 0 no limita el porcentaje, MinCoins y Step en 0 no aplican
+type RedemptionRules struct {
+	MaxPercent float64 `json:"maxpercent"`
+	MinCoins   float64 `json:"mincoins"`
+	Step       float64 `json:"step"`
+	Rounding   string  `json:"rounding"`
+}
+
+//checkRedemption - Valida los coins a canjear contra las reglas, total son los coins de toda la compra
+func checkRedemption(rules RedemptionRules, coins float64, total float64) error {
+	if coins <= 0 {
+		return nil
+	}
+	if rules.MinCoins > 0 && coins < rules.MinCoins-coinEpsilon {
+		return fmt.Errorf("El canje minimo es de %s coins", strconv.FormatFloat(rules.MinCoins, 'f', -1, 64))
+	}
+	if rules.Step > 0 {
+		steps := coins / rules.Step
+		if math.Abs(steps-math.Floor(steps+0.5)) > coinEpsilon {
+			return fmt.Errorf("Los coins a canjear deben ser multiplo de %s", strconv.FormatFloat(rules.Step, 'f', -1, 64))
+		}
+	}
+	if rules.MaxPercent > 0 && coins > total*rules.MaxPercent/100+coinEpsilon {
+		return fmt.Errorf("Los coins solo pueden pagar hasta el %s%% de la compra (%s coins)", strconv.FormatFloat(rules.MaxPercent, 'f', -1, 64), strconv.FormatFloat(total*rules.MaxPercent/100, 'f', 6, 64))
+	}
+	return nil
+}
+
+//roundingMode - Redondeo de la acumulacion que aplica el wallet despues del bonus del tier, none si no se configuro
+func roundingMode(rules RedemptionRules) string {
+	if rules.Rounding == "" {
+		return roundNone
+	}
+	return rules.Rounding
+}
+
+//setRedemptionRules - Configura las reglas de canje: porcentaje maximo, canje minimo, multiplo y redondeo
+func (t *SimpleChaincode) setRedemptionRules(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----setRedemptionRules() is running----")
+
+	if len(args) != 4 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 4")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede configurar las reglas de canje")
+	}
+
+	var values [3]float64
+	for i, arg := range args[:3] {
+		value, err := strconv.ParseFloat(arg, 64)
+		if err != nil || value < 0 {
+			return nil, errors.New("Valor invalido: " + arg)
+		}
+		values[i] = value
+	}
+	if values[0] > 100 {
+		return nil, errors.New("El porcentaje maximo no puede pasar de 100")
+	}
+	if args[3] != roundNone && args[3] != roundDown && args[3] != roundUp && args[3] != roundNearest {
+		return nil, errors.New("Modo de redondeo invalido: " + args[3])
+	}
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Rules = RedemptionRules{
+		MaxPercent: values[0],
+		MinCoins:   values[1],
+		Step:       values[2],
+		Rounding:   args[3],
+	}
+
+	err = putConfig(stub, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(`{"code":0,"response":null}`), nil
+}
+
+//getRedemptionRules - Obtiene las reglas de canje del comercio
+func (t *SimpleChaincode) getRedemptionRules(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getRedemptionRules() is running----")
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(config.Rules)
+}
@@ -0,0 +1,145 @@
+/*
+  Merchant Smart Contract
+  Adrian Pareja
+*/
+package merchant
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/util"
+)
+
+//VoucherRefund - Structure for the closevoucherbatch response of the wallet
+type VoucherRefund struct {
+	Code   int32  `json:"code"`
+	Refund string `json:"refund"`
+	Batch  string `json:"batch"`
+}
+
+//addBalance - Suma amount al balance de coins del negocio, o lo resta si es negativo y alcanza
+func addBalance(stub shim.ChaincodeStubInterface, amount float64) (*Balance, error) {
+	bytesBalance, err := stub.GetState("coinBalance")
+	if err != nil {
+		fmt.Println("Error retrieving balance")
+		return nil, errors.New("Error retrieving coinBalance")
+	}
+
+	balance := Balance{}
+	err = json.Unmarshal(bytesBalance, &balance)
+	if err != nil {
+		fmt.Println("Error parsing json")
+		return nil, errors.New("Error unmarshaling coinBalance")
+	}
+	if balance.Total+amount < 0 {
+		return nil, errors.New("El negocio no cuenta con coins suficientes para el lote")
+	}
+
+	balance.Total = balance.Total + amount
+	balanceJSONasBytes, _ := json.Marshal(balance)
+	err = stub.PutState("coinBalance", balanceJSONasBytes)
+	if err != nil {
+		return nil, errors.New("Error actualizando el balance del negocio")
+	}
+	return &balance, nil
+}
+
+//issueVouchers - Emite en el wallet un lote de cupones pagado por el comercio: lote, valor en coins, vencimiento,
+//tope por wallet y los hashes. El valor de todos los cupones sale del balance del negocio y queda retenido en el lote
+func (t *SimpleChaincode) issueVouchers(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----issueVouchers() is running----")
+
+	if len(args) < 5 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba al menos 5")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede emitir cupones del comercio")
+	}
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || !(value > 0) || math.IsInf(value, 0) {
+		return nil, errors.New("Valor de cupon invalido: " + args[1])
+	}
+	escrow := value * float64(len(args)-4)
+
+	_, err = addBalance(stub, -escrow)
+	if err != nil {
+		return nil, err
+	}
+
+	//Los coins pasan a coinBalance y el wallet los retiene en el lote al emitirlo
+	f := "puttotalcoin"
+	invokeArgs := util.ToChaincodeArgs(f, strconv.FormatFloat(escrow, 'f', 6, 64))
+	_, err = stub.InvokeChaincode(config.WalletContract, invokeArgs)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to invoke chaincode. Got error: %s", err.Error())
+	}
+
+	walletArgs := append([]string{"issuevouchers", args[0], args[1], args[2], args[3], config.MerchantId}, args[4:]...)
+	response, err := stub.InvokeChaincode(config.WalletContract, util.ToChaincodeArgs(walletArgs...))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to invoke chaincode. Got error: %s", err.Error())
+	}
+
+	if !insertRow(stub, config.Business, Movement{Time: makeTimestamp(stub), Amount: escrow, Type: "L", Detail: args[0]}) {
+		return nil, errors.New("Fallo registrar la retencion del lote")
+	}
+
+	return response, nil
+}
+
+//reclaimVouchers - Recupera los coins retenidos por un lote vencido del comercio: lote
+func (t *SimpleChaincode) reclaimVouchers(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----reclaimVouchers() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede recuperar cupones del comercio")
+	}
+
+	config, err := getConfig(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	f := "closevoucherbatch"
+	response, err := stub.InvokeChaincode(config.WalletContract, util.ToChaincodeArgs(f, args[0]))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to invoke chaincode. Got error: %s", err.Error())
+	}
+	refund := VoucherRefund{}
+	err = json.Unmarshal(response, &refund)
+	if err != nil {
+		return nil, fmt.Errorf("Respuesta invalida del wallet: %s", response)
+	}
+	amount, _ := strconv.ParseFloat(refund.Refund, 64)
+
+	if amount > 0 {
+		f = "debittotalcoin"
+		_, err = stub.InvokeChaincode(config.WalletContract, util.ToChaincodeArgs(f, refund.Refund))
+		if err != nil {
+			return nil, fmt.Errorf("Failed to invoke chaincode. Got error: %s", err.Error())
+		}
+		if !insertRow(stub, config.Business, Movement{Time: makeTimestamp(stub), Amount: amount, Type: "E", Detail: args[0]}) {
+			return nil, errors.New("Fallo registrar la devolucion del lote")
+		}
+	}
+	balance, err := addBalance(stub, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf(`{"code":0,"refund":"%s","balance":"%s"}`, strconv.FormatFloat(amount, 'f', 6, 64), strconv.FormatFloat(balance.Total, 'f', 6, 64))), nil
+}
@@ -0,0 +1,396 @@
+/*
+* Adrian Pareja
+ */
+package wallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//Voucher de acumulacion que el comercio firma sin conexion:
+//LV1:<comercio>:<id>:<coins>:<vencimiento>:<firma>
+//comercio e id van escapados como query de URL, el vencimiento en milisegundos y la firma es ECDSA (ASN.1 DER en
+//base64 URL sin relleno) sobre el sha256 de todo lo anterior, incluido el ultimo ":"
+const earnVoucherVersion = "LV1"
+
+//EarnVoucher - Structure for a merchant signed earn voucher
+type EarnVoucher struct {
+	Merchant string  `json:"merchant"`
+	Id       string  `json:"id"`
+	Amount   float64 `json:"amount"`
+	Expiry   int64   `json:"expiry"`
+}
+
+//EarnVoucherRedemption - Structure for a redeemed earn voucher. Se guarda por comercio e id
+type EarnVoucherRedemption struct {
+	EarnVoucher
+	Wallet string `json:"wallet"`
+	Time   int64  `json:"time"`
+}
+
+//EarnVoucherResult - Structure for the result of redeemearnvoucher
+type EarnVoucherResult struct {
+	Code    int32                 `json:"code"`
+	Balance string                `json:"balance"`
+	Voucher EarnVoucherRedemption `json:"voucher"`
+}
+
+//ecdsaSignature - Firma ECDSA en ASN.1
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+//verifySignature - Valida la firma DER sobre el sha256 del texto
+func verifySignature(key *ecdsa.PublicKey, signed []byte, signature []byte) bool {
+	parsed := ecdsaSignature{}
+	rest, err := asn1.Unmarshal(signature, &parsed)
+	if err != nil || len(rest) != 0 || parsed.R == nil || parsed.S == nil || parsed.R.Sign() <= 0 || parsed.S.Sign() <= 0 {
+		return false
+	}
+	digest := sha256.Sum256(signed)
+	return ecdsa.Verify(key, digest[:], parsed.R, parsed.S)
+}
+
+//MerchantKey - Structure for the public key registered for a merchant
+type MerchantKey struct {
+	Merchant string `json:"merchant"`
+	Key      string `json:"key"`
+}
+
+//EarnCap - Structure for the coins a merchant can hand out with earn vouchers. Issued es lo acreditado hasta ahora
+type EarnCap struct {
+	Merchant string  `json:"merchant"`
+	Cap      float64 `json:"cap"`
+	Issued   float64 `json:"issued"`
+}
+
+func earnCapKey(merchantId string) string {
+	return "earnCap:" + merchantId
+}
+
+//getEarnCap - Obtiene el tope de vouchers de acumulacion del comercio, con tope 0 si no tiene
+func getEarnCap(stub shim.ChaincodeStubInterface, merchantId string) (*EarnCap, error) {
+	bytes, err := stub.GetState(earnCapKey(merchantId))
+	if err != nil {
+		return nil, errors.New("Error retrieving " + earnCapKey(merchantId))
+	}
+	earnCap := EarnCap{Merchant: merchantId}
+	if bytes == nil {
+		return &earnCap, nil
+	}
+	err = json.Unmarshal(bytes, &earnCap)
+	if err != nil {
+		return nil, fmt.Errorf("Error parseando el tope de vouchers. %s", err)
+	}
+	return &earnCap, nil
+}
+
+func putEarnCap(stub shim.ChaincodeStubInterface, earnCap *EarnCap) error {
+	bytes, err := json.Marshal(earnCap)
+	if err != nil {
+		return errors.New("Error marshaling earn cap")
+	}
+	return stub.PutState(earnCapKey(earnCap.Merchant), bytes)
+}
+
+func merchantKeyKey(merchantId string) string {
+	return "merchantKey:" + merchantId
+}
+
+func earnVoucherKey(voucher *EarnVoucher) string {
+	return "earnVoucher:" + url.QueryEscape(voucher.Merchant) + ":" + url.QueryEscape(voucher.Id)
+}
+
+//parseMerchantKey - Llave publica ECDSA en PEM o en base64 del DER PKIX
+func parseMerchantKey(text string) (*ecdsa.PublicKey, []byte, error) {
+	var der []byte
+	block, _ := pem.Decode([]byte(text))
+	if block != nil {
+		der = block.Bytes
+	} else {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+		if err != nil {
+			return nil, nil, errors.New("Llave publica invalida")
+		}
+		der = decoded
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, nil, errors.New("Llave publica invalida")
+	}
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, nil, errors.New("La llave publica debe ser ECDSA")
+	}
+	return ecKey, der, nil
+}
+
+//getMerchantKey - Obtiene la llave publica registrada del comercio, nil si no tiene
+func getMerchantKey(stub shim.ChaincodeStubInterface, merchantId string) (*ecdsa.PublicKey, error) {
+	bytes, err := stub.GetState(merchantKeyKey(merchantId))
+	if err != nil {
+		return nil, errors.New("Error retrieving " + merchantKeyKey(merchantId))
+	}
+	if bytes == nil {
+		return nil, nil
+	}
+	key, _, err := parseMerchantKey(string(bytes))
+	return key, err
+}
+
+//decodeEarnVoucher - Valida el formato del voucher y devuelve el texto firmado y la firma
+func decodeEarnVoucher(text string) (*EarnVoucher, []byte, []byte, error) {
+	text = strings.TrimSpace(text)
+	i := strings.LastIndex(text, ":")
+	if i < 0 {
+		return nil, nil, nil, errors.New("Voucher de acumulacion invalido")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(text[i+1:])
+	if err != nil || len(signature) == 0 {
+		return nil, nil, nil, errors.New("Firma del voucher invalida")
+	}
+
+	parts := strings.Split(text[:i], ":")
+	if parts[0] != earnVoucherVersion {
+		return nil, nil, nil, errors.New("Version de voucher no soportada: " + parts[0])
+	}
+	if len(parts) != 5 {
+		return nil, nil, nil, errors.New("Voucher de acumulacion invalido")
+	}
+	merchant, err := url.QueryUnescape(parts[1])
+	if err != nil || merchant == "" {
+		return nil, nil, nil, errors.New("Comercio del voucher invalido: " + parts[1])
+	}
+	id, err := url.QueryUnescape(parts[2])
+	if err != nil || id == "" {
+		return nil, nil, nil, errors.New("Id del voucher invalido: " + parts[2])
+	}
+	amount, err := strconv.ParseFloat(parts[3], 64)
+	if err != nil || !(amount > 0) || math.IsInf(amount, 0) {
+		return nil, nil, nil, errors.New("Monto invalido: " + parts[3])
+	}
+	expiry, err := strconv.ParseInt(parts[4], 10, 64)
+	if err != nil {
+		return nil, nil, nil, errors.New("Vencimiento invalido: " + parts[4])
+	}
+
+	voucher := EarnVoucher{Merchant: merchant, Id: id, Amount: amount, Expiry: expiry}
+	return &voucher, []byte(text[:i+1]), signature, nil
+}
+
+//setMerchantKey - Registra la llave publica ECDSA con la que el comercio firma sus vouchers: comercio y llave
+//en PEM o base64. Reemplaza la anterior, y los vouchers firmados con ella dejan de valer
+func (t *SimpleChaincode) setMerchantKey(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call---Funcion setMerchantKey---")
+
+	if len(args) != 2 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 2 para setMerchantKey")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede registrar llaves de comercios")
+	}
+
+	merchant, err := getMerchant(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if merchant == nil {
+		return nil, errors.New("Comercio desconocido: " + args[0])
+	}
+
+	_, der, err := parseMerchantKey(args[1])
+	if err != nil {
+		return nil, err
+	}
+	key := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	err = stub.PutState(merchantKeyKey(args[0]), key)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(`{"code":0,"response":null}`), nil
+}
+
+//setEarnCap - Fija cuantos coins puede entregar en total el comercio con vouchers de acumulacion: comercio y tope.
+//Lo ya acreditado se mantiene; un tope menor que lo acreditado detiene los canjes hasta subirlo
+func (t *SimpleChaincode) setEarnCap(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call---Funcion setEarnCap---")
+
+	if len(args) != 2 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 2 para setEarnCap")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede fijar el tope de vouchers de un comercio")
+	}
+
+	merchant, err := getMerchant(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if merchant == nil {
+		return nil, errors.New("Comercio desconocido: " + args[0])
+	}
+
+	limit, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || !(limit >= 0) || math.IsInf(limit, 0) {
+		return nil, errors.New("Tope invalido: " + args[1])
+	}
+
+	earnCap, err := getEarnCap(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	earnCap.Cap = limit
+	err = putEarnCap(stub, earnCap)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(earnCap)
+}
+
+//redeemEarnVoucher - Acredita en el wallet un voucher de acumulacion firmado por el comercio: wallet y voucher.
+//Los coins se cargan como en putbalance, se liquidan como emitidos por el comercio y se descuentan de su tope,
+//asi una llave filtrada no puede acreditar coins sin limite
+func (t *SimpleChaincode) redeemEarnVoucher(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call---Funcion redeemEarnVoucher---")
+
+	if len(args) != 2 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 2 para redeemEarnVoucher")
+	}
+
+	voucher, signed, signature, err := decodeEarnVoucher(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	//Quien canjea es el cliente, por eso no se valida el caller del comercio
+	merchant, err := getMerchant(stub, voucher.Merchant)
+	if err != nil {
+		return nil, err
+	}
+	if merchant == nil {
+		return nil, errors.New("Comercio desconocido: " + voucher.Merchant)
+	}
+	if merchant.Status != merchantActive {
+		return nil, errors.New("Comercio suspendido: " + voucher.Merchant)
+	}
+
+	key, err := getMerchantKey(stub, voucher.Merchant)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, errors.New("El comercio no tiene una llave registrada: " + voucher.Merchant)
+	}
+	if !verifySignature(key, signed, signature) {
+		return nil, errors.New("Firma del voucher invalida")
+	}
+
+	a := makeTimestamp(stub)
+	if a >= voucher.Expiry {
+		return nil, errors.New("El voucher esta vencido")
+	}
+
+	bytesUse, err := stub.GetState(earnVoucherKey(voucher))
+	if err != nil {
+		return nil, errors.New("Error retrieving " + earnVoucherKey(voucher))
+	}
+	if bytesUse != nil {
+		return nil, errors.New("El voucher ya fue canjeado: " + voucher.Id)
+	}
+
+	if !walletExists(stub, args[0]) {
+		return nil, errors.New("Wallet desconocido: " + args[0])
+	}
+
+	earnCap, err := getEarnCap(stub, voucher.Merchant)
+	if err != nil {
+		return nil, err
+	}
+	if earnCap.Issued+voucher.Amount > earnCap.Cap {
+		return nil, fmt.Errorf("El comercio alcanzo su tope de vouchers de acumulacion, disponible: %s", strconv.FormatFloat(math.Max(earnCap.Cap-earnCap.Issued, 0), 'f', 6, 64))
+	}
+	earnCap.Issued = earnCap.Issued + voucher.Amount
+	err = putEarnCap(stub, earnCap)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = creditWallet(stub, args[0], voucher.Merchant, strconv.FormatFloat(voucher.Amount, 'f', -1, 64))
+	if err != nil {
+		return nil, err
+	}
+
+	redemption := EarnVoucherRedemption{EarnVoucher: *voucher, Wallet: args[0], Time: a}
+	bytes, err := json.Marshal(redemption)
+	if err != nil {
+		return nil, errors.New("Error marshaling voucher")
+	}
+	err = stub.PutState(earnVoucherKey(voucher), bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	bytesWallet, err := stub.GetState(args[0])
+	if err != nil {
+		return nil, errors.New("Error retrieving " + args[0])
+	}
+	wallet := Wallet{}
+	err = json.Unmarshal(bytesWallet, &wallet)
+	if err != nil {
+		return nil, errors.New("Error retrieving " + args[0])
+	}
+
+	return json.Marshal(EarnVoucherResult{Code: 0, Balance: strconv.FormatFloat(wallet.Amount, 'f', 6, 64), Voucher: redemption})
+}
+
+//getMerchantKeyInfo - Obtiene la llave publica registrada de un comercio
+func (t *SimpleChaincode) getMerchantKeyInfo(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getMerchantKey() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
+	}
+
+	bytes, err := stub.GetState(merchantKeyKey(args[0]))
+	if err != nil {
+		return nil, errors.New("Error retrieving " + merchantKeyKey(args[0]))
+	}
+	if bytes == nil {
+		return nil, errors.New("El comercio no tiene una llave registrada: " + args[0])
+	}
+
+	return json.Marshal(MerchantKey{Merchant: args[0], Key: string(bytes)})
+}
+
+//getEarnCapInfo - Obtiene el tope de vouchers de acumulacion de un comercio y lo ya acreditado
+func (t *SimpleChaincode) getEarnCapInfo(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getEarnCap() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
+	}
+
+	earnCap, err := getEarnCap(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(earnCap)
+}
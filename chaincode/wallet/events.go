@@ -0,0 +1,35 @@
+/*
+* Adrian Pareja
+ */
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//Eventos del contrato. Fabric entrega un solo evento por transaccion
+const (
+	eventCreateWallet = "createWallet"
+	eventBalance      = "balanceEvent"
+)
+
+//BalanceEvent - Structure for the payload of balanceEvent: the movements written by the transaction
+type BalanceEvent struct {
+	Movements []Movement `json:"movements"`
+}
+
+//setBalanceEvent - Envia el evento con los movimientos que cambiaron saldos de wallets
+func setBalanceEvent(stub shim.ChaincodeStubInterface, movements ...Movement) error {
+	payload, err := json.Marshal(BalanceEvent{Movements: movements})
+	if err != nil {
+		return errors.New("Error marshaling balance event")
+	}
+	err = stub.SetEvent(eventBalance, payload)
+	if err != nil {
+		return errors.New("Fallo enviar el evento de saldo")
+	}
+	return nil
+}
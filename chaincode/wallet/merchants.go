@@ -0,0 +1,284 @@
+/*
+* Adrian Pareja
+ */
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	tableMerchant      = "Comercios"
+	columnMerchantId   = "Id"
+	columnMerchantName = "Name"
+	columnStatus       = "Status"
+	columnCaller       = "Caller"
+)
+
+const (
+	merchantActive    = "active"
+	merchantSuspended = "suspended"
+)
+
+//Atributos del certificado usados para autorizar
+const (
+	roleAttribute     = "role"
+	roleAdmin         = "admin"
+	merchantAttribute = "merchant"
+	walletAttribute   = "wallet"
+)
+
+//Merchant - Structure for registered merchants
+type Merchant struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Caller string `json:"caller"`
+}
+
+//isAdmin - Valida que quien invoca tenga el rol de administrador
+func isAdmin(stub shim.ChaincodeStubInterface) bool {
+	role, err := stub.ReadCertAttribute(roleAttribute)
+	if err != nil {
+		fmt.Printf("Error leyendo el atributo %s: %s\n", roleAttribute, err)
+		return false
+	}
+	return string(role) == roleAdmin
+}
+
+//getMerchant - Obtiene un comercio registrado, nil si no existe
+func getMerchant(stub shim.ChaincodeStubInterface, merchantId string) (*Merchant, error) {
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Merchant"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: merchantId}}
+	columns = append(columns, col0)
+	columns = append(columns, col1)
+
+	row, err := stub.GetRow(tableMerchant, columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRow Comercios operation failed. %s", err)
+	}
+	if len(row.Columns) == 0 {
+		return nil, nil
+	}
+
+	return merchantFromRow(row), nil
+}
+
+func merchantFromRow(row shim.Row) *Merchant {
+	columnas := row.GetColumns()
+	return &Merchant{Id: columnas[1].GetString_(), Name: columnas[2].GetString_(), Status: columnas[3].GetString_(), Caller: columnas[4].GetString_()}
+}
+
+//putMerchant - Inserta o reemplaza la fila del comercio
+func putMerchant(stub shim.ChaincodeStubInterface, merchant *Merchant, replace bool) error {
+	var columns []*shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Merchant"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: merchant.Id}}
+	col2 := shim.Column{Value: &shim.Column_String_{String_: merchant.Name}}
+	col3 := shim.Column{Value: &shim.Column_String_{String_: merchant.Status}}
+	col4 := shim.Column{Value: &shim.Column_String_{String_: merchant.Caller}}
+	columns = append(columns, &col0)
+	columns = append(columns, &col1)
+	columns = append(columns, &col2)
+	columns = append(columns, &col3)
+	columns = append(columns, &col4)
+
+	row := shim.Row{Columns: columns}
+	var ok bool
+	var err error
+	if replace {
+		ok, err = stub.ReplaceRow(tableMerchant, row)
+	} else {
+		ok, err = stub.InsertRow(tableMerchant, row)
+	}
+	if err != nil {
+		return fmt.Errorf("Insert Row Comercios operation failed. %s", err)
+	}
+	if !ok {
+		return errors.New("Fallo insertar Row Comercios")
+	}
+	return nil
+}
+
+//checkMerchant - Valida que el comercio exista, este activo y que quien invoca sea el autorizado
+func checkMerchant(stub shim.ChaincodeStubInterface, merchantId string) (*Merchant, error) {
+	merchant, err := getMerchant(stub, merchantId)
+	if err != nil {
+		return nil, err
+	}
+	if merchant == nil {
+		return nil, errors.New("Comercio desconocido: " + merchantId)
+	}
+	if merchant.Status != merchantActive {
+		return nil, errors.New("Comercio suspendido: " + merchantId)
+	}
+	//Un comercio sin caller no lo puede operar nadie
+	caller, err := stub.ReadCertAttribute(merchantAttribute)
+	if err != nil || merchant.Caller == "" || string(caller) != merchant.Caller {
+		return nil, errors.New("No autorizado para operar como el comercio " + merchantId)
+	}
+	return merchant, nil
+}
+
+//registerMerchant - Registra un nuevo comercio: id, nombre y caller autorizado. La tasa de acumulacion es la del
+//chaincode del comercio
+func (t *SimpleChaincode) registerMerchant(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call---Funcion registerMerchant---")
+
+	if len(args) != 3 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 3 para registerMerchant")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede registrar comercios")
+	}
+	if args[2] == "" {
+		return nil, errors.New("El caller del comercio es obligatorio")
+	}
+
+	existing, err := getMerchant(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("El comercio ya existe")
+	}
+
+	merchant := Merchant{
+		Id:     args[0],
+		Name:   args[1],
+		Status: merchantActive,
+		Caller: args[2],
+	}
+
+	err = putMerchant(stub, &merchant, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(`{"code":0,"response":null}`), nil
+}
+
+//updateMerchant - Actualiza nombre y caller autorizado de un comercio
+func (t *SimpleChaincode) updateMerchant(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call---Funcion updateMerchant---")
+
+	if len(args) != 3 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 3 para updateMerchant")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede actualizar comercios")
+	}
+	if args[2] == "" {
+		return nil, errors.New("El caller del comercio es obligatorio")
+	}
+
+	merchant, err := getMerchant(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if merchant == nil {
+		return nil, errors.New("Comercio desconocido: " + args[0])
+	}
+
+	merchant.Name = args[1]
+	merchant.Caller = args[2]
+
+	err = putMerchant(stub, merchant, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(`{"code":0,"response":null}`), nil
+}
+
+//setMerchantStatus - Activa o suspende un comercio
+func (t *SimpleChaincode) setMerchantStatus(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call---Funcion setMerchantStatus---")
+
+	if len(args) != 2 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 2 para setMerchantStatus")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede cambiar el estado de un comercio")
+	}
+	if args[1] != merchantActive && args[1] != merchantSuspended {
+		return nil, errors.New("Estado de comercio invalido: " + args[1])
+	}
+
+	merchant, err := getMerchant(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if merchant == nil {
+		return nil, errors.New("Comercio desconocido: " + args[0])
+	}
+
+	merchant.Status = args[1]
+
+	err = putMerchant(stub, merchant, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(`{"code":0,"response":null}`), nil
+}
+
+//getMerchantInfo - Obtiene los datos de un comercio registrado
+func (t *SimpleChaincode) getMerchantInfo(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getMerchant() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
+	}
+
+	merchant, err := getMerchant(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if merchant == nil {
+		return nil, errors.New("Comercio desconocido: " + args[0])
+	}
+
+	return json.Marshal(merchant)
+}
+
+//getMerchants - Obtiene todos los comercios registrados
+func (t *SimpleChaincode) getMerchants(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getMerchants() is running----")
+
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Merchant"}}
+	columns = append(columns, col0)
+
+	rowChannel, err := stub.GetRows(tableMerchant, columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRows Comercios operation failed. %s", err)
+	}
+
+	merchants := []Merchant{}
+	for {
+		select {
+		case row, ok := <-rowChannel:
+			if !ok {
+				rowChannel = nil
+			} else {
+				merchants = append(merchants, *merchantFromRow(row))
+			}
+		}
+		if rowChannel == nil {
+			break
+		}
+	}
+
+	jsonRows, err := json.Marshal(merchants)
+	if err != nil {
+		return nil, fmt.Errorf("getRows Comercios operation failed. Error marshaling JSON: %s", err)
+	}
+
+	return jsonRows, nil
+}
@@ -0,0 +1,241 @@
+/*
+* Adrian Pareja
+ */
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//Intencion de pago para codigos QR:
+//LP1:<tipo>:<beneficiario>:<monto>:<referencia>:<vencimiento>:<checksum>
+//tipo es M (pago a comercio) o W (pago a otro wallet), beneficiario y referencia van escapados como query de URL,
+//el vencimiento en milisegundos y el checksum es el CRC-32 en hex de todo lo anterior, incluido el ultimo ":".
+//El checksum detecta errores de lectura o tipeo, no autentica al beneficiario
+const (
+	payIntentVersion  = "LP1"
+	payIntentMerchant = "M"
+	payIntentWallet   = "W"
+)
+
+const (
+	paymentPending = "pending"
+	paymentPaid    = "paid"
+	paymentExpired = "expired"
+)
+
+//PayIntent - Structure for a decoded payment intent
+type PayIntent struct {
+	Kind      string  `json:"kind"`
+	Payee     string  `json:"payee"`
+	Amount    float64 `json:"amount"`
+	Reference string  `json:"reference"`
+	Expiry    int64   `json:"expiry"`
+}
+
+//Payment - Structure for the state of a payment intent. Se guarda al pagar con la intencion completa como clave
+type Payment struct {
+	PayIntent
+	Status string `json:"status"`
+	Payer  string `json:"payer,omitempty"`
+	Time   int64  `json:"time,omitempty"`
+}
+
+//PayIntentResult - Structure for the result of payintent
+type PayIntentResult struct {
+	Code    int32   `json:"code"`
+	Balance string  `json:"balance"`
+	Payment Payment `json:"payment"`
+}
+
+//payIntentChecksum - CRC-32 del texto de la intencion hasta el ultimo separador
+func payIntentChecksum(text string) string {
+	return fmt.Sprintf("%08X", crc32.ChecksumIEEE([]byte(text)))
+}
+
+//decodePayIntent - Valida version, checksum y campos de una intencion de pago
+func decodePayIntent(text string) (*PayIntent, error) {
+	text = strings.TrimSpace(text)
+	i := strings.LastIndex(text, ":")
+	if i < 0 {
+		return nil, errors.New("Intencion de pago invalida")
+	}
+	if strings.ToUpper(text[i+1:]) != payIntentChecksum(text[:i+1]) {
+		return nil, errors.New("Checksum de la intencion de pago invalido")
+	}
+
+	parts := strings.Split(text[:i], ":")
+	if parts[0] != payIntentVersion {
+		return nil, errors.New("Version de intencion de pago no soportada: " + parts[0])
+	}
+	if len(parts) != 6 {
+		return nil, errors.New("Intencion de pago invalida")
+	}
+	if parts[1] != payIntentMerchant && parts[1] != payIntentWallet {
+		return nil, errors.New("Tipo de intencion de pago invalido: " + parts[1])
+	}
+	payee, err := url.QueryUnescape(parts[2])
+	if err != nil || payee == "" {
+		return nil, errors.New("Beneficiario invalido: " + parts[2])
+	}
+	amount, err := strconv.ParseFloat(parts[3], 64)
+	if err != nil || !(amount > 0) || math.IsInf(amount, 0) {
+		return nil, errors.New("Monto invalido: " + parts[3])
+	}
+	reference, err := url.QueryUnescape(parts[4])
+	if err != nil || reference == "" {
+		return nil, errors.New("Referencia invalida: " + parts[4])
+	}
+	expiry, err := strconv.ParseInt(parts[5], 10, 64)
+	if err != nil {
+		return nil, errors.New("Vencimiento invalido: " + parts[5])
+	}
+
+	return &PayIntent{Kind: parts[1], Payee: payee, Amount: amount, Reference: reference, Expiry: expiry}, nil
+}
+
+//payIntentKey - Clave del pago. Es la intencion completa: como el checksum no autentica al beneficiario,
+//cualquiera puede armar una intencion con la referencia de otra, y con una clave solo por referencia un pago
+//minimo bloquearia el QR real. El beneficiario concilia por referencia los pagos de QR regenerados
+func payIntentKey(intent *PayIntent) string {
+	return "payIntent:" + intent.Kind + ":" + url.QueryEscape(intent.Payee) + ":" + url.QueryEscape(intent.Reference) + ":" + strconv.FormatFloat(intent.Amount, 'f', -1, 64) + ":" + strconv.FormatInt(intent.Expiry, 10)
+}
+
+//getPayment - Obtiene el pago de una intencion, nil si no fue pagada
+func getPayment(stub shim.ChaincodeStubInterface, intent *PayIntent) (*Payment, error) {
+	key := payIntentKey(intent)
+	bytes, err := stub.GetState(key)
+	if err != nil {
+		return nil, errors.New("Error retrieving " + key)
+	}
+	if bytes == nil {
+		return nil, nil
+	}
+	payment := Payment{}
+	err = json.Unmarshal(bytes, &payment)
+	if err != nil {
+		return nil, fmt.Errorf("Error parseando el pago. %s", err)
+	}
+	return &payment, nil
+}
+
+//payIntent - Paga una intencion leida de un QR desde el wallet del cliente: wallet e intencion.
+//Un pago a comercio debita los coins como debitbalance y un pago a wallet es una transferencia
+func (t *SimpleChaincode) payIntent(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call---Funcion payIntent---")
+
+	if len(args) != 2 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 2 para payIntent")
+	}
+
+	intent, err := decodePayIntent(args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	a := makeTimestamp(stub)
+	if a >= intent.Expiry {
+		return nil, errors.New("La intencion de pago esta vencida")
+	}
+
+	payment, err := getPayment(stub, intent)
+	if err != nil {
+		return nil, err
+	}
+	if payment != nil {
+		return nil, errors.New("La intencion de pago ya fue pagada: " + intent.Reference)
+	}
+
+	if !walletExists(stub, args[0]) {
+		return nil, errors.New("Wallet desconocido: " + args[0])
+	}
+	//Solo el duenio del wallet puede pagar con el, su certificado lleva el id del wallet
+	payer, err := stub.ReadCertAttribute(walletAttribute)
+	if err != nil || string(payer) != args[0] {
+		return nil, errors.New("No autorizado para pagar desde el wallet " + args[0])
+	}
+
+	amount := strconv.FormatFloat(intent.Amount, 'f', -1, 64)
+	if intent.Kind == payIntentWallet {
+		if intent.Payee == args[0] {
+			return nil, errors.New("El wallet no puede pagarse a si mismo")
+		}
+		if !walletExists(stub, intent.Payee) {
+			return nil, errors.New("Wallet desconocido: " + intent.Payee)
+		}
+		_, err = t.transfer(stub, []string{intent.Payee, args[0], amount})
+	} else {
+		//Quien paga es el cliente, validado arriba, por eso no se valida el caller del comercio
+		merchant, err1 := getMerchant(stub, intent.Payee)
+		if err1 != nil {
+			return nil, err1
+		}
+		if merchant == nil {
+			return nil, errors.New("Comercio desconocido: " + intent.Payee)
+		}
+		if merchant.Status != merchantActive {
+			return nil, errors.New("Comercio suspendido: " + intent.Payee)
+		}
+		_, err = debitWallet(stub, args[0], intent.Payee, amount)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	payment = &Payment{PayIntent: *intent, Status: paymentPaid, Payer: args[0], Time: a}
+	bytes, err := json.Marshal(payment)
+	if err != nil {
+		return nil, errors.New("Error marshaling payment")
+	}
+	err = stub.PutState(payIntentKey(intent), bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	bytesWallet, err := stub.GetState(args[0])
+	if err != nil {
+		return nil, errors.New("Error retrieving " + args[0])
+	}
+	wallet := Wallet{}
+	err = json.Unmarshal(bytesWallet, &wallet)
+	if err != nil {
+		return nil, errors.New("Error retrieving " + args[0])
+	}
+
+	return json.Marshal(PayIntentResult{Code: 0, Balance: strconv.FormatFloat(wallet.Amount, 'f', 6, 64), Payment: *payment})
+}
+
+//getPayIntent - Estado de una intencion de pago: pending, paid o expired
+func (t *SimpleChaincode) getPayIntent(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getPayIntent() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
+	}
+
+	intent, err := decodePayIntent(args[0])
+	if err != nil {
+		return nil, err
+	}
+	payment, err := getPayment(stub, intent)
+	if err != nil {
+		return nil, err
+	}
+	if payment == nil {
+		payment = &Payment{PayIntent: *intent, Status: paymentPending}
+		if makeTimestamp(stub) >= intent.Expiry {
+			payment.Status = paymentExpired
+		}
+	}
+
+	return json.Marshal(payment)
+}
@@ -0,0 +1,213 @@
+/*
+* Adrian Pareja
+ */
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//PurchaseResult - Structure for the result of a purchase
+type PurchaseResult struct {
+	Code      int32  `json:"code"`
+	Balance   string `json:"balance"`
+	Limit     string `json:"limit"`
+	Tier      string `json:"tier"`
+	Redeemed  string `json:"redeemed"`
+	Earned    string `json:"earned"`
+	RedeemRef string `json:"redeemref"`
+	EarnRef   string `json:"earnref"`
+}
+
+//Modos de redondeo de la acumulacion, los mismos de las reglas de canje del comercio
+const (
+	roundNone    = "none"
+	roundDown    = "down"
+	roundUp      = "up"
+	roundNearest = "nearest"
+)
+
+//roundEarn - Redondea los coins acumulados, ya con el bonus del tier, a coins enteros segun el modo
+func roundEarn(mode string, earn float64) float64 {
+	switch mode {
+	case roundDown:
+		return math.Floor(earn + 1e-6)
+	case roundUp:
+		return math.Ceil(earn - 1e-6)
+	case roundNearest:
+		return math.Floor(earn + 0.5)
+	}
+	return earn
+}
+
+//movementRef - Referencia a una fila de Movimientos: wallet y tiempo
+func movementRef(walletId string, time int64) string {
+	return fmt.Sprintf("%s:%d", walletId, time)
+}
+
+//purchase - Canje y acumulacion de una compra en una sola transaccion.
+//args: wallet, comercio, coins a canjear, coins a acumular antes del bonus del tier
+//y opcionalmente tienda, terminal y cajero, la moneda ISO 4217 con los montos en unidades menores
+//pagados con coins y en efectivo, y el redondeo de la acumulacion (none, down, up o nearest)
+func (t *SimpleChaincode) purchase(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call---Funcion Purchase---")
+	if len(args) != 4 && len(args) != 7 && len(args) != 10 && len(args) != 11 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 4, 7, 10 u 11 para purchase")
+	}
+
+	rounding := roundNone
+	if len(args) == 11 {
+		rounding = args[10]
+		if rounding != roundNone && rounding != roundDown && rounding != roundUp && rounding != roundNearest {
+			return nil, errors.New("Modo de redondeo invalido: " + rounding)
+		}
+	}
+
+	attribution := Attribution{}
+	if len(args) >= 7 {
+		attribution = Attribution{Store: args[4], Terminal: args[5], Cashier: args[6]}
+	}
+
+	redeemMoney := Money{}
+	earnMoney := Money{}
+	if len(args) >= 10 {
+		redeemMinor, err := strconv.ParseInt(args[8], 10, 64)
+		if err != nil || redeemMinor < 0 {
+			return nil, errors.New("Monto canjeado invalido: " + args[8])
+		}
+		paidMinor, err := strconv.ParseInt(args[9], 10, 64)
+		if err != nil || paidMinor < 0 {
+			return nil, errors.New("Monto pagado invalido: " + args[9])
+		}
+		redeemMoney = Money{Currency: args[7], Minor: redeemMinor}
+		earnMoney = Money{Currency: args[7], Minor: paidMinor}
+	}
+
+	fmt.Printf("WalletId: %s\n", args[0])
+	fmt.Printf("Business: %s\n", args[1])
+	fmt.Printf("Canje: %s Acumulacion: %s\n", args[2], args[3])
+
+	_, err := checkMerchant(stub, args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	redeem, err := parseCoins(args[2], true)
+	if err != nil {
+		return nil, errors.New("Coins a canjear invalidos: " + args[2])
+	}
+	earn, err := parseCoins(args[3], true)
+	if err != nil {
+		return nil, errors.New("Coins a acumular invalidos: " + args[3])
+	}
+
+	bytesWallet, err := stub.GetState(args[0])
+	if err != nil || bytesWallet == nil {
+		fmt.Println("Error retrieving " + args[0])
+		return nil, errors.New("Error retrieving " + args[0])
+	}
+	wallet := Wallet{}
+	err = json.Unmarshal(bytesWallet, &wallet)
+	if err != nil {
+		fmt.Println("Error parseando a Json" + args[0])
+		return nil, errors.New("Error retrieving " + args[0])
+	}
+
+	if redeem > wallet.Amount {
+		return nil, errors.New("El cliente no cuenta con coins suficientes")
+	}
+	if redeem > 0 {
+		err = checkLimit(&wallet, redeem)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	a := makeTimestamp(stub)
+
+	fmt.Printf("Time: %d \n", a)
+
+	earned, err := rollingEarn(stub, args[0], a)
+	if err != nil {
+		return nil, err
+	}
+
+	result := PurchaseResult{Code: 0}
+	movements := []Movement{}
+
+	//Canje: los coins vuelven al balance global como en debitBalance
+	if redeem > 0 {
+		result.RedeemRef = movementRef(args[0], a)
+		wallet.Amount = wallet.Amount - redeem
+		wallet.Limit = wallet.Limit - redeem
+
+		err = insertMovement(stub, args[0], args[1], redeem, wallet.Amount, "D", a, attribution, redeemMoney)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, Movement{Time: a, WalletId: args[0], Business: args[1], Amount: redeem, Balance: wallet.Amount, Type: "D", Store: attribution.Store, Terminal: attribution.Terminal, Cashier: attribution.Cashier, Currency: redeemMoney.Currency, Minor: redeemMoney.Minor})
+
+		coinBalance, err := stub.GetState("coinBalance")
+		if err != nil {
+			fmt.Println("Error retrieving coinBalance")
+			return nil, errors.New("Error retrieving coinBalance")
+		}
+
+		newCoinBalance, _ := strconv.ParseFloat(string(coinBalance), 64)
+		newCoinBalance = newCoinBalance + redeem
+
+		err = stub.PutState("coinBalance", []byte(strconv.FormatFloat(newCoinBalance, 'f', 6, 64)))
+		if err != nil {
+			fmt.Println("Error setting new coinBalance")
+			return nil, err
+		}
+	}
+
+	//Acumulacion con el bonus del tier del cliente, redondeada despues de aplicar el bonus
+	bonusEarn := roundEarn(rounding, earn*tierByName(wallet.Tier).Bonus)
+	if bonusEarn > 0 {
+		result.EarnRef = movementRef(args[0], a+1)
+		wallet.Amount = wallet.Amount + bonusEarn
+
+		err = insertMovement(stub, args[0], args[1], bonusEarn, wallet.Amount, "C", a+1, attribution, earnMoney)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, Movement{Time: a + 1, WalletId: args[0], Business: args[1], Amount: bonusEarn, Balance: wallet.Amount, Type: "C", Store: attribution.Store, Terminal: attribution.Terminal, Cashier: attribution.Cashier, Currency: earnMoney.Currency, Minor: earnMoney.Minor})
+
+		err = updateTier(stub, &wallet, earned+bonusEarn, a+2)
+		if err != nil {
+			return nil, fmt.Errorf("Fallo actualizar el tier. %s", err)
+		}
+	}
+
+	walletJSONasBytes, _ := json.Marshal(wallet)
+	err = stub.PutState(args[0], walletJSONasBytes) //rewrite the wallet
+	if err != nil {
+		return nil, err
+	}
+
+	err = replaceWalletRow(stub, args[0], wallet.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setBalanceEvent(stub, movements...)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Balance = strconv.FormatFloat(wallet.Amount, 'f', 6, 64)
+	result.Limit = strconv.FormatFloat(wallet.Limit, 'f', 6, 64)
+	result.Tier = wallet.Tier
+	result.Redeemed = strconv.FormatFloat(redeem, 'f', 6, 64)
+	result.Earned = strconv.FormatFloat(bonusEarn, 'f', 6, 64)
+
+	return json.Marshal(result)
+}
@@ -0,0 +1,333 @@
+/*
+* Adrian Pareja
+ */
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	tableSettlement = "Liquidaciones"
+	columnFrom      = "From"
+	columnTo        = "To"
+	columnData      = "Data"
+)
+
+//Precio en soles de cada coin para valorizar las liquidaciones
+const coinPriceKey = "coinPrice"
+
+//MerchantSettlement - Structure for the position of a merchant in a period.
+//Net = Issued - Redeemed; si es positivo el comercio debe a la red, si es negativo la red le debe
+type MerchantSettlement struct {
+	Merchant string  `json:"merchant"`
+	Issued   float64 `json:"issued"`
+	Redeemed float64 `json:"redeemed"`
+	Net      float64 `json:"net"`
+	Soles    float64 `json:"soles"`
+}
+
+//Settlement - Structure for settlement reports
+type Settlement struct {
+	From      int64                `json:"from"`
+	To        int64                `json:"to"`
+	Price     float64              `json:"price"`
+	Closed    int64                `json:"closed"`
+	Merchants []MerchantSettlement `json:"merchants"`
+}
+
+//getCoinPrice - Obtiene el precio en soles de un coin
+func getCoinPrice(stub shim.ChaincodeStubInterface) (float64, error) {
+	bytes, err := stub.GetState(coinPriceKey)
+	if err != nil {
+		return 0, errors.New("Error retrieving coinPrice")
+	}
+	if bytes == nil {
+		return 0, errors.New("No se ha configurado el precio del coin")
+	}
+	return strconv.ParseFloat(string(bytes), 64)
+}
+
+//setCoinPrice - Configura el precio en soles de un coin
+func (t *SimpleChaincode) setCoinPrice(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call---Funcion setCoinPrice---")
+
+	if len(args) != 1 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 1 para setCoinPrice")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede configurar el precio del coin")
+	}
+
+	price, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || !(price >= 0) || math.IsInf(price, 0) {
+		return nil, errors.New("Precio invalido: " + args[0])
+	}
+
+	err = stub.PutState(coinPriceKey, []byte(strconv.FormatFloat(price, 'f', 6, 64)))
+	if err != nil {
+		fmt.Println("Error setting coinPrice")
+		return nil, err
+	}
+
+	return []byte(`{"code":0,"response":null}`), nil
+}
+
+//parsePeriod - Obtiene el periodo [from, to) en milisegundos
+func parsePeriod(args []string) (int64, int64, error) {
+	from, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("Inicio de periodo invalido: " + args[0])
+	}
+	to, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("Fin de periodo invalido: " + args[1])
+	}
+	if to <= from {
+		return 0, 0, errors.New("El fin del periodo debe ser mayor al inicio")
+	}
+	return from, to, nil
+}
+
+//computeSettlement - Calcula coins emitidos (C y cupones V) y canjeados (D) por comercio en el periodo
+func computeSettlement(stub shim.ChaincodeStubInterface, from int64, to int64, price float64) (*Settlement, error) {
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Movement"}}
+	columns = append(columns, col0)
+
+	rowChannel, err := stub.GetRows("Movimientos", columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRows Movimientos operation failed. %s", err)
+	}
+
+	//Solo cuentan los movimientos de comercios registrados, no transferencias ni tiers
+	merchants := map[string]bool{}
+	positions := map[string]*MerchantSettlement{}
+	for {
+		select {
+		case row, ok := <-rowChannel:
+			if !ok {
+				rowChannel = nil
+			} else {
+				columnas := row.GetColumns()
+				time := columnas[2].GetInt64()
+				tipo := columnas[6].GetString_()
+				if time < from || time >= to || (tipo != "C" && tipo != "D" && tipo != "V") {
+					continue
+				}
+				business := columnas[3].GetString_()
+				isMerchant, found := merchants[business]
+				if !found {
+					merchant, err := getMerchant(stub, business)
+					if err != nil {
+						return nil, err
+					}
+					isMerchant = merchant != nil
+					merchants[business] = isMerchant
+				}
+				if !isMerchant {
+					continue
+				}
+
+				position, found := positions[business]
+				if !found {
+					position = &MerchantSettlement{Merchant: business}
+					positions[business] = position
+				}
+				amountRow, _ := strconv.ParseFloat(columnas[4].GetString_(), 64)
+				if tipo == "C" || tipo == "V" {
+					position.Issued = position.Issued + amountRow
+				} else {
+					position.Redeemed = position.Redeemed + amountRow
+				}
+			}
+		}
+		if rowChannel == nil {
+			break
+		}
+	}
+
+	//Se ordena por comercio para que todos los peers generen el mismo resultado
+	var ids []string
+	for id := range positions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	settlement := Settlement{From: from, To: to, Price: price, Merchants: []MerchantSettlement{}}
+	for _, id := range ids {
+		position := positions[id]
+		position.Net = position.Issued - position.Redeemed
+		position.Soles = position.Net * price
+		settlement.Merchants = append(settlement.Merchants, *position)
+	}
+
+	return &settlement, nil
+}
+
+//getSettlement - Calcula la liquidacion entre comercios de un periodo: desde, hasta y opcionalmente el precio
+func (t *SimpleChaincode) getSettlement(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getSettlement() is running----")
+
+	if len(args) != 2 && len(args) != 3 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 2 o 3")
+	}
+
+	from, to, err := parsePeriod(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var price float64
+	if len(args) == 3 {
+		price, err = strconv.ParseFloat(args[2], 64)
+		if err != nil || !(price >= 0) || math.IsInf(price, 0) {
+			return nil, errors.New("Precio invalido: " + args[2])
+		}
+	} else {
+		price, err = getCoinPrice(stub)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	settlement, err := computeSettlement(stub, from, to, price)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(settlement)
+}
+
+//closeSettlement - Congela y guarda la liquidacion de un periodo para su facturacion
+func (t *SimpleChaincode) closeSettlement(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call---Funcion closeSettlement---")
+
+	if len(args) != 2 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 2 para closeSettlement")
+	}
+	if !isAdmin(stub) {
+		return nil, errors.New("Solo el administrador puede cerrar liquidaciones")
+	}
+
+	from, to, err := parsePeriod(args)
+	if err != nil {
+		return nil, err
+	}
+
+	a := makeTimestamp(stub)
+	if to > a {
+		return nil, errors.New("No se puede cerrar un periodo que aun no termina")
+	}
+
+	closed, err := closedSettlements(stub)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range closed {
+		if from < c.To && c.From < to {
+			return nil, fmt.Errorf("El periodo se superpone con la liquidacion cerrada %d-%d", c.From, c.To)
+		}
+	}
+
+	price, err := getCoinPrice(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	settlement, err := computeSettlement(stub, from, to, price)
+	if err != nil {
+		return nil, err
+	}
+	settlement.Closed = a
+
+	bytes, err := json.Marshal(settlement)
+	if err != nil {
+		return nil, errors.New("Error marshaling settlement")
+	}
+
+	var columns []*shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Settlement"}}
+	col1 := shim.Column{Value: &shim.Column_Int64{Int64: from}}
+	col2 := shim.Column{Value: &shim.Column_Int64{Int64: to}}
+	col3 := shim.Column{Value: &shim.Column_String_{String_: string(bytes)}}
+	columns = append(columns, &col0)
+	columns = append(columns, &col1)
+	columns = append(columns, &col2)
+	columns = append(columns, &col3)
+
+	row := shim.Row{Columns: columns}
+	ok, err := stub.InsertRow(tableSettlement, row)
+	if err != nil {
+		return nil, fmt.Errorf("Insert Row Liquidaciones operation failed. %s", err)
+	}
+	if !ok {
+		return nil, errors.New("La liquidacion del periodo ya fue cerrada")
+	}
+
+	err = stub.SetEvent("closeSettlement", []byte(fmt.Sprintf("closeSettlement:%d-%d", from, to)))
+	if err != nil {
+		return nil, errors.New("Fallo enviar el evento de cierre de liquidacion")
+	}
+
+	return bytes, nil
+}
+
+//closedSettlements - Obtiene todas las liquidaciones cerradas
+func closedSettlements(stub shim.ChaincodeStubInterface) ([]Settlement, error) {
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Settlement"}}
+	columns = append(columns, col0)
+
+	rowChannel, err := stub.GetRows(tableSettlement, columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRows Liquidaciones operation failed. %s", err)
+	}
+
+	settlements := []Settlement{}
+	for {
+		select {
+		case row, ok := <-rowChannel:
+			if !ok {
+				rowChannel = nil
+			} else {
+				columnas := row.GetColumns()
+				settlement := Settlement{}
+				err = json.Unmarshal([]byte(columnas[3].GetString_()), &settlement)
+				if err != nil {
+					return nil, fmt.Errorf("Error parseando la liquidacion. %s", err)
+				}
+				settlements = append(settlements, settlement)
+			}
+		}
+		if rowChannel == nil {
+			break
+		}
+	}
+
+	return settlements, nil
+}
+
+//getSettlements - Obtiene las liquidaciones cerradas
+func (t *SimpleChaincode) getSettlements(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getSettlements() is running----")
+
+	settlements, err := closedSettlements(stub)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonRows, err := json.Marshal(settlements)
+	if err != nil {
+		return nil, fmt.Errorf("getRows Liquidaciones operation failed. Error marshaling JSON: %s", err)
+	}
+
+	return jsonRows, nil
+}
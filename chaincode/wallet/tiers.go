@@ -0,0 +1,257 @@
+/*
+* Adrian Pareja
+ */
+package wallet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//Ventana movil de 12 meses en milisegundos
+const tierWindow int64 = 365 * 24 * 60 * 60 * 1000
+
+//Historial de cambios de tier, fuera de Movimientos porque no mueven saldo
+const tableTierChange = "CambiosTier"
+
+//TierChange - Structure for a change of tier of a wallet
+type TierChange struct {
+	WalletId string  `json:"walletid"`
+	Time     int64   `json:"time"`
+	From     string  `json:"from"`
+	To       string  `json:"to"`
+	Earned   float64 `json:"earned"`
+}
+
+//Tier - Structure for membership tiers
+type Tier struct {
+	Name     string  `json:"name"`
+	MinEarn  float64 `json:"minearn"`
+	Bonus    float64 `json:"bonus"`
+	Limit    float64 `json:"limit"`
+	Transfer float64 `json:"transfer"`
+}
+
+//Tiers ordenados de menor a mayor acumulacion
+var tiers = []Tier{
+	{Name: "Classic", MinEarn: 0, Bonus: 1, Limit: limit, Transfer: 100},
+	{Name: "Silver", MinEarn: 1000, Bonus: 1.25, Limit: 200, Transfer: 500},
+	{Name: "Gold", MinEarn: 5000, Bonus: 1.5, Limit: 300, Transfer: 2000},
+}
+
+//tierFor - Obtiene el tier que corresponde a los coins acumulados
+func tierFor(earned float64) Tier {
+	tier := tiers[0]
+	for _, t := range tiers {
+		if earned >= t.MinEarn {
+			tier = t
+		}
+	}
+	return tier
+}
+
+//tierByName - Obtiene el tier por nombre, los wallets antiguos son Classic
+func tierByName(name string) Tier {
+	for _, t := range tiers {
+		if t.Name == name {
+			return t
+		}
+	}
+	return tiers[0]
+}
+
+//rollingEarn - Suma los coins ganados en negocios durante los ultimos 12 meses
+func rollingEarn(stub shim.ChaincodeStubInterface, walletId string, now int64) (float64, error) {
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Movement"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: walletId}}
+	columns = append(columns, col0)
+	columns = append(columns, col1)
+
+	rowChannel, err := stub.GetRows("Movimientos", columns)
+	if err != nil {
+		return 0, fmt.Errorf("getRows Movimientos operation failed. %s", err)
+	}
+
+	//Las transferencias tambien son "C" pero el Business es otro wallet
+	wallets := map[string]bool{}
+	var earned float64
+	for {
+		select {
+		case row, ok := <-rowChannel:
+			if !ok {
+				rowChannel = nil
+			} else {
+				columnas := row.GetColumns()
+				if columnas[6].GetString_() != "C" || columnas[2].GetInt64() < now-tierWindow {
+					continue
+				}
+				business := columnas[3].GetString_()
+				isWallet, found := wallets[business]
+				if !found {
+					isWallet = walletExists(stub, business)
+					wallets[business] = isWallet
+				}
+				if isWallet {
+					continue
+				}
+				amountRow, _ := strconv.ParseFloat(columnas[4].GetString_(), 64)
+				earned = earned + amountRow
+			}
+		}
+		if rowChannel == nil {
+			break
+		}
+	}
+
+	return earned, nil
+}
+
+//walletExists - Indica si el id pertenece a un wallet
+func walletExists(stub shim.ChaincodeStubInterface, id string) bool {
+	bytes, err := stub.GetState(id)
+	if err != nil || bytes == nil {
+		return false
+	}
+	wallet := Wallet{}
+	if json.Unmarshal(bytes, &wallet) != nil {
+		return false
+	}
+	return wallet.Id == id
+}
+
+//updateTier - Recalcula el tier del wallet y registra el cambio en CambiosTier si cambia.
+//El wallet debe guardarse luego por quien llama.
+func updateTier(stub shim.ChaincodeStubInterface, wallet *Wallet, earned float64, time int64) error {
+	tier := tierFor(earned)
+	if tierByName(wallet.Tier).Name == tier.Name {
+		wallet.Tier = tier.Name
+		return nil
+	}
+
+	fmt.Printf("Wallet %s cambia de tier %s a %s\n", wallet.Id, wallet.Tier, tier.Name)
+	change := TierChange{WalletId: wallet.Id, Time: time, From: tierByName(wallet.Tier).Name, To: tier.Name, Earned: earned}
+	//El limite cambia en la diferencia entre tiers, lo ya consumido en el periodo se mantiene
+	wallet.Limit = math.Max(wallet.Limit+tier.Limit-tierByName(wallet.Tier).Limit, 0)
+	wallet.Tier = tier.Name
+
+	return insertTierChange(stub, change)
+}
+
+//insertTierChange - Inserta una fila en la tabla de CambiosTier
+func insertTierChange(stub shim.ChaincodeStubInterface, change TierChange) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return errors.New("Error marshaling tier change")
+	}
+
+	var columns []*shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Tier"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: change.WalletId}}
+	col2 := shim.Column{Value: &shim.Column_Int64{Int64: change.Time}}
+	col3 := shim.Column{Value: &shim.Column_String_{String_: string(data)}}
+	columns = append(columns, &col0)
+	columns = append(columns, &col1)
+	columns = append(columns, &col2)
+	columns = append(columns, &col3)
+
+	row := shim.Row{Columns: columns}
+	ok, err := stub.InsertRow(tableTierChange, row)
+	if err != nil {
+		return fmt.Errorf("Insert Row CambiosTier operation failed. %s", err)
+	}
+	if !ok {
+		return errors.New("Fallo insertar Row CambiosTier with given key already exists")
+	}
+	return nil
+}
+
+//checkLimit - El limite del tier se consume con cada debito o transferencia y se repone en reset
+func checkLimit(wallet *Wallet, amt float64) error {
+	if amt > wallet.Limit {
+		return fmt.Errorf("El wallet alcanzo el tope de su limite, disponible: %s", strconv.FormatFloat(math.Max(wallet.Limit, 0), 'f', 6, 64))
+	}
+	return nil
+}
+
+//getTier - Obtiene el tier de un wallet y sus coins acumulados en 12 meses
+func (t *SimpleChaincode) getTier(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getTier() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
+	}
+
+	bytes, err := stub.GetState(args[0])
+	if err != nil || bytes == nil {
+		fmt.Println("Error retrieving " + args[0])
+		return nil, errors.New("Error retrieving " + args[0])
+	}
+	wallet := Wallet{}
+	err = json.Unmarshal(bytes, &wallet)
+	if err != nil {
+		fmt.Println("Error parseando a Json" + args[0])
+		return nil, errors.New("Error retrieving Tier" + args[0])
+	}
+
+	earned, err := rollingEarn(stub, args[0], makeTimestamp(stub))
+	if err != nil {
+		return nil, err
+	}
+	tier := tierByName(wallet.Tier)
+
+	return []byte(fmt.Sprintf(`{"code":0,"tier":"%s","earned":"%s","bonus":"%s","limit":"%s","transfer":"%s"}`, tier.Name, strconv.FormatFloat(earned, 'f', 6, 64), strconv.FormatFloat(tier.Bonus, 'f', 6, 64), strconv.FormatFloat(tier.Limit, 'f', 6, 64), strconv.FormatFloat(tier.Transfer, 'f', 6, 64))), nil
+}
+
+//getTierHistory - Obtiene los cambios de tier de un wallet
+func (t *SimpleChaincode) getTierHistory(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getTierHistory() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
+	}
+
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Tier"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: args[0]}}
+	columns = append(columns, col0)
+	columns = append(columns, col1)
+
+	rowChannel, err := stub.GetRows(tableTierChange, columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRows CambiosTier operation failed. %s", err)
+	}
+
+	changes := []TierChange{}
+	for {
+		select {
+		case row, ok := <-rowChannel:
+			if !ok {
+				rowChannel = nil
+			} else {
+				columnas := row.GetColumns()
+				change := TierChange{}
+				err = json.Unmarshal([]byte(columnas[3].GetString_()), &change)
+				if err != nil {
+					return nil, fmt.Errorf("Error parseando el cambio de tier. %s", err)
+				}
+				changes = append(changes, change)
+			}
+		}
+		if rowChannel == nil {
+			break
+		}
+	}
+
+	jsonRows, err := json.Marshal(changes)
+	if err != nil {
+		return nil, fmt.Errorf("getRows CambiosTier operation failed. Error marshaling JSON: %s", err)
+	}
+
+	return jsonRows, nil
+}
@@ -0,0 +1,484 @@
+/*
+* Adrian Pareja
+ */
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+const (
+	tableVoucherBatch = "Lotes"
+	columnBatchId     = "Id"
+
+	tableVoucher = "Cupones"
+	columnHash   = "Hash"
+	columnBatch  = "Batch"
+)
+
+//Fuente de fondos de un lote pagado por la red y no por un comercio
+const centralSource = "central"
+
+//VoucherBatch - Structure for a batch of single-use voucher codes.
+//Source es "central" (coinBalance) o el id del comercio que paga los coins. Escrow son los coins que un lote de
+//comercio retiene de coinBalance al emitirse para los cupones aun no canjeados
+type VoucherBatch struct {
+	Id          string  `json:"id"`
+	Value       float64 `json:"value"`
+	Expiry      int64   `json:"expiry"`
+	WalletCap   int64   `json:"walletcap"`
+	Source      string  `json:"source"`
+	Issued      int64   `json:"issued"`
+	Redeemed    int64   `json:"redeemed"`
+	Utilization float64 `json:"utilization"`
+	Created     int64   `json:"created"`
+	Escrow      float64 `json:"escrow"`
+}
+
+//voucherHash - Hash con el que se guarda un codigo, el codigo en claro nunca llega al ledger al emitir
+func voucherHash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+//getVoucherBatch - Obtiene un lote de cupones, nil si no existe
+func getVoucherBatch(stub shim.ChaincodeStubInterface, batchId string) (*VoucherBatch, error) {
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Batch"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: batchId}}
+	columns = append(columns, col0)
+	columns = append(columns, col1)
+
+	row, err := stub.GetRow(tableVoucherBatch, columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRow Lotes operation failed. %s", err)
+	}
+	if len(row.Columns) == 0 {
+		return nil, nil
+	}
+
+	batch := VoucherBatch{}
+	err = json.Unmarshal([]byte(row.GetColumns()[2].GetString_()), &batch)
+	if err != nil {
+		return nil, fmt.Errorf("Error parseando el lote. %s", err)
+	}
+	return &batch, nil
+}
+
+//putVoucherBatch - Inserta o reemplaza la fila del lote
+func putVoucherBatch(stub shim.ChaincodeStubInterface, batch *VoucherBatch, replace bool) error {
+	if batch.Issued > 0 {
+		batch.Utilization = float64(batch.Redeemed) / float64(batch.Issued)
+	}
+	bytes, err := json.Marshal(batch)
+	if err != nil {
+		return errors.New("Error marshaling batch")
+	}
+
+	var columns []*shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Batch"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: batch.Id}}
+	col2 := shim.Column{Value: &shim.Column_String_{String_: string(bytes)}}
+	columns = append(columns, &col0)
+	columns = append(columns, &col1)
+	columns = append(columns, &col2)
+
+	row := shim.Row{Columns: columns}
+	var ok bool
+	if replace {
+		ok, err = stub.ReplaceRow(tableVoucherBatch, row)
+	} else {
+		ok, err = stub.InsertRow(tableVoucherBatch, row)
+	}
+	if err != nil {
+		return fmt.Errorf("Insert Row Lotes operation failed. %s", err)
+	}
+	if !ok {
+		return errors.New("Fallo insertar Row Lotes")
+	}
+	return nil
+}
+
+//putVoucher - Inserta o reemplaza la fila de un cupon, wallet vacio si no fue canjeado
+func putVoucher(stub shim.ChaincodeStubInterface, hash string, batchId string, walletId string, time int64, replace bool) error {
+	var columns []*shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Voucher"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: hash}}
+	col2 := shim.Column{Value: &shim.Column_String_{String_: batchId}}
+	col3 := shim.Column{Value: &shim.Column_String_{String_: walletId}}
+	col4 := shim.Column{Value: &shim.Column_Int64{Int64: time}}
+	columns = append(columns, &col0)
+	columns = append(columns, &col1)
+	columns = append(columns, &col2)
+	columns = append(columns, &col3)
+	columns = append(columns, &col4)
+
+	row := shim.Row{Columns: columns}
+	var ok bool
+	var err error
+	if replace {
+		ok, err = stub.ReplaceRow(tableVoucher, row)
+	} else {
+		ok, err = stub.InsertRow(tableVoucher, row)
+	}
+	if err != nil {
+		return fmt.Errorf("Insert Row Cupones operation failed. %s", err)
+	}
+	if !ok {
+		return errors.New("El cupon ya fue emitido")
+	}
+	return nil
+}
+
+//issueVouchers - Emite un lote de cupones de un solo uso. Solo se guardan los hashes sha256 de los codigos.
+//args: lote, valor en coins, vencimiento en milisegundos, tope por wallet, fuente (central o comercio) y los hashes.
+//Un lote de comercio retiene de coinBalance el valor de todos sus cupones; el chaincode del comercio los repone antes
+//con puttotalcoin desde su propio balance (issuevouchers del comercio)
+func (t *SimpleChaincode) issueVouchers(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call---Funcion issueVouchers---")
+
+	if len(args) < 6 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera al menos 6 para issueVouchers")
+	}
+
+	//La red emite con fondos centrales; un comercio emite desde su chaincode, que paga el lote con puttotalcoin
+	//en la misma transaccion, por eso aqui tambien se exige el administrador
+	if args[4] == centralSource {
+		if !isAdmin(stub) {
+			return nil, errors.New("Solo el administrador puede emitir cupones con fondos centrales")
+		}
+	} else if !isAdmin(stub) {
+		return nil, errors.New("Los cupones de comercio se emiten desde el chaincode del comercio")
+	} else {
+		merchant, err := getMerchant(stub, args[4])
+		if err != nil {
+			return nil, err
+		}
+		if merchant == nil {
+			return nil, errors.New("Comercio desconocido: " + args[4])
+		}
+	}
+
+	value, err := strconv.ParseFloat(args[1], 64)
+	if err != nil || !(value > 0) || math.IsInf(value, 0) {
+		return nil, errors.New("Valor de cupon invalido: " + args[1])
+	}
+	expiry, err := strconv.ParseInt(args[2], 10, 64)
+	a := makeTimestamp(stub)
+	if err != nil || expiry <= a {
+		return nil, errors.New("Vencimiento invalido: " + args[2])
+	}
+	walletCap, err := strconv.ParseInt(args[3], 10, 64)
+	if err != nil || walletCap <= 0 {
+		return nil, errors.New("Tope por wallet invalido: " + args[3])
+	}
+
+	existing, err := getVoucherBatch(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, errors.New("El lote ya existe: " + args[0])
+	}
+
+	batch := VoucherBatch{
+		Id:        args[0],
+		Value:     value,
+		Expiry:    expiry,
+		WalletCap: walletCap,
+		Source:    args[4],
+		Created:   a,
+	}
+
+	for _, hash := range args[5:] {
+		hash = strings.ToLower(hash)
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return nil, errors.New("Hash de cupon invalido: " + hash)
+		}
+		err = putVoucher(stub, hash, batch.Id, "", 0, false)
+		if err != nil {
+			return nil, err
+		}
+		batch.Issued = batch.Issued + 1
+	}
+
+	if batch.Source != centralSource {
+		batch.Escrow = batch.Value * float64(batch.Issued)
+		err = moveCoinBalance(stub, -batch.Escrow)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = putVoucherBatch(stub, &batch, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(batch)
+}
+
+//redeemVoucher - Canjea un cupon en la wallet del cliente: wallet y codigo
+func (t *SimpleChaincode) redeemVoucher(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call---Funcion redeemVoucher---")
+
+	if len(args) != 2 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 2 para redeemVoucher")
+	}
+
+	hash := voucherHash(args[1])
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Voucher"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: hash}}
+	columns = append(columns, col0)
+	columns = append(columns, col1)
+
+	row, err := stub.GetRow(tableVoucher, columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRow Cupones operation failed. %s", err)
+	}
+	if len(row.Columns) == 0 {
+		return nil, errors.New("Cupon invalido")
+	}
+	if row.GetColumns()[3].GetString_() != "" {
+		return nil, errors.New("El cupon ya fue canjeado")
+	}
+
+	batch, err := getVoucherBatch(stub, row.GetColumns()[2].GetString_())
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil {
+		return nil, errors.New("Lote desconocido: " + row.GetColumns()[2].GetString_())
+	}
+
+	a := makeTimestamp(stub)
+	if a >= batch.Expiry {
+		return nil, errors.New("El cupon esta vencido")
+	}
+
+	//Cupones canjeados por el wallet en este lote
+	useKey := "voucherUse:" + batch.Id + ":" + args[0]
+	bytesUse, err := stub.GetState(useKey)
+	if err != nil {
+		return nil, errors.New("Error retrieving " + useKey)
+	}
+	var used int64
+	if bytesUse != nil {
+		used, _ = strconv.ParseInt(string(bytesUse), 10, 64)
+	}
+	if used >= batch.WalletCap {
+		return nil, errors.New("El wallet alcanzo el tope de cupones del lote " + batch.Id)
+	}
+
+	bytesWallet, err := stub.GetState(args[0])
+	if err != nil || bytesWallet == nil {
+		fmt.Println("Error retrieving " + args[0])
+		return nil, errors.New("Error retrieving " + args[0])
+	}
+	wallet := Wallet{}
+	err = json.Unmarshal(bytesWallet, &wallet)
+	if err != nil {
+		fmt.Println("Error parseando a Json" + args[0])
+		return nil, errors.New("Error retrieving " + args[0])
+	}
+
+	//Los coins salen del balance global o quedan como emitidos por el comercio en la liquidacion
+	if batch.Source == centralSource {
+		coinBalance, err := stub.GetState("coinBalance")
+		if err != nil {
+			fmt.Println("Error retrieving coinBalance")
+			return nil, errors.New("Error retrieving coinBalance")
+		}
+		newCoinBalance, _ := strconv.ParseFloat(string(coinBalance), 64)
+		if newCoinBalance < batch.Value {
+			return nil, errors.New("No hay coins suficientes en el balance global")
+		}
+		newCoinBalance = newCoinBalance - batch.Value
+		err = stub.PutState("coinBalance", []byte(strconv.FormatFloat(newCoinBalance, 'f', 6, 64)))
+		if err != nil {
+			fmt.Println("Error setting new coinBalance")
+			return nil, err
+		}
+	} else {
+		merchant, err := getMerchant(stub, batch.Source)
+		if err != nil {
+			return nil, err
+		}
+		if merchant == nil || merchant.Status != merchantActive {
+			return nil, errors.New("El comercio del lote no esta activo: " + batch.Source)
+		}
+		if batch.Escrow < batch.Value {
+			return nil, errors.New("El lote no tiene coins retenidos suficientes: " + batch.Id)
+		}
+		batch.Escrow = batch.Escrow - batch.Value
+	}
+
+	wallet.Amount = wallet.Amount + batch.Value
+	walletJSONasBytes, _ := json.Marshal(wallet)
+	err = stub.PutState(args[0], walletJSONasBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = insertMovement(stub, args[0], batch.Source, batch.Value, wallet.Amount, "V", a, Attribution{}, Money{})
+	if err != nil {
+		return nil, err
+	}
+	err = replaceWalletRow(stub, args[0], wallet.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putVoucher(stub, hash, batch.Id, args[0], a, true)
+	if err != nil {
+		return nil, err
+	}
+	err = stub.PutState(useKey, []byte(strconv.FormatInt(used+1, 10)))
+	if err != nil {
+		return nil, err
+	}
+
+	batch.Redeemed = batch.Redeemed + 1
+	err = putVoucherBatch(stub, batch, true)
+	if err != nil {
+		return nil, err
+	}
+
+	err = setBalanceEvent(stub, Movement{Time: a, WalletId: args[0], Business: batch.Source, Amount: batch.Value, Balance: wallet.Amount, Type: "V"})
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf(`{"code":0,"balance":"%s","value":"%s","batch":"%s"}`, strconv.FormatFloat(wallet.Amount, 'f', 6, 64), strconv.FormatFloat(batch.Value, 'f', 6, 64), batch.Id)), nil
+}
+
+//closeVoucherBatch - Devuelve a coinBalance los coins retenidos por un lote de comercio vencido: lote.
+//El chaincode del comercio los recupera con debittotalcoin (reclaimvouchers)
+func (t *SimpleChaincode) closeVoucherBatch(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call---Funcion closeVoucherBatch---")
+
+	if len(args) != 1 {
+		return nil, errors.New("Numero incorrecto de argumentos.Se espera 1 para closeVoucherBatch")
+	}
+
+	batch, err := getVoucherBatch(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil {
+		return nil, errors.New("Lote desconocido: " + args[0])
+	}
+	if batch.Source == centralSource {
+		return nil, errors.New("Los lotes centrales no retienen coins: " + args[0])
+	}
+	//Lo llama el chaincode del comercio, que recupera lo devuelto en la misma transaccion
+	if !isAdmin(stub) {
+		return nil, errors.New("Los lotes de comercio se cierran desde el chaincode del comercio")
+	}
+	if makeTimestamp(stub) < batch.Expiry {
+		return nil, errors.New("El lote aun esta vigente: " + args[0])
+	}
+
+	refund := batch.Escrow
+	err = moveCoinBalance(stub, refund)
+	if err != nil {
+		return nil, err
+	}
+	batch.Escrow = 0
+	err = putVoucherBatch(stub, batch, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf(`{"code":0,"refund":"%s","batch":"%s"}`, strconv.FormatFloat(refund, 'f', 6, 64), batch.Id)), nil
+}
+
+//moveCoinBalance - Suma amount a coinBalance, o lo resta si es negativo y alcanza
+func moveCoinBalance(stub shim.ChaincodeStubInterface, amount float64) error {
+	coinBalance, err := stub.GetState("coinBalance")
+	if err != nil {
+		fmt.Println("Error retrieving coinBalance")
+		return errors.New("Error retrieving coinBalance")
+	}
+	newCoinBalance, _ := strconv.ParseFloat(string(coinBalance), 64)
+	if newCoinBalance+amount < 0 {
+		return errors.New("No hay coins suficientes en el balance global")
+	}
+	newCoinBalance = newCoinBalance + amount
+	err = stub.PutState("coinBalance", []byte(strconv.FormatFloat(newCoinBalance, 'f', 6, 64)))
+	if err != nil {
+		fmt.Println("Error setting new coinBalance")
+		return err
+	}
+	return nil
+}
+
+//getVoucherBatchInfo - Obtiene un lote de cupones con su utilizacion
+func (t *SimpleChaincode) getVoucherBatchInfo(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getVoucherBatch() is running----")
+
+	if len(args) != 1 {
+		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
+	}
+
+	batch, err := getVoucherBatch(stub, args[0])
+	if err != nil {
+		return nil, err
+	}
+	if batch == nil {
+		return nil, errors.New("Lote desconocido: " + args[0])
+	}
+
+	return json.Marshal(batch)
+}
+
+//getVoucherBatches - Obtiene todos los lotes de cupones con su utilizacion
+func (t *SimpleChaincode) getVoucherBatches(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
+	fmt.Println("Call----getVoucherBatches() is running----")
+
+	var columns []shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Batch"}}
+	columns = append(columns, col0)
+
+	rowChannel, err := stub.GetRows(tableVoucherBatch, columns)
+	if err != nil {
+		return nil, fmt.Errorf("getRows Lotes operation failed. %s", err)
+	}
+
+	batches := []VoucherBatch{}
+	for {
+		select {
+		case row, ok := <-rowChannel:
+			if !ok {
+				rowChannel = nil
+			} else {
+				batch := VoucherBatch{}
+				err = json.Unmarshal([]byte(row.GetColumns()[2].GetString_()), &batch)
+				if err != nil {
+					return nil, fmt.Errorf("Error parseando el lote. %s", err)
+				}
+				batches = append(batches, batch)
+			}
+		}
+		if rowChannel == nil {
+			break
+		}
+	}
+
+	jsonRows, err := json.Marshal(batches)
+	if err != nil {
+		return nil, fmt.Errorf("getRows Lotes operation failed. Error marshaling JSON: %s", err)
+	}
+
+	return jsonRows, nil
+}
@@ -1,12 +1,16 @@
 /*
 * Adrian Pareja
  */
-package main
+
+//Package wallet es el chaincode del wallet de coins: wallets, comercios, tiers, liquidacion, vouchers y pagos.
+//main y newVersion lo despliegan con shim.Start
+package wallet
 
 import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"time"
 
@@ -14,7 +18,6 @@ import (
 
 	"crypto/rand"
 	"encoding/hex"
-	
 )
 
 const limit = 100
@@ -28,11 +31,16 @@ const (
 	columnAmount    = "Amount"
 	columnBalance   = "Balance"
 	columnType      = "Type"
+	columnStore     = "Store"
+	columnTerminal  = "Terminal"
+	columnCashier   = "Cashier"
+	columnCurrency  = "Currency"
+	columnMinor     = "Minor"
 )
 
 const (
 	tableWalletColumn     = "Wallet"
-	columnWalletId  = "Id"
+	columnWalletId        = "Id"
 	columnWalletAccountID = "Account"
 	columnWalletBalance   = "Balance"
 )
@@ -61,12 +69,13 @@ type Wallet struct {
 	Password string  `json:"password"`
 	Amount   float64 `json:"amount"`
 	Limit    float64 `json:"limit"`
+	Tier     string  `json:"tier"`
 }
 
 //Wallet - Structure for products used in buy goods
 type WalletBalance struct {
-	WalletId  string  `json:"walletid"`
-	Balance   float64 `json:"balance"`
+	WalletId string  `json:"walletid"`
+	Balance  float64 `json:"balance"`
 }
 
 //Movimiento - Structure for movements
@@ -77,18 +86,28 @@ type Movement struct {
 	Amount   float64 `json:"amount"`
 	Balance  float64 `json:"balance"`
 	Type     string  `json:"type"`
+	Store    string  `json:"store,omitempty"`
+	Terminal string  `json:"terminal,omitempty"`
+	Cashier  string  `json:"cashier,omitempty"`
+	Currency string  `json:"currency,omitempty"`
+	Minor    int64   `json:"minor,omitempty"`
 }
 
-// SimpleChaincode example simple Chaincode implementation
-type SimpleChaincode struct {
+//Attribution - Structure for the store, terminal and cashier of a merchant transaction
+type Attribution struct {
+	Store    string `json:"store"`
+	Terminal string `json:"terminal"`
+	Cashier  string `json:"cashier"`
 }
 
-func main() {
-	fmt.Printf("Iniciandooo Contrato Wallet....")
-	err := shim.Start(new(SimpleChaincode))
-	if err != nil {
-		fmt.Printf("Error Iniciando Wallet Smart Contract: %s", err)
-	}
+//Money - Structure for an ISO 4217 currency amount in integer minor units
+type Money struct {
+	Currency string `json:"currency"`
+	Minor    int64  `json:"minor"`
+}
+
+// SimpleChaincode example simple Chaincode implementation
+type SimpleChaincode struct {
 }
 
 // Init reinicia los estados del ledger
@@ -103,21 +122,61 @@ func (t *SimpleChaincode) Init(stub shim.ChaincodeStubInterface, function string
 	}
 
 	stub.CreateTable(tableColumn, []*shim.ColumnDefinition{
-			&shim.ColumnDefinition{Name: columnWallet, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnWallet, Type: shim.ColumnDefinition_STRING, Key: true},
 		&shim.ColumnDefinition{Name: columnAccountID, Type: shim.ColumnDefinition_STRING, Key: true},
 		&shim.ColumnDefinition{Name: columnTime, Type: shim.ColumnDefinition_INT64, Key: true},
 		&shim.ColumnDefinition{Name: columnBusiness, Type: shim.ColumnDefinition_STRING, Key: false},
 		&shim.ColumnDefinition{Name: columnAmount, Type: shim.ColumnDefinition_STRING, Key: false},
 		&shim.ColumnDefinition{Name: columnBalance, Type: shim.ColumnDefinition_STRING, Key: false},
 		&shim.ColumnDefinition{Name: columnType, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnStore, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnTerminal, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnCashier, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnCurrency, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnMinor, Type: shim.ColumnDefinition_INT64, Key: false},
 	})
-	
+
 	stub.CreateTable(tableWalletColumn, []*shim.ColumnDefinition{
-		&shim.ColumnDefinition{Name: columnWalletId, Type: shim.ColumnDefinition_STRING, Key: true},	
+		&shim.ColumnDefinition{Name: columnWalletId, Type: shim.ColumnDefinition_STRING, Key: true},
 		&shim.ColumnDefinition{Name: columnAccountID, Type: shim.ColumnDefinition_STRING, Key: true},
 		&shim.ColumnDefinition{Name: columnBalance, Type: shim.ColumnDefinition_STRING, Key: false},
 	})
-	
+
+	stub.CreateTable(tableMerchant, []*shim.ColumnDefinition{
+		&shim.ColumnDefinition{Name: columnAccountID, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnMerchantId, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnMerchantName, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnStatus, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnCaller, Type: shim.ColumnDefinition_STRING, Key: false},
+	})
+
+	stub.CreateTable(tableSettlement, []*shim.ColumnDefinition{
+		&shim.ColumnDefinition{Name: columnAccountID, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnFrom, Type: shim.ColumnDefinition_INT64, Key: true},
+		&shim.ColumnDefinition{Name: columnTo, Type: shim.ColumnDefinition_INT64, Key: false},
+		&shim.ColumnDefinition{Name: columnData, Type: shim.ColumnDefinition_STRING, Key: false},
+	})
+
+	stub.CreateTable(tableVoucherBatch, []*shim.ColumnDefinition{
+		&shim.ColumnDefinition{Name: columnAccountID, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnBatchId, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnData, Type: shim.ColumnDefinition_STRING, Key: false},
+	})
+
+	stub.CreateTable(tableVoucher, []*shim.ColumnDefinition{
+		&shim.ColumnDefinition{Name: columnAccountID, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnHash, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnBatch, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnWallet, Type: shim.ColumnDefinition_STRING, Key: false},
+		&shim.ColumnDefinition{Name: columnTime, Type: shim.ColumnDefinition_INT64, Key: false},
+	})
+
+	stub.CreateTable(tableTierChange, []*shim.ColumnDefinition{
+		&shim.ColumnDefinition{Name: columnAccountID, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnWallet, Type: shim.ColumnDefinition_STRING, Key: true},
+		&shim.ColumnDefinition{Name: columnTime, Type: shim.ColumnDefinition_INT64, Key: true},
+		&shim.ColumnDefinition{Name: columnData, Type: shim.ColumnDefinition_STRING, Key: false},
+	})
 	fmt.Printf("Iniciandooo Job de reinicio de limite")
 
 	return nil, nil
@@ -141,11 +200,37 @@ func (t *SimpleChaincode) Invoke(stub shim.ChaincodeStubInterface, function stri
 				} else {
 					if function == "puttotalcoin" {
 						return t.putTotalCoin(stub, args)
-					} else { 
+					} else {
 						if function == "debittotalcoin" {
 							return t.debitTotalCoin(stub, args)
-						} else if function == "reset"{
-							return t.reset(stub, args)	
+						} else if function == "reset" {
+							return t.reset(stub, args)
+						} else if function == "registermerchant" {
+							return t.registerMerchant(stub, args)
+						} else if function == "updatemerchant" {
+							return t.updateMerchant(stub, args)
+						} else if function == "setmerchantstatus" {
+							return t.setMerchantStatus(stub, args)
+						} else if function == "setcoinprice" {
+							return t.setCoinPrice(stub, args)
+						} else if function == "closesettlement" {
+							return t.closeSettlement(stub, args)
+						} else if function == "purchase" {
+							return t.purchase(stub, args)
+						} else if function == "issuevouchers" {
+							return t.issueVouchers(stub, args)
+						} else if function == "redeemvoucher" {
+							return t.redeemVoucher(stub, args)
+						} else if function == "closevoucherbatch" {
+							return t.closeVoucherBatch(stub, args)
+						} else if function == "payintent" {
+							return t.payIntent(stub, args)
+						} else if function == "setmerchantkey" {
+							return t.setMerchantKey(stub, args)
+						} else if function == "redeemearnvoucher" {
+							return t.redeemEarnVoucher(stub, args)
+						} else if function == "setearncap" {
+							return t.setEarnCap(stub, args)
 						}
 					}
 				}
@@ -170,12 +255,34 @@ func (t *SimpleChaincode) Query(stub shim.ChaincodeStubInterface, function strin
 		} else {
 			if function == "getmovimientos" {
 				return t.getMovimientos(stub, args)
-			} else { 
+			} else {
 				if function == "getdatos" {
 					return t.getDatos(stub, args)
 				} else if function == "getwallets" {
 					return t.getWallets(stub, args)
-				} 
+				} else if function == "gettier" {
+					return t.getTier(stub, args)
+				} else if function == "gettierhistory" {
+					return t.getTierHistory(stub, args)
+				} else if function == "getmerchant" {
+					return t.getMerchantInfo(stub, args)
+				} else if function == "getmerchants" {
+					return t.getMerchants(stub, args)
+				} else if function == "getsettlement" {
+					return t.getSettlement(stub, args)
+				} else if function == "getsettlements" {
+					return t.getSettlements(stub, args)
+				} else if function == "getvoucherbatch" {
+					return t.getVoucherBatchInfo(stub, args)
+				} else if function == "getvoucherbatches" {
+					return t.getVoucherBatches(stub, args)
+				} else if function == "getpayintent" {
+					return t.getPayIntent(stub, args)
+				} else if function == "getmerchantkey" {
+					return t.getMerchantKeyInfo(stub, args)
+				} else if function == "getearncap" {
+					return t.getEarnCapInfo(stub, args)
+				}
 			}
 		}
 	}
@@ -190,11 +297,11 @@ func (t *SimpleChaincode) createWallet(stub shim.ChaincodeStubInterface, args []
 	if len(args) != 6 {
 		return nil, errors.New("Numero incorrecto de argumentos.Se espera 6 para createWallet")
 	}
-	
+
 	bytesWallet, _ := stub.GetState(args[0])
 	if bytesWallet != nil {
-		fmt.Println("Ya existe el wallet con id %s",args[0])
-		return nil,errors.New("El wallet ya existe")
+		fmt.Printf("Ya existe el wallet con id %s\n", args[0])
+		return nil, errors.New("El wallet ya existe")
 	}
 
 	walletId := NewV4()
@@ -214,6 +321,7 @@ func (t *SimpleChaincode) createWallet(stub shim.ChaincodeStubInterface, args []
 		Password: args[4],
 		Amount:   amt,
 		Limit:    limit,
+		Tier:     tiers[0].Name,
 	}
 
 	bytes, err := json.Marshal(wallet)
@@ -227,40 +335,15 @@ func (t *SimpleChaincode) createWallet(stub shim.ChaincodeStubInterface, args []
 		return nil, err
 	}
 
-	a := makeTimestamp()
+	a := makeTimestamp(stub)
 
 	fmt.Printf("Time: %d \n", a)
 
-	col1Val := args[0]
-	col2Val := "Create"
-	col3Val := "0"
-	col5Val := "W"
-
-	var columns []*shim.Column
-	col6 := shim.Column{Value: &shim.Column_String_{String_: "Movement"}}
-	col0 := shim.Column{Value: &shim.Column_String_{String_: col1Val}}
-	col1 := shim.Column{Value: &shim.Column_Int64{Int64: a}}
-	col2 := shim.Column{Value: &shim.Column_String_{String_: col2Val}}
-	col3 := shim.Column{Value: &shim.Column_String_{String_: col3Val}}
-	col4 := shim.Column{Value: &shim.Column_String_{String_: col3Val}}
-	col5 := shim.Column{Value: &shim.Column_String_{String_: col5Val}}
-	columns = append(columns, &col6)
-	columns = append(columns, &col0)
-	columns = append(columns, &col1)
-	columns = append(columns, &col2)
-	columns = append(columns, &col3)
-	columns = append(columns, &col4)
-	columns = append(columns, &col5)
-
-	row := shim.Row{Columns: columns}
-	ok, err := stub.InsertRow("Movimientos", row)
+	err = insertMovement(stub, args[0], "Create", 0, 0, "W", a, Attribution{}, Money{})
 	if err != nil {
-		return nil, fmt.Errorf("Insert Row Movimientos operation failed. %s", err)
-	}
-	if !ok {
-		return nil, errors.New("Fallo insertar Row with given key already exists")
+		return nil, err
 	}
-	
+
 	//Se inserta el Wallet en la Tabla de Wallets
 	fmt.Printf("Insertando Wallet en la Tabla")
 
@@ -270,7 +353,7 @@ func (t *SimpleChaincode) createWallet(stub shim.ChaincodeStubInterface, args []
 	col10 := shim.Column{Value: &shim.Column_String_{String_: "Wallet"}}
 	col11 := shim.Column{Value: &shim.Column_String_{String_: col11Val}}
 	col12 := shim.Column{Value: &shim.Column_String_{String_: "0.00"}}
-	
+
 	columns1 = append(columns1, &col10)
 	columns1 = append(columns1, &col11)
 	columns1 = append(columns1, &col12)
@@ -283,9 +366,9 @@ func (t *SimpleChaincode) createWallet(stub shim.ChaincodeStubInterface, args []
 	if !ok1 {
 		return nil, errors.New("Fallo insertar Row with given key already exists")
 	}
-	
+
 	//Se envia un evento de exito
-	err = stub.SetEvent("createWallet", []byte("createWallet:OK"))
+	err = stub.SetEvent(eventCreateWallet, []byte("createWallet:OK:"+args[0]))
 	if err != nil {
 		return nil, errors.New("Fallo enviar el evento de Crear Wallet")
 	}
@@ -304,86 +387,101 @@ func (t *SimpleChaincode) putBalance(stub shim.ChaincodeStubInterface, args []st
 	fmt.Printf("Business: %s\n", args[1])
 	fmt.Printf("Monto: %s\n", args[2])
 
-	bytesWallet1, err1 := stub.GetState(args[0])
+	//Solo comercios registrados y activos pueden cargar coins
+	_, err0 := checkMerchant(stub, args[1])
+	if err0 != nil {
+		return nil, err0
+	}
+
+	return creditWallet(stub, args[0], args[1], args[2])
+}
+
+//parseCoins - Monto de coins de un movimiento, positivo y finito, o cero si allowZero. ParseFloat acepta NaN e Inf,
+//que pasan cualquier comparacion contra el saldo o el limite
+func parseCoins(amount string, allowZero bool) (float64, error) {
+	amt, err := strconv.ParseFloat(amount, 64)
+	if err != nil || !(amt > 0 || allowZero && amt == 0) || math.IsInf(amt, 0) {
+		return 0, errors.New("Monto invalido: " + amount)
+	}
+	return amt, nil
+}
+
+//creditWallet - Carga coins del comercio al wallet y recalcula su tier
+func creditWallet(stub shim.ChaincodeStubInterface, walletId string, business string, amount string) ([]byte, error) {
+	if !walletExists(stub, walletId) {
+		return nil, errors.New("Wallet desconocido: " + walletId)
+	}
+
+	bytesWallet1, err1 := stub.GetState(walletId)
 
 	walletReceiver := Wallet{}
 	err := json.Unmarshal(bytesWallet1, &walletReceiver)
 
 	fmt.Println(walletReceiver)
 	if err1 != nil {
-		fmt.Println("Error retrieving " + args[0])
-		return nil, errors.New("Error retrieving " + args[0])
+		fmt.Println("Error retrieving " + walletId)
+		return nil, errors.New("Error retrieving " + walletId)
 	}
 
-	amt, err := strconv.ParseFloat(args[2], 64)
+	amt, err := parseCoins(amount, false)
+	if err != nil {
+		return nil, err
+	}
 
 	walletReceiver.Amount = walletReceiver.Amount + amt //carga coins al balance
 
-	walletReceiverJSONasBytes, _ := json.Marshal(walletReceiver)
-	err = stub.PutState(args[0], walletReceiverJSONasBytes) //rewrite the wallet
+	a := makeTimestamp(stub)
 
+	fmt.Printf("Time: %d \n", a)
+
+	//Se recalcula el tier con la acumulacion de los ultimos 12 meses
+	earned, err := rollingEarn(stub, walletId, a)
 	if err != nil {
 		return nil, err
 	}
+	err = updateTier(stub, &walletReceiver, earned+amt, a+1)
+	if err != nil {
+		return nil, fmt.Errorf("Fallo actualizar el tier. %s", err)
+	}
 
-	a := makeTimestamp()
+	walletReceiverJSONasBytes, _ := json.Marshal(walletReceiver)
+	err = stub.PutState(walletId, walletReceiverJSONasBytes) //rewrite the wallet
 
-	fmt.Printf("Time: %d \n", a)
+	if err != nil {
+		return nil, err
+	}
 
-	col1Val := args[0]
-	col2Val := args[1]
-	col3Val := strconv.FormatFloat(amt, 'f', 6, 64)
+	col1Val := walletId
 	col4Val := strconv.FormatFloat(walletReceiver.Amount, 'f', 6, 64)
-	col5Val := "C"
-
-	var columns []*shim.Column
-	col6 := shim.Column{Value: &shim.Column_String_{String_: "Movement"}}
-	col0 := shim.Column{Value: &shim.Column_String_{String_: col1Val}}
-	col1 := shim.Column{Value: &shim.Column_Int64{Int64: a}}
-	col2 := shim.Column{Value: &shim.Column_String_{String_: col2Val}}
-	col3 := shim.Column{Value: &shim.Column_String_{String_: col3Val}}
-	col4 := shim.Column{Value: &shim.Column_String_{String_: col4Val}}
-	col5 := shim.Column{Value: &shim.Column_String_{String_: col5Val}}
-	columns = append(columns, &col6)
-	columns = append(columns, &col0)
-	columns = append(columns, &col1)
-	columns = append(columns, &col2)
-	columns = append(columns, &col3)
-	columns = append(columns, &col4)
-	columns = append(columns, &col5)
 
-	row := shim.Row{Columns: columns}
-	ok, err := stub.InsertRow("Movimientos", row)
+	err = insertMovement(stub, walletId, business, amt, walletReceiver.Amount, "C", a, Attribution{}, Money{})
 	if err != nil {
-		return nil, fmt.Errorf("Insert Row Movimientos operation failed. %s", err)
-	}
-	if !ok {
-		return nil, errors.New("Fallo insertar Row with given key already exists")
+		return nil, err
 	}
-	
+
 	//Se actualiza el row de Wallet
 	var columns1 []*shim.Column
 	col10 := shim.Column{Value: &shim.Column_String_{String_: "Wallet"}}
 	col11 := shim.Column{Value: &shim.Column_String_{String_: col1Val}}
 	col12 := shim.Column{Value: &shim.Column_String_{String_: col4Val}}
-	
+
 	columns1 = append(columns1, &col10)
 	columns1 = append(columns1, &col11)
 	columns1 = append(columns1, &col12)
 
 	row1 := shim.Row{Columns: columns1}
-	ok2, err2 := stub.ReplaceRow("Wallet",row1)
+	ok2, err2 := stub.ReplaceRow("Wallet", row1)
 	if err2 != nil {
 		return nil, fmt.Errorf("Insert Row Wallet operation failed. %s", err2)
 	}
 	if !ok2 {
 		return nil, errors.New("Fallo insertar Row Wallet with given key already exists")
 	}
-	
-	//Se envia un evento de exito
-	err = stub.SetEvent("debitEvent", []byte("debitEvent:4"))
+
+	//Se envia un evento de exito con el movimiento
+	err = setBalanceEvent(stub, Movement{Time: a, WalletId: walletId, Business: business, Amount: amt, Balance: walletReceiver.Amount, Type: "C"})
 	if err != nil {
-		return nil, errors.New("Fallo enviar el evento de debito")
+		return nil, err
 	}
 
 	return []byte(`{"code":0,"response":null}`), nil
@@ -397,103 +495,111 @@ func (t *SimpleChaincode) debitBalance(stub shim.ChaincodeStubInterface, args []
 	}
 
 	fmt.Printf("WalletId 1: %s\n", args[0])
-	fmt.Printf("Monto: %s\n", args[1])
+	fmt.Printf("Business: %s\n", args[1])
+	fmt.Printf("Monto: %s\n", args[2])
+
+	//Solo comercios registrados y activos pueden debitar coins
+	_, err0 := checkMerchant(stub, args[1])
+	if err0 != nil {
+		return nil, err0
+	}
+
+	return debitWallet(stub, args[0], args[1], args[2])
+}
 
-	bytesWallet1, err1 := stub.GetState(args[0])
+//debitWallet - Debita coins del wallet a favor del comercio y los devuelve al balance global
+func debitWallet(stub shim.ChaincodeStubInterface, walletId string, business string, amount string) ([]byte, error) {
+	if !walletExists(stub, walletId) {
+		return nil, errors.New("Wallet desconocido: " + walletId)
+	}
+
+	bytesWallet1, err1 := stub.GetState(walletId)
 
 	walletReceiver := Wallet{}
 	err := json.Unmarshal(bytesWallet1, &walletReceiver)
 
 	fmt.Println(walletReceiver)
 	if err1 != nil {
-		fmt.Println("Error retrieving " + args[0])
-		return nil, errors.New("Error retrieving " + args[0])
+		fmt.Println("Error retrieving " + walletId)
+		return nil, errors.New("Error retrieving " + walletId)
 	}
 
-	amt, err := strconv.ParseFloat(args[2], 64)
+	amt, err := parseCoins(amount, false)
+	if err != nil {
+		return nil, err
+	}
+	if amt > walletReceiver.Amount {
+		return nil, errors.New("El cliente no cuenta con coins suficientes")
+	}
+	err = checkLimit(&walletReceiver, amt)
+	if err != nil {
+		return nil, err
+	}
 
 	walletReceiver.Amount = walletReceiver.Amount - amt //debita coins del balance
 	walletReceiver.Limit = walletReceiver.Limit - amt
 
 	walletReceiverJSONasBytes, _ := json.Marshal(walletReceiver)
-	err = stub.PutState(args[0], walletReceiverJSONasBytes) //rewrite the wallet
+	err = stub.PutState(walletId, walletReceiverJSONasBytes) //rewrite the wallet
 
 	if err != nil {
 		return nil, err
 	}
 
-	a := makeTimestamp()
+	a := makeTimestamp(stub)
 
 	fmt.Printf("Time: %d \n", a)
 
-	col1Val := args[0]
-	col2Val := args[1]
-	col3Val := strconv.FormatFloat(amt, 'f', 6, 64)
+	col1Val := walletId
 	col4Val := strconv.FormatFloat(walletReceiver.Amount, 'f', 6, 64)
-	col5Val := "D"
 
-	var columns []*shim.Column
-	col6 := shim.Column{Value: &shim.Column_String_{String_: "Movement"}}
-	col0 := shim.Column{Value: &shim.Column_String_{String_: col1Val}}
-	col1 := shim.Column{Value: &shim.Column_Int64{Int64: a}}
-	col2 := shim.Column{Value: &shim.Column_String_{String_: col2Val}}
-	col3 := shim.Column{Value: &shim.Column_String_{String_: col3Val}}
-	col4 := shim.Column{Value: &shim.Column_String_{String_: col4Val}}
-	col5 := shim.Column{Value: &shim.Column_String_{String_: col5Val}}
-	columns = append(columns, &col6)
-	columns = append(columns, &col0)
-	columns = append(columns, &col1)
-	columns = append(columns, &col2)
-	columns = append(columns, &col3)
-	columns = append(columns, &col4)
-	columns = append(columns, &col5)
-
-	row := shim.Row{Columns: columns}
-	ok, err := stub.InsertRow("Movimientos", row)
+	err = insertMovement(stub, walletId, business, amt, walletReceiver.Amount, "D", a, Attribution{}, Money{})
 	if err != nil {
-		return nil, fmt.Errorf("Insert Row Movimientos operation failed. %s", err)
-	}
-	if !ok {
-		return nil, errors.New("Fallo insertar Row with given key already exists")
+		return nil, err
 	}
-	
+
 	//Se actualiza el row de Wallet
 	var columns1 []*shim.Column
 	col10 := shim.Column{Value: &shim.Column_String_{String_: "Wallet"}}
 	col11 := shim.Column{Value: &shim.Column_String_{String_: col1Val}}
 	col12 := shim.Column{Value: &shim.Column_String_{String_: col4Val}}
-	
+
 	columns1 = append(columns1, &col10)
 	columns1 = append(columns1, &col11)
 	columns1 = append(columns1, &col12)
 
 	row1 := shim.Row{Columns: columns1}
-	ok3, err3 := stub.ReplaceRow("Wallet",row1)
+	ok3, err3 := stub.ReplaceRow("Wallet", row1)
 	if err3 != nil {
 		return nil, fmt.Errorf("Insert Row Wallet operation failed. %s", err3)
 	}
 	if !ok3 {
 		return nil, errors.New("Fallo insertar Row Wallet with given key already exists")
 	}
-	
+
 	//Se actualiza el balance global de coin
-	
+
 	coinBalance, err2 := stub.GetState("coinBalance")
 	fmt.Println(coinBalance)
 	if err2 != nil {
 		fmt.Println("Error retrieving coinBalance")
 		return nil, errors.New("Error retrieving coinBalance")
 	}
-	
-	newCoinBalance,_ := strconv.ParseFloat(string(coinBalance), 64)
+
+	newCoinBalance, _ := strconv.ParseFloat(string(coinBalance), 64)
 	newCoinBalance = newCoinBalance + amt
-	
+
 	err = stub.PutState("coinBalance", []byte(strconv.FormatFloat(newCoinBalance, 'f', 6, 64)))
 	if err != nil {
 		fmt.Println("Error setting new coinBalance")
 		return nil, err
 	}
 
+	err = setBalanceEvent(stub, Movement{Time: a, WalletId: walletId, Business: business, Amount: amt, Balance: walletReceiver.Amount, Type: "D"})
+	if err != nil {
+		return nil, err
+	}
+
 	return []byte(`{"code":0,"response":null}`), nil
 }
 
@@ -508,6 +614,12 @@ func (t *SimpleChaincode) transfer(stub shim.ChaincodeStubInterface, args []stri
 	fmt.Printf("WalletId 2: %s\n", args[0])
 	fmt.Printf("Monto: %s\n", args[2])
 
+	for _, walletId := range args[:2] {
+		if !walletExists(stub, walletId) {
+			return nil, errors.New("Wallet desconocido: " + walletId)
+		}
+	}
+
 	bytesWallet1, err1 := stub.GetState(args[1])
 
 	walletSender := Wallet{}
@@ -528,7 +640,21 @@ func (t *SimpleChaincode) transfer(stub shim.ChaincodeStubInterface, args []stri
 	}
 	fmt.Println(walletReceiver)
 
-	amt, err := strconv.ParseFloat(args[2], 64)
+	amt, err := parseCoins(args[2], false)
+	if err != nil {
+		return nil, err
+	}
+
+	tier := tierByName(walletSender.Tier)
+	if amt > tier.Transfer {
+		return nil, fmt.Errorf("El monto excede el tope de transferencia del tier %s", tier.Name)
+	}
+	if amt <= walletSender.Amount {
+		err = checkLimit(&walletSender, amt)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	if amt <= walletSender.Amount {
 		walletSender.Amount = walletSender.Amount - amt     //debita el monto
@@ -550,57 +676,33 @@ func (t *SimpleChaincode) transfer(stub shim.ChaincodeStubInterface, args []stri
 			return nil, err
 		}
 
-		a := makeTimestamp()
+		a := makeTimestamp(stub)
 
 		fmt.Printf("Time: %d \n", a)
 
 		col1Val := args[0]
-		col2Val := args[1]
-		col3Val := strconv.FormatFloat(amt, 'f', 6, 64)
 		col4Val := strconv.FormatFloat(walletReceiver.Amount, 'f', 6, 64)
-		col5Val := "C"
-
-		var columns []*shim.Column
-		var columns2 []*shim.Column
-		col6 := shim.Column{Value: &shim.Column_String_{String_: "Movement"}}
-		col0 := shim.Column{Value: &shim.Column_String_{String_: col1Val}}
-		col1 := shim.Column{Value: &shim.Column_Int64{Int64: a}}
-		col2 := shim.Column{Value: &shim.Column_String_{String_: col2Val}}
-		col3 := shim.Column{Value: &shim.Column_String_{String_: col3Val}}
-		col4 := shim.Column{Value: &shim.Column_String_{String_: col4Val}}
-		col5 := shim.Column{Value: &shim.Column_String_{String_: col5Val}}
-		columns = append(columns, &col6)
-		columns = append(columns, &col0)
-		columns = append(columns, &col1)
-		columns = append(columns, &col2)
-		columns = append(columns, &col3)
-		columns = append(columns, &col4)
-		columns = append(columns, &col5)
-		
-		row := shim.Row{Columns: columns}
-		ok, err := stub.InsertRow("Movimientos", row)
+
+		err = insertMovement(stub, args[0], args[1], amt, walletReceiver.Amount, "C", a, Attribution{}, Money{})
 		if err != nil {
 			fmt.Println("Error al insertar la fila de sender")
-			return nil, fmt.Errorf("Insert Row Movimientos operation failed. %s", err)
-		}
-		if !ok {
-			return nil, errors.New("Fallo insertar Row with given key already exists")
+			return nil, err
 		}
 
 		fmt.Println("Inserto Fila de Sender")
-		
+
 		//Se actualiza el row de Wallet Sender
 		var columns1 []*shim.Column
 		col10 := shim.Column{Value: &shim.Column_String_{String_: "Wallet"}}
 		col11 := shim.Column{Value: &shim.Column_String_{String_: col1Val}}
 		col12 := shim.Column{Value: &shim.Column_String_{String_: col4Val}}
-	
+
 		columns1 = append(columns1, &col10)
 		columns1 = append(columns1, &col11)
 		columns1 = append(columns1, &col12)
-	
+
 		row1 := shim.Row{Columns: columns1}
-		ok3, err3 := stub.ReplaceRow("Wallet",row1)
+		ok3, err3 := stub.ReplaceRow("Wallet", row1)
 		if err3 != nil {
 			return nil, fmt.Errorf("Insert Row Wallet operation failed. %s", err3)
 		}
@@ -609,54 +711,33 @@ func (t *SimpleChaincode) transfer(stub shim.ChaincodeStubInterface, args []stri
 		}
 
 		//Se inserta fila de Receiver
-		b := a+1
-		
+		b := a + 1
+
 		fmt.Printf("Time: %d \n", b)
-		
+
 		col1Val = args[1]
-		col2Val = args[0]
-		col3Val = strconv.FormatFloat(amt, 'f', 6, 64)
 		col4Val = strconv.FormatFloat(walletSender.Amount, 'f', 6, 64)
-		col5Val = "D"
-
-		col0 = shim.Column{Value: &shim.Column_String_{String_: col1Val}}
-		col1 = shim.Column{Value: &shim.Column_Int64{Int64: b}}
-		col2 = shim.Column{Value: &shim.Column_String_{String_: col2Val}}
-		col3 = shim.Column{Value: &shim.Column_String_{String_: col3Val}}
-		col4 = shim.Column{Value: &shim.Column_String_{String_: col4Val}}
-		col5 = shim.Column{Value: &shim.Column_String_{String_: col5Val}}
-		columns2 = append(columns2, &col6)
-		columns2 = append(columns2, &col0)
-		columns2 = append(columns2, &col1)
-		columns2 = append(columns2, &col2)
-		columns2 = append(columns2, &col3)
-		columns2 = append(columns2, &col4)
-		columns2 = append(columns2, &col5)
-
-		row2 := shim.Row{Columns: columns2}
-		ok2, err2 := stub.InsertRow("Movimientos", row2)
-		if err2 != nil {
+
+		err = insertMovement(stub, args[1], args[0], amt, walletSender.Amount, "D", b, Attribution{}, Money{})
+		if err != nil {
 			fmt.Println("Error al insertar la fila de receiver")
-			return nil, fmt.Errorf("Insert Row2 Movimientos operation failed. %s", err2)
-		}
-		if !ok2 {
-			return nil, errors.New("Fallo insertar Row2 with given key already exists")
+			return nil, err
 		}
 
 		fmt.Println("Inserto fila de receiver")
-		
+
 		//Se actualiza el row de Wallet Receiver
 		var columns3 []*shim.Column
 		col20 := shim.Column{Value: &shim.Column_String_{String_: "Wallet"}}
 		col21 := shim.Column{Value: &shim.Column_String_{String_: col1Val}}
 		col22 := shim.Column{Value: &shim.Column_String_{String_: col4Val}}
-	
+
 		columns3 = append(columns3, &col20)
 		columns3 = append(columns3, &col21)
 		columns3 = append(columns3, &col22)
-	
+
 		row3 := shim.Row{Columns: columns3}
-		ok4, err4 := stub.ReplaceRow("Wallet",row3)
+		ok4, err4 := stub.ReplaceRow("Wallet", row3)
 		if err4 != nil {
 			return nil, fmt.Errorf("Insert Row Wallet operation failed. %s", err4)
 		}
@@ -664,6 +745,13 @@ func (t *SimpleChaincode) transfer(stub shim.ChaincodeStubInterface, args []stri
 			return nil, errors.New("Fallo insertar Row Wallet with given key already exists")
 		}
 
+		err = setBalanceEvent(stub,
+			Movement{Time: a, WalletId: args[0], Business: args[1], Amount: amt, Balance: walletReceiver.Amount, Type: "C"},
+			Movement{Time: b, WalletId: args[1], Business: args[0], Amount: amt, Balance: walletSender.Amount, Type: "D"})
+		if err != nil {
+			return nil, err
+		}
+
 		return []byte(`{"code":0,"response":null}`), nil
 	} else {
 		return nil, errors.New("No cuentas con suficientes coins para esta transferencia")
@@ -695,7 +783,9 @@ func (t *SimpleChaincode) getBalance(stub shim.ChaincodeStubInterface, args []st
 		return nil, errors.New("Error retrieving Balance" + args[0])
 	}
 
-	return []byte(fmt.Sprintf(`{"code":0,"balance":"%s","limit":"%s"}`, strconv.FormatFloat(wallet.Amount, 'f', 6, 64), strconv.FormatFloat(wallet.Limit, 'f', 6, 64))), nil
+	tier := tierByName(wallet.Tier)
+
+	return []byte(fmt.Sprintf(`{"code":0,"balance":"%s","limit":"%s","tier":"%s","bonus":"%s"}`, strconv.FormatFloat(wallet.Amount, 'f', 6, 64), strconv.FormatFloat(wallet.Limit, 'f', 6, 64), tier.Name, strconv.FormatFloat(tier.Bonus, 'f', 6, 64))), nil
 }
 
 //Funcion que obtiene el total de Coins en el sistema
@@ -715,7 +805,7 @@ func (t *SimpleChaincode) getTotalCoin(stub shim.ChaincodeStubInterface, args []
 //Funcion que otorga coins a los negocios
 func (t *SimpleChaincode) debitTotalCoin(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	fmt.Println("Call----debitTotalCoin() is running----")
-	
+
 	if len(args) != 1 {
 		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
 	}
@@ -726,11 +816,11 @@ func (t *SimpleChaincode) debitTotalCoin(stub shim.ChaincodeStubInterface, args
 		fmt.Println("Error retrieving coinBalance")
 		return nil, errors.New("Error retrieving coinBalance")
 	}
-	
-	newCoinBalance,_ := strconv.ParseFloat(string(coinBalance), 64)
-	amount,_ := strconv.ParseFloat(args[0], 64)
+
+	newCoinBalance, _ := strconv.ParseFloat(string(coinBalance), 64)
+	amount, _ := strconv.ParseFloat(args[0], 64)
 	newCoinBalance = newCoinBalance - amount
-	
+
 	err = stub.PutState("coinBalance", []byte(strconv.FormatFloat(newCoinBalance, 'f', 6, 64)))
 	if err != nil {
 		fmt.Println("Error setting new coinBalance")
@@ -743,7 +833,7 @@ func (t *SimpleChaincode) debitTotalCoin(stub shim.ChaincodeStubInterface, args
 //Funcion que devuelve coins a los negocios
 func (t *SimpleChaincode) putTotalCoin(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	fmt.Println("Call----putTotalCoin() is running----")
-	
+
 	if len(args) != 1 {
 		return nil, errors.New("Incorrecto numero de argumentos. Se esperaba 1")
 	}
@@ -754,12 +844,12 @@ func (t *SimpleChaincode) putTotalCoin(stub shim.ChaincodeStubInterface, args []
 		fmt.Println("Error retrieving coinBalance")
 		return nil, errors.New("Error retrieving coinBalance")
 	}
-	
-	newCoinBalance,_ := strconv.ParseFloat(string(coinBalance), 64)
-	amount,_ := strconv.ParseFloat(args[0], 64)
-	
+
+	newCoinBalance, _ := strconv.ParseFloat(string(coinBalance), 64)
+	amount, _ := strconv.ParseFloat(args[0], 64)
+
 	newCoinBalance = newCoinBalance + amount
-	
+
 	err = stub.PutState("coinBalance", []byte(strconv.FormatFloat(newCoinBalance, 'f', 6, 64)))
 	if err != nil {
 		fmt.Println("Error setting new coinBalance")
@@ -787,7 +877,7 @@ func (t *SimpleChaincode) getMovimientos(stub shim.ChaincodeStubInterface, args
 		col1 := shim.Column{Value: &shim.Column_String_{String_: walletId}}
 		columns = append(columns, col1)
 	}
-	
+
 	rowChannel, err := stub.GetRows("Movimientos", columns)
 	if err != nil {
 		return nil, fmt.Errorf("getRowTableOne operation failed. %s", err)
@@ -801,9 +891,22 @@ func (t *SimpleChaincode) getMovimientos(stub shim.ChaincodeStubInterface, args
 				rowChannel = nil
 			} else {
 				columnas := row.GetColumns()
+				//Los cambios de tier de versiones anteriores se guardaban como movimientos "T" sin saldo
+				if columnas[6].GetString_() == "T" {
+					continue
+				}
 				amountRow, _ := strconv.ParseFloat(columnas[4].GetString_(), 64)
 				balanceRow, _ := strconv.ParseFloat(columnas[5].GetString_(), 64)
 				movimiento := Movement{Time: columnas[2].GetInt64(), WalletId: columnas[1].GetString_(), Business: columnas[3].GetString_(), Amount: amountRow, Balance: balanceRow, Type: columnas[6].GetString_()}
+				if len(columnas) > 9 {
+					movimiento.Store = columnas[7].GetString_()
+					movimiento.Terminal = columnas[8].GetString_()
+					movimiento.Cashier = columnas[9].GetString_()
+				}
+				if len(columnas) > 11 {
+					movimiento.Currency = columnas[10].GetString_()
+					movimiento.Minor = columnas[11].GetInt64()
+				}
 
 				movimientos = append(movimientos, movimiento)
 			}
@@ -893,11 +996,10 @@ func (t *SimpleChaincode) getDatos(stub shim.ChaincodeStubInterface, args []stri
 	return bytes, nil
 }
 
-
 //Reinica los limites
 func (t *SimpleChaincode) reset(stub shim.ChaincodeStubInterface, args []string) ([]byte, error) {
 	fmt.Println("Call----Reset() is running----")
-	
+
 	var columns []shim.Column
 	col1 := shim.Column{Value: &shim.Column_String_{String_: "Wallet"}}
 	columns = append(columns, col1)
@@ -907,6 +1009,8 @@ func (t *SimpleChaincode) reset(stub shim.ChaincodeStubInterface, args []string)
 		return nil, fmt.Errorf("getRow TableWallet operation failed. %s", err)
 	}
 
+	a := makeTimestamp(stub)
+
 	for {
 		select {
 		case row, ok := <-rowChannel:
@@ -914,22 +1018,32 @@ func (t *SimpleChaincode) reset(stub shim.ChaincodeStubInterface, args []string)
 				rowChannel = nil
 			} else {
 				columnas := row.GetColumns()
-				
-				bytesWallet1, err7:= stub.GetState(columnas[1].GetString_())
+
+				bytesWallet1, err7 := stub.GetState(columnas[1].GetString_())
 				wallet := Wallet{}
 				err7 = json.Unmarshal(bytesWallet1, &wallet)
-			
+
 				fmt.Println(wallet)
 				if err7 != nil {
 					fmt.Println("Error retrieving " + args[0])
 					return nil, errors.New("Error retrieving " + args[0])
 				}
-			
-				wallet.Limit = limit //reinicia el limite del cliente
-			
+
+				//Se recalcula el tier, la acumulacion antigua sale de la ventana
+				earned, err9 := rollingEarn(stub, wallet.Id, a)
+				if err9 != nil {
+					return nil, err9
+				}
+				err9 = updateTier(stub, &wallet, earned, a)
+				if err9 != nil {
+					return nil, fmt.Errorf("Fallo actualizar el tier. %s", err9)
+				}
+
+				wallet.Limit = tierByName(wallet.Tier).Limit //reinicia el limite del cliente segun su tier
+
 				walletJSONasBytes, _ := json.Marshal(wallet)
 				err8 := stub.PutState(columnas[1].GetString_(), walletJSONasBytes) //rewrite the wallet
-			
+
 				if err8 != nil {
 					return nil, err8
 				}
@@ -943,6 +1057,67 @@ func (t *SimpleChaincode) reset(stub shim.ChaincodeStubInterface, args []string)
 	return []byte(fmt.Sprintf(`{"code":0,"response":"OK"}`)), nil
 }
 
+//insertMovement - Inserta una fila en la tabla de Movimientos
+//money es el monto de la compra en su moneda, vacio si el movimiento no viene de una compra
+func insertMovement(stub shim.ChaincodeStubInterface, walletId string, business string, amount float64, balance float64, tipo string, time int64, attribution Attribution, money Money) error {
+	var columns []*shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Movement"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: walletId}}
+	col2 := shim.Column{Value: &shim.Column_Int64{Int64: time}}
+	col3 := shim.Column{Value: &shim.Column_String_{String_: business}}
+	col4 := shim.Column{Value: &shim.Column_String_{String_: strconv.FormatFloat(amount, 'f', 6, 64)}}
+	col5 := shim.Column{Value: &shim.Column_String_{String_: strconv.FormatFloat(balance, 'f', 6, 64)}}
+	col6 := shim.Column{Value: &shim.Column_String_{String_: tipo}}
+	col7 := shim.Column{Value: &shim.Column_String_{String_: attribution.Store}}
+	col8 := shim.Column{Value: &shim.Column_String_{String_: attribution.Terminal}}
+	col9 := shim.Column{Value: &shim.Column_String_{String_: attribution.Cashier}}
+	col10 := shim.Column{Value: &shim.Column_String_{String_: money.Currency}}
+	col11 := shim.Column{Value: &shim.Column_Int64{Int64: money.Minor}}
+	columns = append(columns, &col0)
+	columns = append(columns, &col1)
+	columns = append(columns, &col2)
+	columns = append(columns, &col3)
+	columns = append(columns, &col4)
+	columns = append(columns, &col5)
+	columns = append(columns, &col6)
+	columns = append(columns, &col7)
+	columns = append(columns, &col8)
+	columns = append(columns, &col9)
+	columns = append(columns, &col10)
+	columns = append(columns, &col11)
+
+	row := shim.Row{Columns: columns}
+	ok, err := stub.InsertRow("Movimientos", row)
+	if err != nil {
+		return fmt.Errorf("Insert Row Movimientos operation failed. %s", err)
+	}
+	if !ok {
+		return errors.New("Fallo insertar Row with given key already exists")
+	}
+	return nil
+}
+
+//replaceWalletRow - Actualiza el balance del wallet en la tabla de Wallets
+func replaceWalletRow(stub shim.ChaincodeStubInterface, walletId string, balance float64) error {
+	var columns []*shim.Column
+	col0 := shim.Column{Value: &shim.Column_String_{String_: "Wallet"}}
+	col1 := shim.Column{Value: &shim.Column_String_{String_: walletId}}
+	col2 := shim.Column{Value: &shim.Column_String_{String_: strconv.FormatFloat(balance, 'f', 6, 64)}}
+	columns = append(columns, &col0)
+	columns = append(columns, &col1)
+	columns = append(columns, &col2)
+
+	row := shim.Row{Columns: columns}
+	ok, err := stub.ReplaceRow("Wallet", row)
+	if err != nil {
+		return fmt.Errorf("Insert Row Wallet operation failed. %s", err)
+	}
+	if !ok {
+		return errors.New("Fallo insertar Row Wallet with given key already exists")
+	}
+	return nil
+}
+
 func safeRandom(dest []byte) {
 	if _, err := rand.Read(dest); err != nil {
 		panic(err)
@@ -1003,6 +1178,12 @@ func NewV4() UUID {
 	return u
 }
 
-func makeTimestamp() int64 {
-	return time.Now().UnixNano() / int64(time.Millisecond)
+//makeTimestamp - Tiempo de la transaccion en milisegundos. Es el mismo en todos los peers, y en el simulador
+//lo controla Network.Now
+func makeTimestamp(stub shim.ChaincodeStubInterface) int64 {
+	txTime, err := stub.GetTxTimestamp()
+	if err != nil || txTime == nil {
+		return time.Now().UnixNano() / int64(time.Millisecond)
+	}
+	return txTime.Seconds*1000 + int64(txTime.Nanos)/int64(time.Millisecond)
 }
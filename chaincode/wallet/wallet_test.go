@@ -0,0 +1,216 @@
+/*
+* Adrian Pareja
+ */
+package wallet_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ccamaleon5/blockchain/chaincode/wallet"
+	"github.com/ccamaleon5/blockchain/client"
+	"github.com/ccamaleon5/blockchain/simulator"
+)
+
+var start = time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+//newNetwork - Red con el wallet, el comercio cineplanet y los wallets w1 y w2
+func newNetwork(t *testing.T) *simulator.Network {
+	n := simulator.NewNetwork()
+	n.SetTime(start)
+	n.Attributes = map[string]string{"role": "admin", "merchant": "cineplanet"}
+	invoke(t, n, "init", "1000000")
+	invoke(t, n, "registermerchant", "cineplanet", "Cineplanet", "cineplanet")
+	invoke(t, n, "createwallet", "w1", "w1@mail.com", "999", "123", "pw", "0")
+	invoke(t, n, "createwallet", "w2", "w2@mail.com", "998", "124", "pw", "0")
+	return n
+}
+
+//invoke - Ejecuta una funcion del wallet y falla el test si devuelve error. init lo despliega
+func invoke(t *testing.T, n *simulator.Network, function string, args ...string) string {
+	t.Helper()
+	var response []byte
+	var err error
+	if function == "init" {
+		response, err = n.Deploy("wallet", new(wallet.SimpleChaincode), function, args...)
+	} else {
+		response, err = n.Invoke("wallet", function, args...)
+	}
+	if err != nil {
+		t.Fatalf("%s %v: %s", function, args, err)
+	}
+	return string(response)
+}
+
+//invokeError - Ejecuta una funcion del wallet que debe fallar con un mensaje que contiene fragment
+func invokeError(t *testing.T, n *simulator.Network, fragment string, function string, args ...string) {
+	t.Helper()
+	_, err := n.Invoke("wallet", function, args...)
+	if err == nil || !strings.Contains(err.Error(), fragment) {
+		t.Fatalf("%s %v: se esperaba el error %q y se obtuvo %v", function, args, fragment, err)
+	}
+}
+
+func query(t *testing.T, n *simulator.Network, function string, args ...string) string {
+	t.Helper()
+	response, err := n.Query("wallet", function, args...)
+	if err != nil {
+		t.Fatalf("%s %v: %s", function, args, err)
+	}
+	return string(response)
+}
+
+func TestLimit(t *testing.T) {
+	n := newNetwork(t)
+	invoke(t, n, "putbalance", "w1", "cineplanet", "500")
+
+	invoke(t, n, "debitbalance", "w1", "cineplanet", "80")
+	invokeError(t, n, "alcanzo el tope de su limite", "debitbalance", "w1", "cineplanet", "30")
+	invokeError(t, n, "alcanzo el tope de su limite", "transfer", "w2", "w1", "30")
+	invoke(t, n, "transfer", "w2", "w1", "20")
+
+	invoke(t, n, "reset")
+	invoke(t, n, "debitbalance", "w1", "cineplanet", "30")
+	if balance := query(t, n, "getbalance", "w1"); !strings.Contains(balance, `"limit":"70.000000"`) {
+		t.Fatalf("limite incorrecto despues de reset: %s", balance)
+	}
+}
+
+func TestLimitTierUpgrade(t *testing.T) {
+	n := newNetwork(t)
+	invoke(t, n, "putbalance", "w1", "cineplanet", "500")
+	invoke(t, n, "debitbalance", "w1", "cineplanet", "60")
+
+	//Silver tiene 200 de limite, se mantienen los 60 ya consumidos
+	invoke(t, n, "putbalance", "w1", "cineplanet", "600")
+	balance := query(t, n, "getbalance", "w1")
+	if !strings.Contains(balance, `"tier":"Silver"`) || !strings.Contains(balance, `"limit":"140.000000"`) {
+		t.Fatalf("el cambio de tier no ajusto el limite: %s", balance)
+	}
+	invoke(t, n, "debitbalance", "w1", "cineplanet", "120")
+
+	//El cambio de tier queda en su historial y no entre los movimientos de saldo
+	if history := query(t, n, "gettierhistory", "w1"); !strings.Contains(history, `"from":"Classic","to":"Silver"`) {
+		t.Fatalf("historial de tier incorrecto: %s", history)
+	}
+	if movements := query(t, n, "getmovimientos", "Movement", "w1"); strings.Contains(movements, `"type":"T"`) || strings.Contains(movements, "Silver") {
+		t.Fatalf("el cambio de tier aparece en los movimientos: %s", movements)
+	}
+}
+
+//payIntent - Texto de una intencion de pago con su checksum, sin validar el monto
+func payIntent(kind string, payee string, amount string, reference string, expiry time.Time) string {
+	text := strings.Join([]string{"LP1", kind, payee, amount, reference, strconv.FormatInt(expiry.UnixNano()/int64(time.Millisecond), 10)}, ":") + ":"
+	return text + fmt.Sprintf("%08X", crc32.ChecksumIEEE([]byte(text)))
+}
+
+func TestPayIntent(t *testing.T) {
+	n := newNetwork(t)
+	invoke(t, n, "putbalance", "w1", "cineplanet", "50")
+	expiry := start.Add(time.Hour)
+
+	//Ni el administrador ni otro cliente pagan desde el wallet de w1
+	invokeError(t, n, "No autorizado", "payintent", "w1", payIntent("M", "cineplanet", "1", "pedido-1", expiry))
+	n.Attributes = map[string]string{"role": "admin", "merchant": "cineplanet", "wallet": "w2"}
+	invokeError(t, n, "No autorizado", "payintent", "w1", payIntent("M", "cineplanet", "1", "pedido-1", expiry))
+	n.Attributes = map[string]string{"role": "admin", "merchant": "cineplanet", "wallet": "w1"}
+
+	for _, amount := range []string{"NaN", "Inf", "-Inf", "-5"} {
+		invokeError(t, n, "Monto invalido", "payintent", "w1", payIntent("M", "cineplanet", amount, "pedido-1", expiry))
+	}
+	invokeError(t, n, "Monto invalido", "debitbalance", "w1", "cineplanet", "NaN")
+	invokeError(t, n, "Monto invalido", "transfer", "w2", "w1", "NaN")
+
+	//Una intencion armada con la misma referencia y otro monto no bloquea el QR real
+	invoke(t, n, "payintent", "w1", payIntent("M", "cineplanet", "1", "pedido-1", expiry))
+	real := payIntent("M", "cineplanet", "20", "pedido-1", expiry)
+	if status := query(t, n, "getpayintent", real); !strings.Contains(status, `"status":"pending"`) {
+		t.Fatalf("la intencion real quedo pagada: %s", status)
+	}
+	invoke(t, n, "payintent", "w1", real)
+	invokeError(t, n, "ya fue pagada", "payintent", "w1", real)
+	if balance := query(t, n, "getbalance", "w1"); !strings.Contains(balance, `"balance":"29.000000"`) {
+		t.Fatalf("balance incorrecto: %s", balance)
+	}
+}
+
+func TestEarnVoucherCap(t *testing.T) {
+	n := newNetwork(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err := client.MarshalMerchantKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoke(t, n, "setmerchantkey", "cineplanet", public)
+
+	voucher := func(id string, amount float64) string {
+		text, err := client.EarnVoucher{Merchant: "cineplanet", Id: id, Amount: amount, Expiry: start.Add(time.Hour).UnixNano() / int64(time.Millisecond)}.Sign(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return text
+	}
+
+	//Sin tope el comercio no acredita vouchers
+	invokeError(t, n, "tope de vouchers", "redeemearnvoucher", "w1", voucher("R-1", 10))
+
+	invoke(t, n, "setearncap", "cineplanet", "15")
+	invoke(t, n, "redeemearnvoucher", "w1", voucher("R-1", 10))
+	invokeError(t, n, "disponible: 5.000000", "redeemearnvoucher", "w1", voucher("R-2", 10))
+	invoke(t, n, "redeemearnvoucher", "w1", voucher("R-3", 5))
+	if earnCap := query(t, n, "getearncap", "cineplanet"); !strings.Contains(earnCap, `"cap":15,"issued":15`) {
+		t.Fatalf("tope incorrecto: %s", earnCap)
+	}
+
+	//NaN no pasa la validacion del monto aunque la firma sea valida
+	invoke(t, n, "setearncap", "cineplanet", "100")
+	nan := strings.Replace(voucher("R-4", 1), ":1:", ":NaN:", 1)
+	invokeError(t, n, "Monto invalido", "redeemearnvoucher", "w1", nan)
+}
+
+func TestCoinPriceFinite(t *testing.T) {
+	n := newNetwork(t)
+	from := strconv.FormatInt(start.Add(-time.Hour).UnixNano()/int64(time.Millisecond), 10)
+	to := strconv.FormatInt(start.Add(time.Hour).UnixNano()/int64(time.Millisecond), 10)
+	for _, price := range []string{"NaN", "Inf", "-Inf", "-1"} {
+		invokeError(t, n, "Precio invalido", "setcoinprice", price)
+		if _, err := n.Query("wallet", "getsettlement", from, to, price); err == nil || !strings.Contains(err.Error(), "Precio invalido") {
+			t.Fatalf("getsettlement con precio %s: %v", price, err)
+		}
+	}
+	invoke(t, n, "setcoinprice", "0.05")
+	query(t, n, "getsettlement", from, to)
+}
+
+func TestPurchaseCoinsFinite(t *testing.T) {
+	n := newNetwork(t)
+	invoke(t, n, "putbalance", "w1", "cineplanet", "50")
+	for _, coins := range []string{"NaN", "Inf", "-Inf", "-1"} {
+		invokeError(t, n, "Coins a canjear invalidos", "purchase", "w1", "cineplanet", coins, "0")
+		invokeError(t, n, "Coins a acumular invalidos", "purchase", "w1", "cineplanet", "0", coins)
+	}
+	invoke(t, n, "purchase", "w1", "cineplanet", "0", "10")
+	invoke(t, n, "purchase", "w1", "cineplanet", "10", "0")
+}
+
+func TestMerchantCaller(t *testing.T) {
+	n := newNetwork(t)
+	invokeError(t, n, "caller del comercio es obligatorio", "registermerchant", "promart", "Promart", "")
+	invokeError(t, n, "caller del comercio es obligatorio", "updatemerchant", "cineplanet", "Cineplanet", "")
+
+	//Ni el administrador sin el atributo ni otro comercio operan como cineplanet
+	for _, attributes := range []map[string]string{{"role": "admin"}, {"role": "admin", "merchant": "promart"}} {
+		n.Attributes = attributes
+		invokeError(t, n, "No autorizado para operar como el comercio", "putbalance", "w1", "cineplanet", "10")
+	}
+}
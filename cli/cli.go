@@ -0,0 +1,79 @@
+/*
+* Adrian Pareja
+ */
+
+//Package cli implementa los subcomandos del cliente de linea de comandos sobre un client.Transport,
+//de modo que se puede ejecutar contra un peer o contra el ledger simulado.
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ccamaleon5/blockchain/client"
+)
+
+//Options - Structure for the flags that apply to every subcommand
+type Options struct {
+	//Output es table o json
+	Output string
+	//Merchant es el alias del comercio en el perfil o el id de su chaincode
+	Merchant string
+}
+
+//Run - Ejecuta un subcomando: contrato (wallet|merchant), funcion y argumentos
+func Run(transport client.Transport, profile Profile, options Options, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("Se espera wallet o merchant y una funcion")
+	}
+	contract := args[0]
+	if len(args) == 1 {
+		return PrintCommands(out, contract)
+	}
+
+	command, err := findCommand(contract, args[1])
+	if err != nil {
+		return err
+	}
+	callArgs := args[2:]
+	err = command.Check(callArgs)
+	if err != nil {
+		return err
+	}
+
+	chaincode, err := profile.Chaincode(contract, options.Merchant)
+	if err != nil {
+		return err
+	}
+
+	var response []byte
+	if command.Kind == kindInvoke {
+		response, err = transport.Invoke(chaincode, command.Name, callArgs...)
+	} else {
+		response, err = transport.Query(chaincode, command.Name, callArgs...)
+	}
+	if err != nil {
+		return err
+	}
+
+	format := options.Output
+	if format == "" {
+		format = outputTable
+	}
+	return writeOutput(out, format, response)
+}
+
+//PrintCommands - Lista los subcomandos de un contrato con su sintaxis
+func PrintCommands(out io.Writer, contract string) error {
+	commands, err := commandsFor(contract)
+	if err != nil {
+		return err
+	}
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for i := range commands {
+		fmt.Fprintf(writer, "  %s\t%s\t%s\n", commands[i].Kind, commands[i].Usage(), commands[i].Help)
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,103 @@
+/*
+* Adrian Pareja
+ */
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ccamaleon5/blockchain/chaincode/merchant"
+	"github.com/ccamaleon5/blockchain/chaincode/wallet"
+	"github.com/ccamaleon5/blockchain/cli"
+	"github.com/ccamaleon5/blockchain/client"
+	"github.com/ccamaleon5/blockchain/simulator"
+)
+
+var profile = cli.Profile{Transport: "simulator", Wallet: "wallet", Merchants: map[string]string{"cine": "cine"}}
+
+//newTransport - Ledger simulado con el wallet y el comercio cineplanet desplegado como cine
+func newTransport(t *testing.T) *client.SimulatorTransport {
+	n := simulator.NewNetwork()
+	n.SetTime(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC))
+	n.Attributes = map[string]string{"role": "admin", "merchant": "cineplanet"}
+	if _, err := n.Deploy("wallet", new(wallet.SimpleChaincode), "init", "1000000"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Invoke("wallet", "registermerchant", "cineplanet", "Cineplanet", "cineplanet"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Deploy("cine", new(merchant.SimpleChaincode), "init", "10000", "Cineplanet", "cineplanet", "1", "wallet"); err != nil {
+		t.Fatal(err)
+	}
+	return &client.SimulatorTransport{Network: n}
+}
+
+//run - Ejecuta la linea de comandos y devuelve la salida
+func run(t *testing.T, transport client.Transport, options cli.Options, args ...string) string {
+	t.Helper()
+	out := bytes.Buffer{}
+	err := cli.Run(transport, profile, options, args, &out)
+	if err != nil {
+		t.Fatalf("%v: %s", args, err)
+	}
+	return out.String()
+}
+
+func runError(t *testing.T, transport client.Transport, fragment string, args ...string) {
+	t.Helper()
+	err := cli.Run(transport, profile, cli.Options{}, args, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), fragment) {
+		t.Fatalf("%v: se esperaba el error %q y se obtuvo %v", args, fragment, err)
+	}
+}
+
+func TestRunSimulated(t *testing.T) {
+	cli.Transports["simulator"] = func(cli.Profile) (client.Transport, error) {
+		return newTransport(t), nil
+	}
+	defer delete(cli.Transports, "simulator")
+	transport, err := profile.NewTransport()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run(t, transport, cli.Options{}, "wallet", "createwallet", "w1", "w1@mail.com", "999", "123", "pw", "0")
+	run(t, transport, cli.Options{Merchant: "cine"}, "merchant", "buy", "w1", "100", "0")
+	run(t, transport, cli.Options{}, "merchant", "buy", "w1", "50", "20")
+
+	balance := struct {
+		Balance string `json:"balance"`
+	}{}
+	output := run(t, transport, cli.Options{Output: "json"}, "wallet", "getbalance", "w1")
+	if err := json.Unmarshal([]byte(output), &balance); err != nil || balance.Balance != "110.000000" {
+		t.Fatalf("saldo incorrecto: %s", output)
+	}
+
+	//La tabla de una lista lleva una columna por campo
+	output = run(t, transport, cli.Options{}, "merchant", "getcanjes", "w1")
+	if lines := strings.Split(strings.TrimSpace(output), "\n"); len(lines) != 4 || !strings.Contains(lines[0], "AMOUNT") || !strings.Contains(lines[0], "TYPE") {
+		t.Fatalf("tabla incorrecta:\n%s", output)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	transport := newTransport(t)
+	events := len(transport.Network.Events)
+
+	//Los argumentos se validan antes de llegar al ledger
+	runError(t, transport, "Uso: createwallet", "wallet", "createwallet", "w1")
+	runError(t, transport, "Valor invalido para monto", "wallet", "createwallet", "w1", "w1@mail.com", "999", "123", "pw", "mucho")
+	runError(t, transport, "Funcion desconocida", "wallet", "noexiste")
+	runError(t, transport, "Contrato invalido", "banco", "getbalance", "w1")
+	if len(transport.Network.Events) != events {
+		t.Fatal("un comando invalido llego al ledger")
+	}
+
+	//Los errores del chaincode llegan tal cual
+	run(t, transport, cli.Options{}, "wallet", "createwallet", "w1", "w1@mail.com", "999", "123", "pw", "0")
+	runError(t, transport, "El cliente no cuenta con coins suficientes", "merchant", "buy", "w1", "100", "50")
+}
@@ -0,0 +1,213 @@
+/*
+* Adrian Pareja
+ */
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//Tipos de los parametros de un comando
+const (
+	typeString = "string"
+	typeNumber = "number"
+	typeInt    = "int"
+	typeBool   = "bool"
+)
+
+//Contratos y tipos de llamada
+const (
+	contractWallet   = "wallet"
+	contractMerchant = "merchant"
+	kindInvoke       = "invoke"
+	kindQuery        = "query"
+)
+
+//Param - Structure for a command parameter. Los opcionales van al final y el variadic es el ultimo
+type Param struct {
+	Name     string
+	Type     string
+	Optional bool
+	Variadic bool
+}
+
+//Command - Structure for a chaincode function exposed as a CLI subcommand
+type Command struct {
+	Name   string
+	Kind   string
+	Params []Param
+	Help   string
+}
+
+func req(name string, kind string) Param {
+	return Param{Name: name, Type: kind}
+}
+
+func opt(name string, kind string) Param {
+	return Param{Name: name, Type: kind, Optional: true}
+}
+
+func many(name string, kind string) Param {
+	return Param{Name: name, Type: kind, Variadic: true}
+}
+
+var walletCommands = []Command{
+	{Name: "createwallet", Kind: kindInvoke, Help: "Crea un wallet", Params: []Param{req("id", typeString), req("email", typeString), req("telefono", typeString), req("documento", typeString), req("password", typeString), req("monto", typeNumber)}},
+	{Name: "transfer", Kind: kindInvoke, Help: "Transfiere coins al receptor desde el emisor", Params: []Param{req("receptor", typeString), req("emisor", typeString), req("monto", typeNumber)}},
+	{Name: "putbalance", Kind: kindInvoke, Help: "Acredita coins a un wallet por un comercio", Params: []Param{req("wallet", typeString), req("comercio", typeString), req("monto", typeNumber)}},
+	{Name: "debitbalance", Kind: kindInvoke, Help: "Debita coins de un wallet por un comercio", Params: []Param{req("wallet", typeString), req("comercio", typeString), req("monto", typeNumber)}},
+	{Name: "puttotalcoin", Kind: kindInvoke, Help: "Aumenta la bolsa central de coins", Params: []Param{req("monto", typeNumber)}},
+	{Name: "debittotalcoin", Kind: kindInvoke, Help: "Disminuye la bolsa central de coins", Params: []Param{req("monto", typeNumber)}},
+	{Name: "reset", Kind: kindInvoke, Help: "Reinicia los saldos"},
+	{Name: "registermerchant", Kind: kindInvoke, Help: "Registra un comercio", Params: []Param{req("id", typeString), req("nombre", typeString), req("caller", typeString)}},
+	{Name: "updatemerchant", Kind: kindInvoke, Help: "Actualiza un comercio", Params: []Param{req("id", typeString), req("nombre", typeString), req("caller", typeString)}},
+	{Name: "setmerchantstatus", Kind: kindInvoke, Help: "Activa o suspende un comercio (active|suspended)", Params: []Param{req("id", typeString), req("estado", typeString)}},
+	{Name: "setcoinprice", Kind: kindInvoke, Help: "Fija el precio del coin para la liquidacion", Params: []Param{req("precio", typeNumber)}},
+	{Name: "closesettlement", Kind: kindInvoke, Help: "Cierra la liquidacion de un periodo en milisegundos", Params: []Param{req("desde", typeInt), req("hasta", typeInt)}},
+	{Name: "purchase", Kind: kindInvoke, Help: "Canje y acumulacion de una compra", Params: []Param{req("wallet", typeString), req("comercio", typeString), req("canje", typeNumber), req("acumulacion", typeNumber), opt("tienda", typeString), opt("terminal", typeString), opt("cajero", typeString), opt("moneda", typeString), opt("canjeMinor", typeInt), opt("pagadoMinor", typeInt), opt("redondeo", typeString)}},
+	{Name: "issuevouchers", Kind: kindInvoke, Help: "Emite un lote de cupones con los hashes sha256 de sus codigos", Params: []Param{req("lote", typeString), req("valor", typeNumber), req("vencimiento", typeInt), req("topeWallet", typeInt), req("origen", typeString), many("hash", typeString)}},
+	{Name: "redeemvoucher", Kind: kindInvoke, Help: "Canjea un cupon en un wallet", Params: []Param{req("wallet", typeString), req("codigo", typeString)}},
+	{Name: "closevoucherbatch", Kind: kindInvoke, Help: "Devuelve a la bolsa central los coins retenidos por un lote vencido de comercio", Params: []Param{req("lote", typeString)}},
+	{Name: "setmerchantkey", Kind: kindInvoke, Help: "Registra la llave publica ECDSA en PEM de un comercio", Params: []Param{req("comercio", typeString), req("llave", typeString)}},
+	{Name: "setearncap", Kind: kindInvoke, Help: "Tope de coins que un comercio puede entregar con vouchers de acumulacion", Params: []Param{req("comercio", typeString), req("tope", typeNumber)}},
+	{Name: "redeemearnvoucher", Kind: kindInvoke, Help: "Acredita en un wallet un voucher firmado por el comercio", Params: []Param{req("wallet", typeString), req("voucher", typeString)}},
+	{Name: "payintent", Kind: kindInvoke, Help: "Paga desde un wallet la intencion de pago de un QR", Params: []Param{req("wallet", typeString), req("intencion", typeString)}},
+	{Name: "getbalance", Kind: kindQuery, Help: "Saldo de un wallet", Params: []Param{req("wallet", typeString)}},
+	{Name: "gettotalcoin", Kind: kindQuery, Help: "Saldo de la bolsa central"},
+	{Name: "getmovimientos", Kind: kindQuery, Help: "Movimientos, de todos o de un wallet", Params: []Param{req("cuenta", typeString), opt("wallet", typeString)}},
+	{Name: "getdatos", Kind: kindQuery, Help: "Datos de un wallet", Params: []Param{req("wallet", typeString)}},
+	{Name: "getwallets", Kind: kindQuery, Help: "Lista los wallets", Params: []Param{req("cuenta", typeString)}},
+	{Name: "gettier", Kind: kindQuery, Help: "Nivel de un wallet", Params: []Param{req("wallet", typeString)}},
+	{Name: "gettierhistory", Kind: kindQuery, Help: "Cambios de nivel de un wallet", Params: []Param{req("wallet", typeString)}},
+	{Name: "getmerchant", Kind: kindQuery, Help: "Datos de un comercio", Params: []Param{req("id", typeString)}},
+	{Name: "getmerchants", Kind: kindQuery, Help: "Lista los comercios"},
+	{Name: "getsettlement", Kind: kindQuery, Help: "Liquidacion de un periodo", Params: []Param{req("desde", typeInt), req("hasta", typeInt), opt("precio", typeNumber)}},
+	{Name: "getsettlements", Kind: kindQuery, Help: "Liquidaciones cerradas"},
+	{Name: "getvoucherbatch", Kind: kindQuery, Help: "Estado de un lote de cupones", Params: []Param{req("lote", typeString)}},
+	{Name: "getvoucherbatches", Kind: kindQuery, Help: "Lista los lotes de cupones"},
+	{Name: "getearncap", Kind: kindQuery, Help: "Tope de vouchers de acumulacion de un comercio y lo ya acreditado", Params: []Param{req("comercio", typeString)}},
+	{Name: "getmerchantkey", Kind: kindQuery, Help: "Llave publica registrada de un comercio", Params: []Param{req("comercio", typeString)}},
+	{Name: "getpayintent", Kind: kindQuery, Help: "Estado de una intencion de pago", Params: []Param{req("intencion", typeString)}},
+}
+
+var merchantCommands = []Command{
+	{Name: "createwallet", Kind: kindInvoke, Help: "Crea un wallet desde el comercio", Params: []Param{req("id", typeString), req("email", typeString), req("telefono", typeString), req("documento", typeString), req("monto", typeNumber)}},
+	{Name: "buy", Kind: kindInvoke, Help: "Compra: monto o canasta, coins a canjear, atribucion y recibo opcionales", Params: []Param{req("wallet", typeString), req("compra", typeString), req("coins", typeNumber), opt("tienda", typeString), opt("terminal", typeString), opt("cajero", typeString), opt("recibo", typeString)}},
+	{Name: "getcoins", Kind: kindInvoke, Help: "Compra coins a la bolsa central", Params: []Param{req("monto", typeNumber)}},
+	{Name: "returncoins", Kind: kindInvoke, Help: "Devuelve coins a la bolsa central", Params: []Param{req("monto", typeNumber)}},
+	{Name: "setrate", Kind: kindInvoke, Help: "Programa una tasa de acumulacion", Params: []Param{req("tasa", typeNumber), opt("desde", typeInt), opt("moneda", typeString)}},
+	{Name: "setwalletcontract", Kind: kindInvoke, Help: "Cambia el chaincode del wallet", Params: []Param{req("chaincode", typeString)}},
+	{Name: "setcategoryrule", Kind: kindInvoke, Help: "Regla de acumulacion por categoria", Params: []Param{req("categoria", typeString), req("multiplicador", typeNumber), req("canjeable", typeBool)}},
+	{Name: "setreplenish", Kind: kindInvoke, Help: "Reposicion automatica: minimo, objetivo y tope diario", Params: []Param{req("minimo", typeNumber), req("objetivo", typeNumber), req("topeDiario", typeNumber)}},
+	{Name: "setreward", Kind: kindInvoke, Help: "Crea o actualiza un premio del catalogo", Params: []Param{req("id", typeString), req("nombre", typeString), req("precio", typeNumber), req("stock", typeInt), req("desde", typeInt), req("hasta", typeInt)}},
+	{Name: "redeemreward", Kind: kindInvoke, Help: "Canjea un premio con el hash sha256 del codigo en mayusculas", Params: []Param{req("wallet", typeString), req("premio", typeString), req("hash", typeString), opt("tienda", typeString), opt("terminal", typeString), opt("cajero", typeString)}},
+	{Name: "useredemption", Kind: kindInvoke, Help: "Marca un codigo de premio como usado", Params: []Param{req("codigo", typeString)}},
+	{Name: "issuevouchers", Kind: kindInvoke, Help: "Emite un lote de cupones pagado con los coins del comercio", Params: []Param{req("lote", typeString), req("valor", typeNumber), req("vencimiento", typeInt), req("topeWallet", typeInt), many("hash", typeString)}},
+	{Name: "reclaimvouchers", Kind: kindInvoke, Help: "Recupera los coins retenidos por un lote vencido", Params: []Param{req("lote", typeString)}},
+	{Name: "setredemptionrules", Kind: kindInvoke, Help: "Reglas de canje: porcentaje maximo, minimo, multiplo y redondeo", Params: []Param{req("maxPorcentaje", typeNumber), req("minimo", typeNumber), req("multiplo", typeNumber), req("redondeo", typeString)}},
+	{Name: "getbalance", Kind: kindQuery, Help: "Saldo de un wallet", Params: []Param{req("wallet", typeString)}},
+	{Name: "gettotalcoin", Kind: kindQuery, Help: "Saldo de coins del comercio"},
+	{Name: "getmovimientos", Kind: kindQuery, Help: "Canjes del comercio, filtrados por atribucion", Params: []Param{req("comercio", typeString), opt("tienda", typeString), opt("terminal", typeString), opt("cajero", typeString)}},
+	{Name: "getrates", Kind: kindQuery, Help: "Historial de tasas", Params: []Param{opt("moneda", typeString)}},
+	{Name: "checkwallet", Kind: kindQuery, Help: "Verifica el chaincode del wallet"},
+	{Name: "getcategoryrules", Kind: kindQuery, Help: "Reglas por categoria"},
+	{Name: "gettotalsby", Kind: kindQuery, Help: "Totales por store|terminal|cashier", Params: []Param{req("agrupacion", typeString), opt("desde", typeInt), opt("hasta", typeInt)}},
+	{Name: "getreceipt", Kind: kindQuery, Help: "Resultado de un recibo", Params: []Param{req("recibo", typeString)}},
+	{Name: "getcanjes", Kind: kindQuery, Help: "Canjes de un wallet (vacio para todos)", Params: []Param{req("wallet", typeString), opt("desde", typeInt), opt("hasta", typeInt)}},
+	{Name: "getperiodtotals", Kind: kindQuery, Help: "Totales por day|week|month", Params: []Param{req("periodo", typeString), req("desde", typeInt), req("hasta", typeInt)}},
+	{Name: "gettopcustomers", Kind: kindQuery, Help: "Clientes con mas compras", Params: []Param{req("desde", typeInt), req("hasta", typeInt), opt("limite", typeInt)}},
+	{Name: "getrewards", Kind: kindQuery, Help: "Catalogo de premios"},
+	{Name: "getredemption", Kind: kindQuery, Help: "Estado de un codigo de premio", Params: []Param{req("codigo", typeString)}},
+	{Name: "getredemptionrules", Kind: kindQuery, Help: "Reglas de canje"},
+}
+
+//commandsFor - Comandos de un contrato
+func commandsFor(contract string) ([]Command, error) {
+	switch contract {
+	case contractWallet:
+		return walletCommands, nil
+	case contractMerchant:
+		return merchantCommands, nil
+	}
+	return nil, errors.New("Contrato invalido: " + contract + ". Se espera wallet o merchant")
+}
+
+//findCommand - Busca un comando por nombre
+func findCommand(contract string, name string) (*Command, error) {
+	commands, err := commandsFor(contract)
+	if err != nil {
+		return nil, err
+	}
+	for i := range commands {
+		if commands[i].Name == name {
+			return &commands[i], nil
+		}
+	}
+	return nil, fmt.Errorf("Funcion desconocida para %s: %s", contract, name)
+}
+
+//Usage - Sintaxis del comando, con los opcionales entre corchetes
+func (c *Command) Usage() string {
+	parts := []string{c.Name}
+	for _, param := range c.Params {
+		switch {
+		case param.Variadic:
+			parts = append(parts, "<"+param.Name+">...")
+		case param.Optional:
+			parts = append(parts, "["+param.Name+"]")
+		default:
+			parts = append(parts, "<"+param.Name+">")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+//Check - Valida la cantidad y el tipo de los argumentos
+func (c *Command) Check(args []string) error {
+	required := 0
+	variadic := false
+	for _, param := range c.Params {
+		if param.Variadic {
+			variadic = true
+		} else if !param.Optional {
+			required = required + 1
+		}
+	}
+	if len(args) < required || (!variadic && len(args) > len(c.Params)) {
+		return fmt.Errorf("Numero incorrecto de argumentos. Uso: %s", c.Usage())
+	}
+
+	for i, arg := range args {
+		param := c.Params[len(c.Params)-1]
+		if i < len(c.Params) {
+			param = c.Params[i]
+		}
+		if err := checkValue(param, arg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//checkValue - Valida el tipo de un argumento. Un opcional vacio se envia tal cual
+func checkValue(param Param, value string) error {
+	if param.Optional && value == "" {
+		return nil
+	}
+	var err error
+	switch param.Type {
+	case typeNumber:
+		_, err = strconv.ParseFloat(value, 64)
+	case typeInt:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case typeBool:
+		_, err = strconv.ParseBool(value)
+	}
+	if err != nil {
+		return fmt.Errorf("Valor invalido para %s (%s): %s", param.Name, param.Type, value)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+* Adrian Pareja
+ */
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+//Formatos de salida
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+//writeOutput - Escribe la respuesta en el formato pedido. Una respuesta que no es JSON,
+//como el id de transaccion de un invoke contra un peer, se escribe tal cual
+func writeOutput(out io.Writer, format string, response []byte) error {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(response))
+	decoder.UseNumber()
+	if len(bytes.TrimSpace(response)) == 0 || decoder.Decode(&value) != nil {
+		_, err := fmt.Fprintln(out, string(response))
+		return err
+	}
+
+	switch format {
+	case outputJSON:
+		indented, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(indented))
+		return err
+	case outputTable:
+		return writeTable(out, value)
+	}
+	return fmt.Errorf("Formato de salida invalido: %s. Se espera table o json", format)
+}
+
+//writeTable - Lista de objetos como filas con una columna por campo, objeto como campo y valor
+func writeTable(out io.Writer, value interface{}) error {
+	writer := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	switch v := value.(type) {
+	case []interface{}:
+		columns := []string{}
+		seen := map[string]bool{}
+		for _, item := range v {
+			if object, ok := item.(map[string]interface{}); ok {
+				for key := range object {
+					if !seen[key] {
+						seen[key] = true
+						columns = append(columns, key)
+					}
+				}
+			}
+		}
+		sort.Strings(columns)
+
+		if len(columns) == 0 {
+			fmt.Fprintln(writer, "VALOR")
+			for _, item := range v {
+				fmt.Fprintln(writer, cell(item))
+			}
+			break
+		}
+
+		header := []string{}
+		for _, column := range columns {
+			header = append(header, strings.ToUpper(column))
+		}
+		fmt.Fprintln(writer, strings.Join(header, "\t"))
+		for _, item := range v {
+			object, _ := item.(map[string]interface{})
+			cells := []string{}
+			for _, column := range columns {
+				cells = append(cells, cell(object[column]))
+			}
+			fmt.Fprintln(writer, strings.Join(cells, "\t"))
+		}
+	case map[string]interface{}:
+		keys := []string{}
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(writer, "%s\t%s\n", key, cell(v[key]))
+		}
+	default:
+		fmt.Fprintln(writer, cell(v))
+	}
+	return writer.Flush()
+}
+
+//cell - Valor de una celda, los objetos y listas anidados en JSON compacto
+func cell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return fmt.Sprintf("%t", v)
+	}
+	compact, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(compact)
+}
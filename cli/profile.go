@@ -0,0 +1,114 @@
+/*
+* Adrian Pareja
+ */
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/ccamaleon5/blockchain/client"
+)
+
+//Profile - Structure for a peer and the chaincode ids deployed on it
+type Profile struct {
+	//Transport es el nombre registrado en Transports, rest por defecto
+	Transport     string            `json:"transport,omitempty"`
+	Peer          string            `json:"peer"`
+	SecureContext string            `json:"secureContext,omitempty"`
+	Wallet        string            `json:"wallet"`
+	Merchants     map[string]string `json:"merchants"`
+}
+
+//Config - Structure for the profiles file
+type Config struct {
+	Default  string             `json:"default"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+//TransportFactory - Crea el transport de un perfil
+type TransportFactory func(profile Profile) (client.Transport, error)
+
+//Transports - Transports disponibles por nombre. Se pueden registrar otros, por ejemplo uno simulado
+var Transports = map[string]TransportFactory{
+	"rest": func(profile Profile) (client.Transport, error) {
+		if profile.Peer == "" {
+			return nil, errors.New("El perfil no tiene la URL del peer")
+		}
+		return client.NewRESTTransport(profile.Peer, profile.SecureContext), nil
+	},
+}
+
+//LoadConfig - Lee el archivo de perfiles
+func LoadConfig(path string) (*Config, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := Config{}
+	err = json.Unmarshal(bytes, &config)
+	if err != nil {
+		return nil, fmt.Errorf("Archivo de perfiles invalido %s. %s", path, err)
+	}
+	return &config, nil
+}
+
+//Profile - Perfil por nombre, el de por defecto si el nombre es vacio
+func (c *Config) Profile(name string) (Profile, error) {
+	if name == "" {
+		name = c.Default
+	}
+	profile, found := c.Profiles[name]
+	if !found {
+		return Profile{}, errors.New("No existe el perfil " + name)
+	}
+	return profile, nil
+}
+
+//NewTransport - Crea el transport del perfil
+func (p Profile) NewTransport() (client.Transport, error) {
+	name := p.Transport
+	if name == "" {
+		name = "rest"
+	}
+	factory, found := Transports[name]
+	if !found {
+		return nil, errors.New("Transport desconocido: " + name)
+	}
+	return factory(p)
+}
+
+//Chaincode - Id del chaincode del contrato. Para merchant, merchant es el alias del perfil
+//o directamente el id; si es vacio y el perfil tiene un solo comercio se usa ese
+func (p Profile) Chaincode(contract string, merchant string) (string, error) {
+	switch contract {
+	case contractWallet:
+		if p.Wallet == "" {
+			return "", errors.New("El perfil no tiene el chaincode del wallet")
+		}
+		return p.Wallet, nil
+	case contractMerchant:
+		if merchant == "" {
+			if len(p.Merchants) != 1 {
+				names := []string{}
+				for name := range p.Merchants {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				return "", errors.New("Indique el comercio con -merchant: " + strings.Join(names, ", "))
+			}
+			for _, id := range p.Merchants {
+				return id, nil
+			}
+		}
+		if id, found := p.Merchants[merchant]; found {
+			return id, nil
+		}
+		return merchant, nil
+	}
+	return "", errors.New("Contrato invalido: " + contract + ". Se espera wallet o merchant")
+}
@@ -0,0 +1,125 @@
+/*
+* Adrian Pareja
+ */
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//Tx - Transaccion enviada. Contra un peer el invoke solo devuelve el id y el resultado queda vacio
+type Tx struct {
+	TxID string `json:"-"`
+}
+
+//Amount - Monto que los chaincodes devuelven como numero o como texto ("100.000000")
+type Amount float64
+
+//UnmarshalJSON - Acepta numero o texto
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		*a = 0
+		return nil
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("Monto invalido: %s", data)
+	}
+	*a = Amount(value)
+	return nil
+}
+
+//formatAmount - Monto como argumento de un chaincode
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+//codeResponse - Respuesta estandar {"code":0,"response":...}
+type codeResponse struct {
+	Code     *int            `json:"code"`
+	Response json.RawMessage `json:"response"`
+}
+
+//checkCode - Una respuesta objeto con code distinto de 0 es un error del chaincode
+func checkCode(function string, response []byte) error {
+	trimmed := bytes.TrimSpace(response)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil
+	}
+	result := codeResponse{}
+	if json.Unmarshal(trimmed, &result) != nil || result.Code == nil || *result.Code == 0 {
+		return nil
+	}
+	message := strings.Trim(string(result.Response), `"`)
+	if message == "" || message == "null" {
+		message = "El chaincode respondio con code distinto de 0"
+	}
+	return &Error{Kind: KindChaincode, Function: function, Code: *result.Code, Message: message}
+}
+
+//query - Consulta y decodifica la respuesta en value
+func query(transport Transport, chaincode string, function string, value interface{}, args ...string) error {
+	response, err := transport.Query(chaincode, function, args...)
+	if err != nil {
+		return DecodeError(function, err)
+	}
+	if err := checkCode(function, response); err != nil {
+		return err
+	}
+	if value == nil {
+		return nil
+	}
+	err = json.Unmarshal(response, value)
+	if err != nil {
+		return &Error{Kind: KindChaincode, Function: function, Code: 1, Message: fmt.Sprintf("Respuesta invalida: %s", response)}
+	}
+	return nil
+}
+
+//invoke - Ejecuta la transaccion y decodifica la respuesta en value. Si el transport devuelve el id de
+//transaccion en lugar de la respuesta, se guarda en tx y value queda vacio
+func invoke(transport Transport, chaincode string, function string, value interface{}, tx *Tx, args ...string) error {
+	response, err := transport.Invoke(chaincode, function, args...)
+	if err != nil {
+		return DecodeError(function, err)
+	}
+	trimmed := bytes.TrimSpace(response)
+	if len(trimmed) == 0 {
+		return nil
+	}
+	if !json.Valid(trimmed) {
+		if tx != nil {
+			tx.TxID = string(trimmed)
+		}
+		return nil
+	}
+	if err := checkCode(function, trimmed); err != nil {
+		return err
+	}
+	if value == nil {
+		return nil
+	}
+	err = json.Unmarshal(trimmed, value)
+	if err != nil {
+		return &Error{Kind: KindChaincode, Function: function, Code: 1, Message: fmt.Sprintf("Respuesta invalida: %s", response)}
+	}
+	return nil
+}
+
+//responseAmount - Monto de una respuesta {"code":0,"response":"1000"}
+func responseAmount(function string, response codeResponse) (float64, error) {
+	var amount Amount
+	if len(response.Response) == 0 {
+		return 0, nil
+	}
+	err := json.Unmarshal(response.Response, &amount)
+	if err != nil {
+		return 0, &Error{Kind: KindChaincode, Function: function, Code: 1, Message: err.Error()}
+	}
+	return float64(amount), nil
+}
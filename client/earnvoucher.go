@@ -0,0 +1,131 @@
+/*
+* Adrian Pareja
+ */
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const earnVoucherVersion = "LV1"
+
+//EarnVoucher - Structure for an earn voucher signed offline by a merchant. Expiry en milisegundos
+type EarnVoucher struct {
+	Merchant string  `json:"merchant"`
+	Id       string  `json:"id"`
+	Amount   float64 `json:"amount"`
+	Expiry   int64   `json:"expiry"`
+}
+
+//EarnVoucherRedemption - Structure for a redeemed earn voucher
+type EarnVoucherRedemption struct {
+	EarnVoucher
+	Wallet string `json:"wallet"`
+	Time   int64  `json:"time"`
+}
+
+//EarnVoucherResult - Structure for the result of redeemearnvoucher
+type EarnVoucherResult struct {
+	Tx
+	Balance Amount                `json:"balance"`
+	Voucher EarnVoucherRedemption `json:"voucher"`
+}
+
+//EarnCap - Structure for the coins a merchant can hand out with earn vouchers and how many it already did
+type EarnCap struct {
+	Tx
+	Merchant string `json:"merchant"`
+	Cap      Amount `json:"cap"`
+	Issued   Amount `json:"issued"`
+}
+
+//MerchantKey - Structure for the public key registered for a merchant, en PEM
+type MerchantKey struct {
+	Merchant string `json:"merchant"`
+	Key      string `json:"key"`
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+//signedText - Texto que firma el comercio: LV1:<comercio>:<id>:<coins>:<vencimiento>:
+func (v EarnVoucher) signedText() string {
+	return strings.Join([]string{
+		earnVoucherVersion,
+		url.QueryEscape(v.Merchant),
+		url.QueryEscape(v.Id),
+		formatAmount(v.Amount),
+		strconv.FormatInt(v.Expiry, 10),
+	}, ":") + ":"
+}
+
+//Sign - Texto del voucher firmado con la llave privada del comercio. No necesita conexion; el id debe ser unico
+//por comercio porque el wallet acepta cada id una sola vez
+func (v EarnVoucher) Sign(key *ecdsa.PrivateKey) (string, error) {
+	text := v.signedText()
+	digest := sha256.Sum256([]byte(text))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	signature, err := asn1.Marshal(ecdsaSignature{R: r, S: s})
+	if err != nil {
+		return "", err
+	}
+	return text + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+//DecodeEarnVoucher - Lee los campos de un voucher sin validar la firma ni el vencimiento
+func DecodeEarnVoucher(text string) (*EarnVoucher, error) {
+	text = strings.TrimSpace(text)
+	i := strings.LastIndex(text, ":")
+	if i < 0 {
+		return nil, errors.New("Voucher de acumulacion invalido")
+	}
+	parts := strings.Split(text[:i], ":")
+	if parts[0] != earnVoucherVersion {
+		return nil, errors.New("Version de voucher no soportada: " + parts[0])
+	}
+	if len(parts) != 5 {
+		return nil, errors.New("Voucher de acumulacion invalido")
+	}
+	merchant, err := url.QueryUnescape(parts[1])
+	if err != nil || merchant == "" {
+		return nil, errors.New("Comercio del voucher invalido: " + parts[1])
+	}
+	id, err := url.QueryUnescape(parts[2])
+	if err != nil || id == "" {
+		return nil, errors.New("Id del voucher invalido: " + parts[2])
+	}
+	amount, err := strconv.ParseFloat(parts[3], 64)
+	if err != nil || !(amount > 0) || math.IsInf(amount, 0) {
+		return nil, errors.New("Monto invalido: " + parts[3])
+	}
+	expiry, err := strconv.ParseInt(parts[4], 10, 64)
+	if err != nil {
+		return nil, errors.New("Vencimiento invalido: " + parts[4])
+	}
+	return &EarnVoucher{Merchant: merchant, Id: id, Amount: amount, Expiry: expiry}, nil
+}
+
+//MarshalMerchantKey - Llave publica en PEM para setmerchantkey
+func MarshalMerchantKey(key *ecdsa.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
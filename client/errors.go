@@ -0,0 +1,114 @@
+/*
+* Adrian Pareja
+ */
+package client
+
+import (
+	"fmt"
+	"strings"
+)
+
+//ErrorKind - Clase de error de una llamada a un chaincode
+type ErrorKind string
+
+//Clases de error que se reconocen en los mensajes de los chaincodes
+const (
+	KindInvalidArgument   ErrorKind = "invalid_argument"
+	KindForbidden         ErrorKind = "forbidden"
+	KindNotFound          ErrorKind = "not_found"
+	KindConflict          ErrorKind = "conflict"
+	KindInsufficientFunds ErrorKind = "insufficient_funds"
+	//KindChaincode es cualquier otra falla del chaincode, del peer o una respuesta con code distinto de 0
+	KindChaincode ErrorKind = "chaincode"
+)
+
+//Error - Structure for a failed chaincode call. Message es el mensaje del chaincode sin los prefijos
+//que agregan el peer y las llamadas entre chaincodes
+type Error struct {
+	Kind     ErrorKind
+	Function string
+	Code     int
+	Message  string
+}
+
+func (e *Error) Error() string {
+	if e.Function == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Function, e.Message)
+}
+
+//errorRule - Fragmentos de los mensajes del chaincode que corresponden a una clase de error
+type errorRule struct {
+	kind      ErrorKind
+	fragments []string
+}
+
+//errorRules - Se evaluan en orden sobre el mensaje en minusculas
+var errorRules = []errorRule{
+	{KindForbidden, []string{"solo el administrador", "no autorizado", "desde el chaincode del comercio"}},
+	//Las funciones antiguas del wallet reportan asi un wallet que no existe
+	{KindNotFound, []string{"desconocid", "no existe", "no tiene una llave", "error retrieving "}},
+	{KindInsufficientFunds, []string{"suficientes", "no cuentas con"}},
+	{KindConflict, []string{"ya existe", "already exists", "ya fue", "suspendido", "vencido", "vencida", "alcanzo", "no esta activo",
+		"no esta vigente", "no tiene stock", "aun ", "superpone", "no retienen", "no se ha configurado"}},
+	{KindInvalidArgument, []string{"invalid", "incorrecto", "se espera", "debe ", "no puede", "vacia", "obligatorio", "no soportada", "no hay una tasa", "exced", "solo pueden pagar", "canje minimo", "multiplo"}},
+}
+
+//errorPrefixes - Prefijos con los que el peer y InvokeChaincode envuelven el mensaje original
+var errorPrefixes = []string{
+	"Failed to invoke chaincode. Got error: ",
+	"Failed to query chaincode. Got error: ",
+	"Error when querying chaincode: ",
+	"Error when invoking chaincode: ",
+	"Error:",
+}
+
+//DecodeError - Clasifica el error de una llamada segun el mensaje del chaincode
+func DecodeError(function string, err error) *Error {
+	if err == nil {
+		return nil
+	}
+	if decoded, ok := err.(*Error); ok {
+		return decoded
+	}
+
+	message := strings.TrimSpace(err.Error())
+	for trimmed := true; trimmed; {
+		trimmed = false
+		for _, prefix := range errorPrefixes {
+			if strings.HasPrefix(message, prefix) {
+				message = strings.TrimSpace(strings.TrimPrefix(message, prefix))
+				trimmed = true
+			}
+		}
+	}
+
+	lower := strings.ToLower(message)
+	for _, rule := range errorRules {
+		for _, fragment := range rule.fragments {
+			if strings.Contains(lower, fragment) {
+				return &Error{Kind: rule.kind, Function: function, Code: 1, Message: message}
+			}
+		}
+	}
+	return &Error{Kind: KindChaincode, Function: function, Code: 1, Message: message}
+}
+
+//KindOf - Clase de un error devuelto por el cliente, KindChaincode si no es un *Error
+func KindOf(err error) ErrorKind {
+	if decoded, ok := err.(*Error); ok {
+		return decoded.Kind
+	}
+	return KindChaincode
+}
+
+//IsNotFound - El wallet, comercio, lote, premio o recibo no existe
+func IsNotFound(err error) bool {
+	return err != nil && KindOf(err) == KindNotFound
+}
+
+//IsInsufficientFunds - El wallet, el comercio o la bolsa central no tienen coins suficientes
+func IsInsufficientFunds(err error) bool {
+	return err != nil && KindOf(err) == KindInsufficientFunds
+}
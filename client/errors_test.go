@@ -0,0 +1,152 @@
+/*
+* Adrian Pareja
+ */
+package client_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ccamaleon5/blockchain/chaincode/merchant"
+	"github.com/ccamaleon5/blockchain/chaincode/wallet"
+	"github.com/ccamaleon5/blockchain/client"
+	"github.com/ccamaleon5/blockchain/simulator"
+)
+
+var admin = map[string]string{"role": "admin", "merchant": "cineplanet"}
+
+//newNetwork - Red con el wallet, el comercio cineplanet desplegado como cine y el wallet w1 con 50 coins
+func newNetwork(t *testing.T) *simulator.Network {
+	n := simulator.NewNetwork()
+	n.SetTime(time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC))
+	n.Attributes = admin
+	if _, err := n.Deploy("wallet", new(wallet.SimpleChaincode), "init", "1000000"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Invoke("wallet", "registermerchant", "cineplanet", "Cineplanet", "cineplanet"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Deploy("cine", new(merchant.SimpleChaincode), "init", "10000", "Cineplanet", "cineplanet", "1", "wallet"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Invoke("wallet", "createwallet", "w1", "w1@mail.com", "999", "123", "pw", "0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Invoke("wallet", "putbalance", "w1", "cineplanet", "50"); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func hash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+//Cada caso provoca en los chaincodes el error real y verifica la clase que le asigna DecodeError.
+//Un mensaje nuevo o cambiado en un chaincode que deje de clasificarse aparece aqui
+func TestErrorKinds(t *testing.T) {
+	expiry := strconv.FormatInt(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC).UnixNano()/int64(time.Millisecond), 10)
+	centralBatch := []string{"wallet", "issuevouchers", "l1", "10", expiry, "1", "central", hash("C1")}
+	merchantBatch := []string{"cine", "issuevouchers", "l1", "10", expiry, "1", hash("C1")}
+	cases := []struct {
+		//setup son invocaciones del administrador antes del caso: chaincode, funcion y argumentos
+		setup      [][]string
+		kind       client.ErrorKind
+		attributes map[string]string
+		chaincode  string
+		function   string
+		args       []string
+	}{
+		{nil, client.KindForbidden, map[string]string{"role": "cashier"}, "cine", "returncoins", []string{"100"}},
+		{nil, client.KindForbidden, map[string]string{"role": "cashier"}, "wallet", "setearncap", []string{"cineplanet", "10"}},
+		{nil, client.KindForbidden, map[string]string{"merchant": "otro"}, "cine", "useredemption", []string{"ABC"}},
+		{nil, client.KindForbidden, map[string]string{"role": "cashier"}, "wallet", "issuevouchers", []string{"l1", "10", expiry, "1", "cineplanet", hash("C1")}},
+		{nil, client.KindNotFound, admin, "wallet", "transfer", []string{"w9", "w1", "10"}},
+		{nil, client.KindNotFound, admin, "wallet", "setmerchantkey", []string{"otro", "llave"}},
+		{nil, client.KindNotFound, admin, "wallet", "closevoucherbatch", []string{"l9"}},
+		{nil, client.KindNotFound, admin, "cine", "useredemption", []string{"ABC"}},
+		{nil, client.KindInsufficientFunds, admin, "wallet", "transfer", []string{"w1", "w1", "60"}},
+		{nil, client.KindInsufficientFunds, admin, "cine", "buy", []string{"w1", "100", "60"}},
+		{nil, client.KindInsufficientFunds, admin, "cine", "issuevouchers", []string{"l1", "20000", expiry, "1", hash("C1")}},
+		{nil, client.KindConflict, admin, "wallet", "createwallet", []string{"w1", "w1@mail.com", "999", "123", "pw", "0"}},
+		{[][]string{{"wallet", "setmerchantstatus", "cineplanet", "suspended"}}, client.KindConflict, admin, "cine", "buy", []string{"w1", "100", "0"}},
+		{[][]string{merchantBatch}, client.KindForbidden, map[string]string{"role": "cashier"}, "wallet", "closevoucherbatch", []string{"l1"}},
+		{nil, client.KindNotFound, admin, "wallet", "putbalance", []string{"w9", "cineplanet", "10"}},
+		{[][]string{centralBatch}, client.KindConflict, admin, "wallet", "closevoucherbatch", []string{"l1"}},
+		{[][]string{merchantBatch}, client.KindConflict, admin, "wallet", "closevoucherbatch", []string{"l1"}},
+		{[][]string{centralBatch}, client.KindConflict, admin, "wallet", "issuevouchers", centralBatch[2:]},
+		{nil, client.KindInvalidArgument, admin, "wallet", "debitbalance", []string{"w1", "cineplanet", "NaN"}},
+		{nil, client.KindInvalidArgument, admin, "wallet", "payintent", []string{"w1", "LP1:M:cineplanet:10:p1:0:00000000"}},
+		{nil, client.KindInvalidArgument, admin, "wallet", "purchase", []string{"w1", "cineplanet", "0", "1", "", "", "", "PEN", "0", "100", "hacia-arriba"}},
+		{nil, client.KindInvalidArgument, admin, "cine", "setredemptionrules", []string{"0", "0", "0"}},
+		{nil, client.KindInvalidArgument, admin, "cine", "buy", []string{"w1", `[{"quantity":1,"price":10.5}]`, "0"}},
+	}
+	for _, c := range cases {
+		n := newNetwork(t)
+		for _, step := range c.setup {
+			if _, err := n.Invoke(step[0], step[1], step[2:]...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		n.Attributes = c.attributes
+		_, err := n.Invoke(c.chaincode, c.function, c.args...)
+		if err == nil {
+			t.Fatalf("%s %v: se esperaba un error", c.function, c.args)
+		}
+		decoded := client.DecodeError(c.function, err)
+		if decoded.Kind != c.kind {
+			t.Fatalf("%s %v: %q se clasifico como %s, se esperaba %s", c.function, c.args, decoded.Message, decoded.Kind, c.kind)
+		}
+	}
+}
+
+//Mensajes de los chaincodes que la red simulada no provoca facilmente, copiados tal cual
+func TestErrorKindsMessages(t *testing.T) {
+	cases := []struct {
+		message string
+		kind    client.ErrorKind
+	}{
+		{"Solo el administrador puede fijar el tope de vouchers de un comercio", client.KindForbidden},
+		{"El comercio no tiene una llave registrada: cineplanet", client.KindNotFound},
+		{"Error retrieving Balancew9", client.KindNotFound},
+		{"No hay coins suficientes en el balance global", client.KindInsufficientFunds},
+		{"El comercio alcanzo su tope de vouchers de acumulacion, disponible: 5.000000", client.KindConflict},
+		{"El wallet alcanzo el tope de cupones del lote l1", client.KindConflict},
+		{"El codigo de premio ABC ya fue usado", client.KindConflict},
+		{"El recibo R-1 ya fue usado en otra compra", client.KindConflict},
+		{"El premio p1 no esta vigente", client.KindConflict},
+		{"El premio p1 no tiene stock", client.KindConflict},
+		{"Fallo insertar Row Wallet with given key already exists", client.KindConflict},
+		{"No se puede cerrar un periodo que aun no termina", client.KindConflict},
+		{"No se ha configurado el precio del coin", client.KindConflict},
+		{"Ya existe una tasa vigente desde ese momento", client.KindConflict},
+		{"El periodo se superpone con la liquidacion cerrada 1-2", client.KindConflict},
+		{"Firma del voucher invalida", client.KindInvalidArgument},
+		{"Version de intencion de pago no soportada: LP2", client.KindInvalidArgument},
+		{"No hay una tasa de cambio vigente para BOB", client.KindInvalidArgument},
+		{"Número de Argumentos incorrecto. Se esperaba 1 argumento", client.KindInvalidArgument},
+		{"Insert Row Movimientos operation failed. timeout", client.KindChaincode},
+		{"Fallo enviar el evento de saldo", client.KindChaincode},
+	}
+	for _, c := range cases {
+		decoded := client.DecodeError("f", errors.New("Error: "+c.message))
+		if decoded.Kind != c.kind || decoded.Message != c.message {
+			t.Fatalf("%q se decodifico como %+v, se esperaba %s", c.message, decoded, c.kind)
+		}
+	}
+}
+
+//Los prefijos del peer y de InvokeChaincode no forman parte del mensaje
+func TestDecodeErrorPrefixes(t *testing.T) {
+	n := newNetwork(t)
+	_, err := n.Invoke("cine", "buy", "w9", "100", "0")
+	decoded := client.DecodeError("buy", err)
+	if decoded.Kind != client.KindNotFound || decoded.Message != "Error retrieving w9" {
+		t.Fatalf("error de InvokeChaincode mal decodificado: %+v (%s)", decoded, err)
+	}
+}
@@ -0,0 +1,69 @@
+/*
+* Adrian Pareja
+ */
+package client
+
+import (
+	"errors"
+)
+
+//FakeHandler - Respuesta programada de una funcion del FakeTransport
+type FakeHandler func(args []string) ([]byte, error)
+
+//FakeCall - Structure for a call received by the FakeTransport
+type FakeCall struct {
+	Kind      string
+	Chaincode string
+	Function  string
+	Args      []string
+}
+
+//FakeTransport - Transport con respuestas programadas por chaincode y funcion, para probar sin peer ni chaincodes.
+//Registra las llamadas recibidas en Calls
+type FakeTransport struct {
+	Handlers map[string]FakeHandler
+	Calls    []FakeCall
+}
+
+//NewFakeTransport - Crea un FakeTransport sin respuestas programadas
+func NewFakeTransport() *FakeTransport {
+	return &FakeTransport{Handlers: map[string]FakeHandler{}}
+}
+
+//Handle - Programa la respuesta de una funcion
+func (f *FakeTransport) Handle(chaincode string, function string, handler FakeHandler) {
+	f.Handlers[chaincode+"/"+function] = handler
+}
+
+//Respond - Programa una respuesta fija
+func (f *FakeTransport) Respond(chaincode string, function string, response string) {
+	f.Handle(chaincode, function, func(args []string) ([]byte, error) {
+		return []byte(response), nil
+	})
+}
+
+//Fail - Programa un error fijo, como el que devuelve el chaincode
+func (f *FakeTransport) Fail(chaincode string, function string, message string) {
+	f.Handle(chaincode, function, func(args []string) ([]byte, error) {
+		return nil, errors.New(message)
+	})
+}
+
+//Invoke - Registra la llamada y devuelve la respuesta programada
+func (f *FakeTransport) Invoke(chaincode string, function string, args ...string) ([]byte, error) {
+	return f.call("invoke", chaincode, function, args)
+}
+
+//Query - Registra la llamada y devuelve la respuesta programada
+func (f *FakeTransport) Query(chaincode string, function string, args ...string) ([]byte, error) {
+	return f.call("query", chaincode, function, args)
+}
+
+func (f *FakeTransport) call(kind string, chaincode string, function string, args []string) ([]byte, error) {
+	f.Calls = append(f.Calls, FakeCall{Kind: kind, Chaincode: chaincode, Function: function, Args: args})
+	handler, found := f.Handlers[chaincode+"/"+function]
+	if !found {
+		return nil, errors.New("Funcion invocada desconocida: " + function)
+	}
+	return handler(args)
+}
@@ -0,0 +1,537 @@
+/*
+* Adrian Pareja
+ */
+package client
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//MerchantClient - Cliente tipado del chaincode de un comercio
+type MerchantClient struct {
+	Transport Transport
+	Chaincode string
+}
+
+//NewMerchantClient - Crea el cliente del chaincode de un comercio con su id
+func NewMerchantClient(transport Transport, chaincode string) *MerchantClient {
+	return &MerchantClient{Transport: transport, Chaincode: chaincode}
+}
+
+//MerchantWalletRequest - Structure for createwallet desde el comercio
+type MerchantWalletRequest struct {
+	Id       string
+	Email    string
+	Phone    string
+	Document string
+	Amount   float64
+}
+
+//BasketLine - Structure for a basket line. Price va en unidades menores, en Lines y en Basket
+type BasketLine struct {
+	Sku      string  `json:"sku"`
+	Category string  `json:"category"`
+	Quantity float64 `json:"quantity"`
+	Price    int64   `json:"price"`
+}
+
+//Basket - Structure for a basket in a currency with prices in minor units
+type Basket struct {
+	Currency string       `json:"currency"`
+	Lines    []BasketLine `json:"lines"`
+}
+
+//BuyRequest - Structure for buy. La compra es Basket, o Lines en la moneda del comercio, o Currency con Minor,
+//o Amount en la moneda del comercio, en ese orden. Receipt hace idempotente el reintento del POS
+type BuyRequest struct {
+	Wallet      string
+	Amount      float64
+	Currency    string
+	Minor       int64
+	Lines       []BasketLine
+	Basket      *Basket
+	Coins       float64
+	Attribution Attribution
+	Receipt     string
+}
+
+//LineResult - Structure for the result of a basket line
+type LineResult struct {
+	Sku        string  `json:"sku"`
+	Category   string  `json:"category"`
+	Quantity   float64 `json:"quantity"`
+	Price      float64 `json:"price"`
+	Subtotal   float64 `json:"subtotal"`
+	Coins      float64 `json:"coins"`
+	Multiplier float64 `json:"multiplier"`
+	Redeemable bool    `json:"redeemable"`
+	Redeemed   float64 `json:"redeemed"`
+	Earned     float64 `json:"earned"`
+}
+
+//BuyResult - Structure for the result of buy
+type BuyResult struct {
+	Tx
+	Balance  Amount       `json:"balance"`
+	Limit    Amount       `json:"limit"`
+	Tier     string       `json:"tier"`
+	Redeemed Amount       `json:"redeemed"`
+	Earned   Amount       `json:"earned"`
+	Currency string       `json:"currency"`
+	Minor    int64        `json:"minor"`
+	Lines    []LineResult `json:"lines"`
+}
+
+//Receipt - Structure for getreceipt
+type Receipt struct {
+	Receipt string    `json:"receipt"`
+	Time    int64     `json:"time"`
+	Result  BuyResult `json:"result"`
+}
+
+//MerchantBalance - Structure for the coins of the merchant (gettotalcoin). Spent son los coins canjeados
+//por clientes y Sent los acreditados a clientes
+type MerchantBalance struct {
+	Business string  `json:"business"`
+	Balance  float64 `json:"balance"`
+	Spent    float64 `json:"spend"`
+	Sent     float64 `json:"sents"`
+}
+
+//Canje - Structure for a merchant movement (getmovimientos, getcanjes)
+type Canje struct {
+	Time      int64   `json:"time"`
+	WalletId  string  `json:"walletid"`
+	Amount    float64 `json:"amount"`
+	Type      string  `json:"type"`
+	Detail    string  `json:"detail,omitempty"`
+	Store     string  `json:"store,omitempty"`
+	Terminal  string  `json:"terminal,omitempty"`
+	Cashier   string  `json:"cashier,omitempty"`
+	Wallet    string  `json:"wallet,omitempty"`
+	Soles     float64 `json:"soles,omitempty"`
+	Reference string  `json:"reference,omitempty"`
+	Currency  string  `json:"currency,omitempty"`
+	Minor     int64   `json:"minor,omitempty"`
+}
+
+//Rate - Structure for an earn rate
+type Rate struct {
+	Currency string  `json:"currency"`
+	From     int64   `json:"from"`
+	Rate     float64 `json:"rate"`
+	SetTime  int64   `json:"settime"`
+}
+
+//CategoryRule - Structure for an earn rule by category
+type CategoryRule struct {
+	Category   string  `json:"category"`
+	Multiplier float64 `json:"multiplier"`
+	Redeemable bool    `json:"redeemable"`
+}
+
+//AttributionTotal - Structure for gettotalsby
+type AttributionTotal struct {
+	Key      string  `json:"key"`
+	Redeemed float64 `json:"redeemed"`
+	Earned   float64 `json:"earned"`
+	Count    int     `json:"count"`
+}
+
+//PeriodTotal - Structure for getperiodtotals, un total por periodo y moneda
+type PeriodTotal struct {
+	Start         int64   `json:"start"`
+	Currency      string  `json:"currency"`
+	Minor         int64   `json:"minor"`
+	Redeemed      float64 `json:"redeemed"`
+	Earned        float64 `json:"earned"`
+	Redemptions   int     `json:"redemptions"`
+	Earnings      int     `json:"earnings"`
+	AverageRedeem float64 `json:"averageredeem"`
+}
+
+//CustomerTotal - Structure for gettopcustomers, un total por cliente y moneda. Soles esta en unidades de Currency
+type CustomerTotal struct {
+	Wallet      string  `json:"wallet"`
+	Currency    string  `json:"currency"`
+	Soles       float64 `json:"soles"`
+	Minor       int64   `json:"minor"`
+	Redeemed    float64 `json:"redeemed"`
+	Earned      float64 `json:"earned"`
+	Redemptions int     `json:"redemptions"`
+	Earnings    int     `json:"earnings"`
+}
+
+//Reward - Structure for a catalog reward
+type Reward struct {
+	Id        string  `json:"id"`
+	Name      string  `json:"name"`
+	Price     float64 `json:"price"`
+	Stock     int64   `json:"stock"`
+	ValidFrom int64   `json:"validfrom"`
+	ValidTo   int64   `json:"validto"`
+}
+
+//Redemption - Structure for a reward redemption code. Code solo lo conoce quien canjea, el ledger guarda Hash
+type Redemption struct {
+	Tx
+	Code   string  `json:"code,omitempty"`
+	Hash   string  `json:"hash"`
+	Reward string  `json:"reward"`
+	Wallet string  `json:"wallet"`
+	Price  float64 `json:"price"`
+	Time   int64   `json:"time"`
+	Used   int64   `json:"used"`
+}
+
+//RedemptionRules - Structure for the redemption rules of the merchant
+type RedemptionRules struct {
+	MaxPercent float64 `json:"maxpercent"`
+	MinCoins   float64 `json:"mincoins"`
+	Step       float64 `json:"step"`
+	Rounding   string  `json:"rounding"`
+}
+
+//WalletHealth - Structure for checkwallet
+type WalletHealth struct {
+	Code           int32  `json:"code"`
+	WalletContract string `json:"walletcontract"`
+	Healthy        bool   `json:"healthy"`
+	Response       string `json:"response"`
+}
+
+//ReplenishConfig - Structure for setreplenish
+type ReplenishConfig struct {
+	LowWater float64
+	Target   float64
+	DailyCap float64
+}
+
+func (c *MerchantClient) invoke(function string, value interface{}, tx *Tx, args ...string) error {
+	return invoke(c.Transport, c.Chaincode, function, value, tx, args...)
+}
+
+func (c *MerchantClient) query(function string, value interface{}, args ...string) error {
+	return query(c.Transport, c.Chaincode, function, value, args...)
+}
+
+func (c *MerchantClient) invokeTx(function string, args ...string) (Tx, error) {
+	tx := Tx{}
+	err := c.invoke(function, nil, &tx, args...)
+	return tx, err
+}
+
+//period - Desde y hasta como argumentos, ninguno si ambos son 0
+func period(from int64, to int64) []string {
+	if from == 0 && to == 0 {
+		return nil
+	}
+	return []string{strconv.FormatInt(from, 10), strconv.FormatInt(to, 10)}
+}
+
+//purchaseArgument - La compra como la recibe buy
+func (r BuyRequest) purchaseArgument() (string, error) {
+	switch {
+	case r.Basket != nil:
+		bytes, err := json.Marshal(r.Basket)
+		return string(bytes), err
+	case len(r.Lines) > 0:
+		bytes, err := json.Marshal(r.Lines)
+		return string(bytes), err
+	case r.Currency != "":
+		return fmt.Sprintf("%s:%d", r.Currency, r.Minor), nil
+	}
+	return formatAmount(r.Amount), nil
+}
+
+//CreateWallet - Crea un wallet desde el comercio
+func (c *MerchantClient) CreateWallet(request MerchantWalletRequest) (Tx, error) {
+	return c.invokeTx("createwallet", request.Id, request.Email, request.Phone, request.Document, formatAmount(request.Amount))
+}
+
+//Buy - Compra con canje y acumulacion
+func (c *MerchantClient) Buy(request BuyRequest) (*BuyResult, error) {
+	purchase, err := request.purchaseArgument()
+	if err != nil {
+		return nil, &Error{Kind: KindInvalidArgument, Function: "buy", Code: 1, Message: err.Error()}
+	}
+	args := []string{request.Wallet, purchase, formatAmount(request.Coins)}
+	if !request.Attribution.empty() {
+		args = append(args, request.Attribution.Store, request.Attribution.Terminal, request.Attribution.Cashier)
+	}
+	if request.Receipt != "" {
+		args = append(args, request.Receipt)
+	}
+
+	result := BuyResult{}
+	err = c.invoke("buy", &result, &result.Tx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+//GetCoins - Compra coins a la bolsa central
+func (c *MerchantClient) GetCoins(amount float64) (Tx, error) {
+	return c.invokeTx("getcoins", formatAmount(amount))
+}
+
+//ReturnCoins - Devuelve coins a la bolsa central y devuelve el saldo que queda en el comercio
+func (c *MerchantClient) ReturnCoins(amount float64) (float64, Tx, error) {
+	tx := Tx{}
+	response := codeResponse{}
+	err := c.invoke("returncoins", &response, &tx, formatAmount(amount))
+	if err != nil || tx.TxID != "" {
+		return 0, tx, err
+	}
+	balance, err := responseAmount("returncoins", response)
+	return balance, tx, err
+}
+
+//SetRate - Programa una tasa desde from (0 es ahora) en una moneda (vacio es la del comercio)
+func (c *MerchantClient) SetRate(rate float64, from int64, currency string) (Tx, error) {
+	args := []string{formatAmount(rate)}
+	if from != 0 || currency != "" {
+		start := ""
+		if from != 0 {
+			start = strconv.FormatInt(from, 10)
+		}
+		args = append(args, start)
+	}
+	if currency != "" {
+		args = append(args, currency)
+	}
+	return c.invokeTx("setrate", args...)
+}
+
+//SetWalletContract - Cambia el chaincode del wallet
+func (c *MerchantClient) SetWalletContract(chaincode string) (Tx, error) {
+	return c.invokeTx("setwalletcontract", chaincode)
+}
+
+//SetCategoryRule - Regla de acumulacion de una categoria
+func (c *MerchantClient) SetCategoryRule(rule CategoryRule) (Tx, error) {
+	return c.invokeTx("setcategoryrule", rule.Category, formatAmount(rule.Multiplier), strconv.FormatBool(rule.Redeemable))
+}
+
+//SetReplenish - Reposicion automatica de coins
+func (c *MerchantClient) SetReplenish(config ReplenishConfig) (Tx, error) {
+	return c.invokeTx("setreplenish", formatAmount(config.LowWater), formatAmount(config.Target), formatAmount(config.DailyCap))
+}
+
+//SetReward - Crea o actualiza un premio
+func (c *MerchantClient) SetReward(reward Reward) (Tx, error) {
+	return c.invokeTx("setreward", reward.Id, reward.Name, formatAmount(reward.Price), strconv.FormatInt(reward.Stock, 10), strconv.FormatInt(reward.ValidFrom, 10), strconv.FormatInt(reward.ValidTo, 10))
+}
+
+//NewRedemptionCode - Codigo de canje aleatorio de 16 caracteres hexadecimales
+func NewRedemptionCode() (string, error) {
+	bytes := make([]byte, 8)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(hex.EncodeToString(bytes)), nil
+}
+
+//RedemptionHash - Hash sha256 en hexadecimal con el que se registra un codigo de canje, sin distinguir mayusculas
+func RedemptionHash(code string) string {
+	sum := sha256.Sum256([]byte(strings.ToUpper(strings.TrimSpace(code))))
+	return hex.EncodeToString(sum[:])
+}
+
+//RedeemReward - Canjea un premio y devuelve su codigo. El codigo se genera aqui y al chaincode solo llega su hash
+func (c *MerchantClient) RedeemReward(wallet string, reward string, attribution Attribution) (*Redemption, error) {
+	code, err := NewRedemptionCode()
+	if err != nil {
+		return nil, err
+	}
+	args := []string{wallet, reward, RedemptionHash(code)}
+	if !attribution.empty() {
+		args = append(args, attribution.Store, attribution.Terminal, attribution.Cashier)
+	}
+	redemption := Redemption{}
+	err = c.invoke("redeemreward", &redemption, &redemption.Tx, args...)
+	if err != nil {
+		return nil, err
+	}
+	redemption.Code = code
+	return &redemption, nil
+}
+
+//UseRedemption - Marca un codigo de premio como usado. Lo invoca el administrador o el certificado del comercio
+func (c *MerchantClient) UseRedemption(code string) (*Redemption, error) {
+	redemption := Redemption{}
+	err := c.invoke("useredemption", &redemption, &redemption.Tx, code)
+	if err != nil {
+		return nil, err
+	}
+	return &redemption, nil
+}
+
+//IssueVouchers - Emite un lote de cupones pagado por el comercio; Source se ignora, es el id del comercio
+func (c *MerchantClient) IssueVouchers(request VoucherBatchRequest) (*VoucherBatch, error) {
+	args := []string{request.Id, formatAmount(request.Value), strconv.FormatInt(request.Expiry, 10), strconv.FormatInt(request.WalletCap, 10)}
+	args = append(args, request.Hashes...)
+
+	batch := VoucherBatch{}
+	err := c.invoke("issuevouchers", &batch, &batch.Tx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+//ReclaimVouchers - Recupera en el comercio los coins retenidos por un lote vencido
+func (c *MerchantClient) ReclaimVouchers(id string) (*VoucherRefund, error) {
+	refund := VoucherRefund{}
+	err := c.invoke("reclaimvouchers", &refund, &refund.Tx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+//SetRedemptionRules - Reglas de canje del comercio
+func (c *MerchantClient) SetRedemptionRules(rules RedemptionRules) (Tx, error) {
+	return c.invokeTx("setredemptionrules", formatAmount(rules.MaxPercent), formatAmount(rules.MinCoins), formatAmount(rules.Step), rules.Rounding)
+}
+
+//GetBalance - Saldo y limite de un wallet consultado desde el comercio
+func (c *MerchantClient) GetBalance(wallet string) (*Balance, error) {
+	balance := Balance{}
+	err := c.query("getbalance", &balance, wallet)
+	if err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+//GetTotalCoin - Coins del comercio
+func (c *MerchantClient) GetTotalCoin() (*MerchantBalance, error) {
+	balance := MerchantBalance{}
+	err := c.query("gettotalcoin", &balance)
+	if err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+//GetMovements - Canjes del comercio filtrados por la atribucion que no este vacia
+func (c *MerchantClient) GetMovements(business string, attribution Attribution) ([]Canje, error) {
+	args := []string{business}
+	if !attribution.empty() {
+		args = append(args, attribution.Store, attribution.Terminal, attribution.Cashier)
+	}
+	canjes := []Canje{}
+	err := c.query("getmovimientos", &canjes, args...)
+	return canjes, err
+}
+
+//GetRates - Historial de tasas de una moneda, o de todas si es vacio
+func (c *MerchantClient) GetRates(currency string) ([]Rate, error) {
+	args := []string{}
+	if currency != "" {
+		args = append(args, currency)
+	}
+	rates := []Rate{}
+	err := c.query("getrates", &rates, args...)
+	return rates, err
+}
+
+//CheckWallet - Verifica que el chaincode del wallet responda
+func (c *MerchantClient) CheckWallet() (*WalletHealth, error) {
+	health := WalletHealth{}
+	response, err := c.Transport.Query(c.Chaincode, "checkwallet")
+	if err != nil {
+		return nil, DecodeError("checkwallet", err)
+	}
+	//checkwallet informa con code 1 que el wallet no responde, no es un error de la consulta
+	err = json.Unmarshal(response, &health)
+	if err != nil {
+		return nil, &Error{Kind: KindChaincode, Function: "checkwallet", Code: 1, Message: fmt.Sprintf("Respuesta invalida: %s", response)}
+	}
+	return &health, nil
+}
+
+//GetCategoryRules - Reglas por categoria
+func (c *MerchantClient) GetCategoryRules() ([]CategoryRule, error) {
+	rules := []CategoryRule{}
+	err := c.query("getcategoryrules", &rules)
+	return rules, err
+}
+
+//GetTotalsBy - Totales por store, terminal o cashier, opcionalmente en un periodo
+func (c *MerchantClient) GetTotalsBy(group string, from int64, to int64) ([]AttributionTotal, error) {
+	totals := []AttributionTotal{}
+	err := c.query("gettotalsby", &totals, append([]string{group}, period(from, to)...)...)
+	return totals, err
+}
+
+//GetReceipt - Resultado guardado de un recibo
+func (c *MerchantClient) GetReceipt(receipt string) (*Receipt, error) {
+	result := Receipt{}
+	err := c.query("getreceipt", &result, receipt)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+//GetCanjes - Canjes de un wallet, o de todos si es vacio, opcionalmente en un periodo
+func (c *MerchantClient) GetCanjes(wallet string, from int64, to int64) ([]Canje, error) {
+	canjes := []Canje{}
+	err := c.query("getcanjes", &canjes, append([]string{wallet}, period(from, to)...)...)
+	return canjes, err
+}
+
+//GetPeriodTotals - Totales por day, week o month
+func (c *MerchantClient) GetPeriodTotals(bucket string, from int64, to int64) ([]PeriodTotal, error) {
+	totals := []PeriodTotal{}
+	err := c.query("getperiodtotals", &totals, bucket, strconv.FormatInt(from, 10), strconv.FormatInt(to, 10))
+	return totals, err
+}
+
+//GetTopCustomers - Clientes con mas compras en el periodo; limit 0 usa el del chaincode
+func (c *MerchantClient) GetTopCustomers(from int64, to int64, limit int) ([]CustomerTotal, error) {
+	args := []string{strconv.FormatInt(from, 10), strconv.FormatInt(to, 10)}
+	if limit > 0 {
+		args = append(args, strconv.Itoa(limit))
+	}
+	customers := []CustomerTotal{}
+	err := c.query("gettopcustomers", &customers, args...)
+	return customers, err
+}
+
+//GetRewards - Catalogo de premios
+func (c *MerchantClient) GetRewards() ([]Reward, error) {
+	rewards := []Reward{}
+	err := c.query("getrewards", &rewards)
+	return rewards, err
+}
+
+//GetRedemption - Estado de un codigo de premio
+func (c *MerchantClient) GetRedemption(code string) (*Redemption, error) {
+	redemption := Redemption{}
+	err := c.query("getredemption", &redemption, code)
+	if err != nil {
+		return nil, err
+	}
+	return &redemption, nil
+}
+
+//GetRedemptionRules - Reglas de canje del comercio
+func (c *MerchantClient) GetRedemptionRules() (*RedemptionRules, error) {
+	rules := RedemptionRules{}
+	err := c.query("getredemptionrules", &rules)
+	if err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
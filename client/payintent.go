@@ -0,0 +1,111 @@
+/*
+* Adrian Pareja
+ */
+package client
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+//Tipos de intencion de pago
+const (
+	PayIntentMerchant = "M"
+	PayIntentWallet   = "W"
+)
+
+const payIntentVersion = "LP1"
+
+//Estados de una intencion de pago
+const (
+	PaymentPending = "pending"
+	PaymentPaid    = "paid"
+	PaymentExpired = "expired"
+)
+
+//PayIntent - Structure for a payment intent shown as a QR code. Expiry en milisegundos
+type PayIntent struct {
+	Kind      string  `json:"kind"`
+	Payee     string  `json:"payee"`
+	Amount    float64 `json:"amount"`
+	Reference string  `json:"reference"`
+	Expiry    int64   `json:"expiry"`
+}
+
+//Payment - Structure for the state of a payment intent
+type Payment struct {
+	PayIntent
+	Status string `json:"status"`
+	Payer  string `json:"payer,omitempty"`
+	Time   int64  `json:"time,omitempty"`
+}
+
+//PayIntentResult - Structure for the result of payintent
+type PayIntentResult struct {
+	Tx
+	Balance Amount  `json:"balance"`
+	Payment Payment `json:"payment"`
+}
+
+func payIntentChecksum(text string) string {
+	return fmt.Sprintf("%08X", crc32.ChecksumIEEE([]byte(text)))
+}
+
+//Encode - Texto del QR: LP1:<tipo>:<beneficiario>:<monto>:<referencia>:<vencimiento>:<checksum>
+func (p PayIntent) Encode() string {
+	text := strings.Join([]string{
+		payIntentVersion,
+		p.Kind,
+		url.QueryEscape(p.Payee),
+		formatAmount(p.Amount),
+		url.QueryEscape(p.Reference),
+		strconv.FormatInt(p.Expiry, 10),
+	}, ":") + ":"
+	return text + payIntentChecksum(text)
+}
+
+//DecodePayIntent - Lee el texto de un QR con las mismas validaciones que el chaincode, salvo el vencimiento
+func DecodePayIntent(text string) (*PayIntent, error) {
+	text = strings.TrimSpace(text)
+	i := strings.LastIndex(text, ":")
+	if i < 0 {
+		return nil, errors.New("Intencion de pago invalida")
+	}
+	if strings.ToUpper(text[i+1:]) != payIntentChecksum(text[:i+1]) {
+		return nil, errors.New("Checksum de la intencion de pago invalido")
+	}
+
+	parts := strings.Split(text[:i], ":")
+	if parts[0] != payIntentVersion {
+		return nil, errors.New("Version de intencion de pago no soportada: " + parts[0])
+	}
+	if len(parts) != 6 {
+		return nil, errors.New("Intencion de pago invalida")
+	}
+	if parts[1] != PayIntentMerchant && parts[1] != PayIntentWallet {
+		return nil, errors.New("Tipo de intencion de pago invalido: " + parts[1])
+	}
+	payee, err := url.QueryUnescape(parts[2])
+	if err != nil || payee == "" {
+		return nil, errors.New("Beneficiario invalido: " + parts[2])
+	}
+	amount, err := strconv.ParseFloat(parts[3], 64)
+	if err != nil || !(amount > 0) || math.IsInf(amount, 0) {
+		return nil, errors.New("Monto invalido: " + parts[3])
+	}
+	reference, err := url.QueryUnescape(parts[4])
+	if err != nil || reference == "" {
+		return nil, errors.New("Referencia invalida: " + parts[4])
+	}
+	expiry, err := strconv.ParseInt(parts[5], 10, 64)
+	if err != nil {
+		return nil, errors.New("Vencimiento invalido: " + parts[5])
+	}
+
+	return &PayIntent{Kind: parts[1], Payee: payee, Amount: amount, Reference: reference, Expiry: expiry}, nil
+}
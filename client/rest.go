@@ -0,0 +1,130 @@
+/*
+* Adrian Pareja
+ */
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//RESTTransport - Transport contra el endpoint /chaincode (JSON-RPC 2.0) de un peer
+type RESTTransport struct {
+	//URL del peer, por ejemplo http://localhost:7050
+	URL string
+	//SecureContext es el usuario registrado en el peer que firma las transacciones
+	SecureContext string
+	Client        *http.Client
+
+	lastId int
+}
+
+//NewRESTTransport - Crea un transport REST con timeout de 30 segundos
+func NewRESTTransport(url string, secureContext string) *RESTTransport {
+	return &RESTTransport{URL: strings.TrimRight(url, "/"), SecureContext: secureContext, Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+type rpcChaincodeID struct {
+	Name string `json:"name"`
+}
+
+type rpcCtorMsg struct {
+	Args []string `json:"args"`
+}
+
+type rpcParams struct {
+	Type          int            `json:"type"`
+	ChaincodeID   rpcChaincodeID `json:"chaincodeID"`
+	CtorMsg       rpcCtorMsg     `json:"ctorMsg"`
+	SecureContext string         `json:"secureContext,omitempty"`
+}
+
+type rpcRequest struct {
+	Jsonrpc string    `json:"jsonrpc"`
+	Method  string    `json:"method"`
+	Params  rpcParams `json:"params"`
+	Id      int       `json:"id"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data"`
+}
+
+type rpcResult struct {
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+type rpcResponse struct {
+	Result *rpcResult `json:"result"`
+	Error  *rpcError  `json:"error"`
+}
+
+//Invoke - Envia la transaccion al peer y devuelve el id de la transaccion
+func (r *RESTTransport) Invoke(chaincode string, function string, args ...string) ([]byte, error) {
+	return r.call("invoke", chaincode, function, args)
+}
+
+//Query - Consulta el chaincode en el peer
+func (r *RESTTransport) Query(chaincode string, function string, args ...string) ([]byte, error) {
+	return r.call("query", chaincode, function, args)
+}
+
+func (r *RESTTransport) call(method string, chaincode string, function string, args []string) ([]byte, error) {
+	r.lastId = r.lastId + 1
+	request := rpcRequest{
+		Jsonrpc: "2.0",
+		Method:  method,
+		Params: rpcParams{
+			Type:          1,
+			ChaincodeID:   rpcChaincodeID{Name: chaincode},
+			CtorMsg:       rpcCtorMsg{Args: append([]string{function}, args...)},
+			SecureContext: r.SecureContext,
+		},
+		Id: r.lastId,
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	httpResponse, err := client.Post(r.URL+"/chaincode", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("Fallo la llamada al peer %s. %s", r.URL, err)
+	}
+	defer httpResponse.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	response := rpcResponse{}
+	err = json.Unmarshal(responseBody, &response)
+	if err != nil {
+		return nil, fmt.Errorf("Respuesta invalida del peer (HTTP %d): %s", httpResponse.StatusCode, responseBody)
+	}
+	if response.Error != nil {
+		message := response.Error.Message
+		if response.Error.Data != "" {
+			message = message + ": " + response.Error.Data
+		}
+		return nil, errors.New(message)
+	}
+	if response.Result == nil {
+		return nil, fmt.Errorf("Respuesta sin resultado del peer: %s", responseBody)
+	}
+	return []byte(response.Result.Message), nil
+}
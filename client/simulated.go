@@ -0,0 +1,24 @@
+/*
+* Adrian Pareja
+ */
+package client
+
+import (
+	"github.com/ccamaleon5/blockchain/simulator"
+)
+
+//SimulatorTransport - Transport contra una red en memoria del paquete simulator.
+//A diferencia de un peer, Invoke devuelve la respuesta de la transaccion
+type SimulatorTransport struct {
+	Network *simulator.Network
+}
+
+//Invoke - Ejecuta la transaccion en la red simulada
+func (s *SimulatorTransport) Invoke(chaincode string, function string, args ...string) ([]byte, error) {
+	return s.Network.Invoke(chaincode, function, args...)
+}
+
+//Query - Ejecuta la consulta en la red simulada
+func (s *SimulatorTransport) Query(chaincode string, function string, args ...string) ([]byte, error) {
+	return s.Network.Query(chaincode, function, args...)
+}
@@ -0,0 +1,15 @@
+/*
+* Adrian Pareja
+ */
+
+//Package client llama a las funciones de los chaincodes wallet y merchant a traves de un Transport:
+//el API REST JSON-RPC de un peer o un ledger simulado en memoria.
+package client
+
+//Transport - Llama a las funciones de un chaincode por su id
+type Transport interface {
+	//Invoke ejecuta una transaccion. Contra un peer devuelve el id de la transaccion
+	Invoke(chaincode string, function string, args ...string) ([]byte, error)
+	//Query ejecuta una consulta y devuelve su respuesta
+	Query(chaincode string, function string, args ...string) ([]byte, error)
+}
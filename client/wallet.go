@@ -0,0 +1,536 @@
+/*
+* Adrian Pareja
+ */
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+//WalletClient - Cliente tipado del chaincode wallet
+type WalletClient struct {
+	Transport Transport
+	Chaincode string
+}
+
+//NewWalletClient - Crea el cliente del chaincode wallet con su id
+func NewWalletClient(transport Transport, chaincode string) *WalletClient {
+	return &WalletClient{Transport: transport, Chaincode: chaincode}
+}
+
+//Wallet - Structure for the data of a wallet (getdatos)
+type Wallet struct {
+	Id       string  `json:"id"`
+	Email    string  `json:"email"`
+	Phone    string  `json:"phone"`
+	Document string  `json:"document"`
+	Password string  `json:"password"`
+	Amount   float64 `json:"amount"`
+	Limit    float64 `json:"limit"`
+	Tier     string  `json:"tier"`
+}
+
+//CreateWalletRequest - Structure for createwallet
+type CreateWalletRequest struct {
+	Id       string
+	Email    string
+	Phone    string
+	Document string
+	Password string
+	Amount   float64
+}
+
+//TransferRequest - Structure for transfer. El chaincode recibe primero el receptor
+type TransferRequest struct {
+	From   string
+	To     string
+	Amount float64
+}
+
+//Balance - Structure for getbalance. Tier y Bonus vienen vacios cuando lo consulta el chaincode merchant
+type Balance struct {
+	Balance Amount `json:"balance"`
+	Limit   Amount `json:"limit"`
+	Tier    string `json:"tier"`
+	Bonus   Amount `json:"bonus"`
+}
+
+//TierInfo - Structure for gettier
+type TierInfo struct {
+	Tier     string `json:"tier"`
+	Earned   Amount `json:"earned"`
+	Bonus    Amount `json:"bonus"`
+	Limit    Amount `json:"limit"`
+	Transfer Amount `json:"transfer"`
+}
+
+//Movement - Structure for a wallet movement (getmovimientos)
+type Movement struct {
+	Time     int64   `json:"time"`
+	WalletId string  `json:"walletid"`
+	Business string  `json:"business"`
+	Amount   float64 `json:"amount"`
+	Balance  float64 `json:"balance"`
+	Type     string  `json:"type"`
+	Store    string  `json:"store,omitempty"`
+	Terminal string  `json:"terminal,omitempty"`
+	Cashier  string  `json:"cashier,omitempty"`
+	Currency string  `json:"currency,omitempty"`
+	Minor    int64   `json:"minor,omitempty"`
+}
+
+//WalletBalance - Structure for a row of getwallets
+type WalletBalance struct {
+	WalletId string  `json:"walletid"`
+	Balance  float64 `json:"balance"`
+}
+
+//Merchant - Structure for a merchant registered in the wallet
+type Merchant struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Caller string `json:"caller"`
+}
+
+//Attribution - Structure for the store, terminal and cashier of a merchant transaction
+type Attribution struct {
+	Store    string
+	Terminal string
+	Cashier  string
+}
+
+func (a Attribution) empty() bool {
+	return a.Store == "" && a.Terminal == "" && a.Cashier == ""
+}
+
+//PurchaseRequest - Structure for purchase. Currency con RedeemMinor y PaidMinor registra el monto de la compra
+type PurchaseRequest struct {
+	Wallet      string
+	Merchant    string
+	Redeem      float64
+	Earn        float64
+	Attribution Attribution
+	Currency    string
+	RedeemMinor int64
+	PaidMinor   int64
+	//Rounding redondea la acumulacion despues del bonus del tier: none, down, up o nearest
+	Rounding string
+}
+
+//PurchaseResult - Structure for the result of purchase
+type PurchaseResult struct {
+	Tx
+	Balance   Amount `json:"balance"`
+	Limit     Amount `json:"limit"`
+	Tier      string `json:"tier"`
+	Redeemed  Amount `json:"redeemed"`
+	Earned    Amount `json:"earned"`
+	RedeemRef string `json:"redeemref"`
+	EarnRef   string `json:"earnref"`
+}
+
+//MerchantSettlement - Structure for the position of a merchant in a settlement
+type MerchantSettlement struct {
+	Merchant string  `json:"merchant"`
+	Issued   float64 `json:"issued"`
+	Redeemed float64 `json:"redeemed"`
+	Net      float64 `json:"net"`
+	Soles    float64 `json:"soles"`
+}
+
+//Settlement - Structure for a settlement report
+type Settlement struct {
+	Tx
+	From      int64                `json:"from"`
+	To        int64                `json:"to"`
+	Price     float64              `json:"price"`
+	Closed    int64                `json:"closed"`
+	Merchants []MerchantSettlement `json:"merchants"`
+}
+
+//VoucherBatchRequest - Structure for issuevouchers. Hashes son los sha256 de los codigos (ver VoucherHash)
+type VoucherBatchRequest struct {
+	Id        string
+	Value     float64
+	Expiry    int64
+	WalletCap int64
+	Source    string
+	Hashes    []string
+}
+
+//VoucherBatch - Structure for a voucher batch
+type VoucherBatch struct {
+	Tx
+	Id          string  `json:"id"`
+	Value       float64 `json:"value"`
+	Expiry      int64   `json:"expiry"`
+	WalletCap   int64   `json:"walletcap"`
+	Source      string  `json:"source"`
+	Issued      int64   `json:"issued"`
+	Redeemed    int64   `json:"redeemed"`
+	Utilization float64 `json:"utilization"`
+	Created     int64   `json:"created"`
+	Escrow      float64 `json:"escrow"`
+}
+
+//VoucherRefund - Structure for the coins returned by an expired merchant batch (closevoucherbatch, reclaimvouchers)
+type VoucherRefund struct {
+	Tx
+	Refund  Amount `json:"refund"`
+	Batch   string `json:"batch,omitempty"`
+	Balance Amount `json:"balance,omitempty"`
+}
+
+//VoucherRedemption - Structure for the result of redeemvoucher
+type VoucherRedemption struct {
+	Tx
+	Balance Amount `json:"balance"`
+	Value   Amount `json:"value"`
+	Batch   string `json:"batch"`
+}
+
+//VoucherHash - Hash sha256 en hexadecimal con el que se emite un codigo de cupon
+func VoucherHash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *WalletClient) invoke(function string, value interface{}, tx *Tx, args ...string) error {
+	return invoke(c.Transport, c.Chaincode, function, value, tx, args...)
+}
+
+func (c *WalletClient) query(function string, value interface{}, args ...string) error {
+	return query(c.Transport, c.Chaincode, function, value, args...)
+}
+
+//invokeAmount - Invoke cuya respuesta es {"code":0,"response":"monto"}
+func (c *WalletClient) invokeAmount(function string, args ...string) (float64, Tx, error) {
+	tx := Tx{}
+	response := codeResponse{}
+	err := c.invoke(function, &response, &tx, args...)
+	if err != nil || tx.TxID != "" {
+		return 0, tx, err
+	}
+	amount, err := responseAmount(function, response)
+	return amount, tx, err
+}
+
+//CreateWallet - Crea un wallet
+func (c *WalletClient) CreateWallet(request CreateWalletRequest) (Tx, error) {
+	tx := Tx{}
+	err := c.invoke("createwallet", nil, &tx, request.Id, request.Email, request.Phone, request.Document, request.Password, formatAmount(request.Amount))
+	return tx, err
+}
+
+//Transfer - Transfiere coins de From a To
+func (c *WalletClient) Transfer(request TransferRequest) (Tx, error) {
+	tx := Tx{}
+	err := c.invoke("transfer", nil, &tx, request.To, request.From, formatAmount(request.Amount))
+	return tx, err
+}
+
+//PutBalance - Acredita coins a un wallet por un comercio
+func (c *WalletClient) PutBalance(wallet string, merchant string, amount float64) (Tx, error) {
+	tx := Tx{}
+	err := c.invoke("putbalance", nil, &tx, wallet, merchant, formatAmount(amount))
+	return tx, err
+}
+
+//DebitBalance - Debita coins de un wallet por un comercio
+func (c *WalletClient) DebitBalance(wallet string, merchant string, amount float64) (Tx, error) {
+	tx := Tx{}
+	err := c.invoke("debitbalance", nil, &tx, wallet, merchant, formatAmount(amount))
+	return tx, err
+}
+
+//PutTotalCoin - Aumenta la bolsa central y devuelve el nuevo saldo
+func (c *WalletClient) PutTotalCoin(amount float64) (float64, Tx, error) {
+	return c.invokeAmount("puttotalcoin", formatAmount(amount))
+}
+
+//DebitTotalCoin - Disminuye la bolsa central y devuelve el nuevo saldo
+func (c *WalletClient) DebitTotalCoin(amount float64) (float64, Tx, error) {
+	return c.invokeAmount("debittotalcoin", formatAmount(amount))
+}
+
+//Reset - Reinicia los saldos
+func (c *WalletClient) Reset() (Tx, error) {
+	tx := Tx{}
+	err := c.invoke("reset", nil, &tx)
+	return tx, err
+}
+
+//RegisterMerchant - Registra un comercio; Status se ignora
+func (c *WalletClient) RegisterMerchant(merchant Merchant) (Tx, error) {
+	tx := Tx{}
+	err := c.invoke("registermerchant", nil, &tx, merchant.Id, merchant.Name, merchant.Caller)
+	return tx, err
+}
+
+//UpdateMerchant - Actualiza nombre y caller de un comercio
+func (c *WalletClient) UpdateMerchant(merchant Merchant) (Tx, error) {
+	tx := Tx{}
+	err := c.invoke("updatemerchant", nil, &tx, merchant.Id, merchant.Name, merchant.Caller)
+	return tx, err
+}
+
+//SetMerchantStatus - Cambia el estado de un comercio: active o suspended
+func (c *WalletClient) SetMerchantStatus(id string, status string) (Tx, error) {
+	tx := Tx{}
+	err := c.invoke("setmerchantstatus", nil, &tx, id, status)
+	return tx, err
+}
+
+//SetMerchantKey - Registra la llave publica ECDSA del comercio en PEM (ver MarshalMerchantKey)
+func (c *WalletClient) SetMerchantKey(merchant string, key string) (Tx, error) {
+	tx := Tx{}
+	err := c.invoke("setmerchantkey", nil, &tx, merchant, key)
+	return tx, err
+}
+
+//SetEarnCap - Fija cuantos coins puede entregar en total el comercio con vouchers de acumulacion
+func (c *WalletClient) SetEarnCap(merchant string, limit float64) (*EarnCap, error) {
+	earnCap := EarnCap{}
+	err := c.invoke("setearncap", &earnCap, &earnCap.Tx, merchant, formatAmount(limit))
+	if err != nil {
+		return nil, err
+	}
+	return &earnCap, nil
+}
+
+//SetCoinPrice - Fija el precio en soles del coin
+func (c *WalletClient) SetCoinPrice(price float64) (Tx, error) {
+	tx := Tx{}
+	err := c.invoke("setcoinprice", nil, &tx, formatAmount(price))
+	return tx, err
+}
+
+//CloseSettlement - Cierra la liquidacion de un periodo en milisegundos
+func (c *WalletClient) CloseSettlement(from int64, to int64) (*Settlement, error) {
+	settlement := Settlement{}
+	err := c.invoke("closesettlement", &settlement, &settlement.Tx, strconv.FormatInt(from, 10), strconv.FormatInt(to, 10))
+	if err != nil {
+		return nil, err
+	}
+	return &settlement, nil
+}
+
+//Purchase - Canje y acumulacion de una compra en una sola transaccion
+func (c *WalletClient) Purchase(request PurchaseRequest) (*PurchaseResult, error) {
+	args := []string{request.Wallet, request.Merchant, formatAmount(request.Redeem), formatAmount(request.Earn)}
+	if !request.Attribution.empty() || request.Currency != "" || request.Rounding != "" {
+		args = append(args, request.Attribution.Store, request.Attribution.Terminal, request.Attribution.Cashier)
+	}
+	if request.Currency != "" || request.Rounding != "" {
+		args = append(args, request.Currency, strconv.FormatInt(request.RedeemMinor, 10), strconv.FormatInt(request.PaidMinor, 10))
+	}
+	if request.Rounding != "" {
+		args = append(args, request.Rounding)
+	}
+
+	result := PurchaseResult{}
+	err := c.invoke("purchase", &result, &result.Tx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+//IssueVouchers - Emite un lote de cupones
+func (c *WalletClient) IssueVouchers(request VoucherBatchRequest) (*VoucherBatch, error) {
+	args := []string{request.Id, formatAmount(request.Value), strconv.FormatInt(request.Expiry, 10), strconv.FormatInt(request.WalletCap, 10), request.Source}
+	args = append(args, request.Hashes...)
+
+	batch := VoucherBatch{}
+	err := c.invoke("issuevouchers", &batch, &batch.Tx, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+//CloseVoucherBatch - Devuelve a la bolsa central los coins retenidos por un lote vencido de comercio
+func (c *WalletClient) CloseVoucherBatch(id string) (*VoucherRefund, error) {
+	refund := VoucherRefund{}
+	err := c.invoke("closevoucherbatch", &refund, &refund.Tx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+//RedeemVoucher - Canjea un cupon en un wallet
+func (c *WalletClient) RedeemVoucher(wallet string, code string) (*VoucherRedemption, error) {
+	redemption := VoucherRedemption{}
+	err := c.invoke("redeemvoucher", &redemption, &redemption.Tx, wallet, code)
+	if err != nil {
+		return nil, err
+	}
+	return &redemption, nil
+}
+
+//PayIntent - Paga desde el wallet la intencion de pago leida de un QR (ver PayIntent.Encode)
+func (c *WalletClient) PayIntent(wallet string, intent string) (*PayIntentResult, error) {
+	result := PayIntentResult{}
+	err := c.invoke("payintent", &result, &result.Tx, wallet, intent)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+//RedeemEarnVoucher - Acredita en el wallet un voucher firmado por el comercio (ver EarnVoucher.Sign)
+func (c *WalletClient) RedeemEarnVoucher(wallet string, voucher string) (*EarnVoucherResult, error) {
+	result := EarnVoucherResult{}
+	err := c.invoke("redeemearnvoucher", &result, &result.Tx, wallet, voucher)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+//GetBalance - Saldo, limite y nivel de un wallet
+func (c *WalletClient) GetBalance(wallet string) (*Balance, error) {
+	balance := Balance{}
+	err := c.query("getbalance", &balance, wallet)
+	if err != nil {
+		return nil, err
+	}
+	return &balance, nil
+}
+
+//GetTotalCoin - Saldo de la bolsa central
+func (c *WalletClient) GetTotalCoin() (float64, error) {
+	response := codeResponse{}
+	err := c.query("gettotalcoin", &response)
+	if err != nil {
+		return 0, err
+	}
+	return responseAmount("gettotalcoin", response)
+}
+
+//GetMovements - Movimientos de un wallet, o de todos si wallet es vacio
+func (c *WalletClient) GetMovements(wallet string) ([]Movement, error) {
+	args := []string{""}
+	if wallet != "" {
+		args = append(args, wallet)
+	}
+	movements := []Movement{}
+	err := c.query("getmovimientos", &movements, args...)
+	return movements, err
+}
+
+//GetWallet - Datos de un wallet
+func (c *WalletClient) GetWallet(wallet string) (*Wallet, error) {
+	data := Wallet{}
+	err := c.query("getdatos", &data, wallet)
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+//GetWallets - Saldo de todos los wallets
+func (c *WalletClient) GetWallets() ([]WalletBalance, error) {
+	wallets := []WalletBalance{}
+	err := c.query("getwallets", &wallets, "")
+	return wallets, err
+}
+
+//GetTier - Nivel de un wallet
+func (c *WalletClient) GetTier(wallet string) (*TierInfo, error) {
+	tier := TierInfo{}
+	err := c.query("gettier", &tier, wallet)
+	if err != nil {
+		return nil, err
+	}
+	return &tier, nil
+}
+
+//GetMerchant - Comercio registrado
+func (c *WalletClient) GetMerchant(id string) (*Merchant, error) {
+	merchant := Merchant{}
+	err := c.query("getmerchant", &merchant, id)
+	if err != nil {
+		return nil, err
+	}
+	return &merchant, nil
+}
+
+//GetMerchants - Comercios registrados
+func (c *WalletClient) GetMerchants() ([]Merchant, error) {
+	merchants := []Merchant{}
+	err := c.query("getmerchants", &merchants)
+	return merchants, err
+}
+
+//GetSettlement - Liquidacion de un periodo; price 0 usa el precio configurado
+func (c *WalletClient) GetSettlement(from int64, to int64, price float64) (*Settlement, error) {
+	args := []string{strconv.FormatInt(from, 10), strconv.FormatInt(to, 10)}
+	if price != 0 {
+		args = append(args, formatAmount(price))
+	}
+	settlement := Settlement{}
+	err := c.query("getsettlement", &settlement, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &settlement, nil
+}
+
+//GetSettlements - Liquidaciones cerradas
+func (c *WalletClient) GetSettlements() ([]Settlement, error) {
+	settlements := []Settlement{}
+	err := c.query("getsettlements", &settlements)
+	return settlements, err
+}
+
+//GetVoucherBatch - Estado de un lote de cupones
+func (c *WalletClient) GetVoucherBatch(id string) (*VoucherBatch, error) {
+	batch := VoucherBatch{}
+	err := c.query("getvoucherbatch", &batch, id)
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+//GetVoucherBatches - Lotes de cupones
+func (c *WalletClient) GetVoucherBatches() ([]VoucherBatch, error) {
+	batches := []VoucherBatch{}
+	err := c.query("getvoucherbatches", &batches)
+	return batches, err
+}
+
+//GetPayIntent - Estado de una intencion de pago: PaymentPending, PaymentPaid o PaymentExpired
+func (c *WalletClient) GetPayIntent(intent string) (*Payment, error) {
+	payment := Payment{}
+	err := c.query("getpayintent", &payment, intent)
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+//GetEarnCap - Tope de vouchers de acumulacion de un comercio y lo ya acreditado
+func (c *WalletClient) GetEarnCap(merchant string) (*EarnCap, error) {
+	earnCap := EarnCap{}
+	err := c.query("getearncap", &earnCap, merchant)
+	if err != nil {
+		return nil, err
+	}
+	return &earnCap, nil
+}
+
+//GetMerchantKey - Llave publica registrada de un comercio
+func (c *WalletClient) GetMerchantKey(merchant string) (*MerchantKey, error) {
+	key := MerchantKey{}
+	err := c.query("getmerchantkey", &key, merchant)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
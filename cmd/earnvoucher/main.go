@@ -0,0 +1,96 @@
+/*
+* Adrian Pareja
+ */
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/ccamaleon5/blockchain/client"
+)
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	os.Exit(1)
+}
+
+//generateKey - Crea la llave P-256 del comercio y escribe la privada en path y la publica en path.pub
+func generateKey(path string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600)
+	if err != nil {
+		return err
+	}
+	public, err := client.MarshalMerchantKey(&key.PublicKey)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path+".pub", []byte(public), 0644)
+}
+
+func loadKey(path string) (*ecdsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("Llave privada invalida en " + path)
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+func main() {
+	keyPath := flag.String("key", "merchant.pem", "llave privada del comercio")
+	genkey := flag.Bool("genkey", false, "crea la llave en -key y la publica en -key.pub para setmerchantkey")
+	merchant := flag.String("merchant", "", "id del comercio en el wallet")
+	id := flag.String("id", "", "id unico del voucher, por ejemplo el numero de recibo")
+	amount := flag.Float64("amount", 0, "coins a acreditar")
+	ttl := flag.Duration("ttl", 30*24*time.Hour, "vigencia del voucher")
+	flag.Parse()
+
+	if *genkey {
+		err := generateKey(*keyPath)
+		if err != nil {
+			fail(err)
+		}
+		fmt.Println("Llave creada en", *keyPath, "y", *keyPath+".pub")
+		return
+	}
+
+	if *merchant == "" || *id == "" || *amount <= 0 {
+		fail(errors.New("Se esperan -merchant, -id y -amount"))
+	}
+	key, err := loadKey(*keyPath)
+	if err != nil {
+		fail(err)
+	}
+	voucher := client.EarnVoucher{
+		Merchant: *merchant,
+		Id:       *id,
+		Amount:   *amount,
+		Expiry:   time.Now().Add(*ttl).UnixNano() / int64(time.Millisecond),
+	}
+	text, err := voucher.Sign(key)
+	if err != nil {
+		fail(err)
+	}
+	fmt.Println(text)
+}
@@ -0,0 +1,52 @@
+/*
+* Adrian Pareja
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/ccamaleon5/blockchain/cli"
+	"github.com/ccamaleon5/blockchain/gateway"
+)
+
+func main() {
+	configPath := flag.String("config", filepath.Join(os.Getenv("HOME"), ".loyalty.json"), "archivo de perfiles del cliente loyalty")
+	profileName := flag.String("profile", "", "perfil a usar, el de por defecto si es vacio")
+	listen := flag.String("listen", ":8080", "direccion HTTP")
+	spec := flag.Bool("openapi", false, "imprime el documento OpenAPI y termina")
+	flag.Parse()
+
+	if *spec {
+		fmt.Print(gateway.OpenAPI)
+		return
+	}
+
+	config, err := cli.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	profile, err := config.Profile(*profileName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	transport, err := profile.NewTransport()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	server := gateway.NewServer(transport, profile.Wallet, profile.Merchants)
+	fmt.Println("Gateway escuchando en", *listen)
+	err = http.ListenAndServe(*listen, server)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+* Adrian Pareja
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ccamaleon5/blockchain/cli"
+)
+
+func defaultConfigPath() string {
+	if path := os.Getenv("LOYALTY_CONFIG"); path != "" {
+		return path
+	}
+	return filepath.Join(os.Getenv("HOME"), ".loyalty.json")
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Uso: loyalty [flags] wallet|merchant <funcion> [argumentos...]")
+	fmt.Fprintln(os.Stderr, "")
+	flag.PrintDefaults()
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Funciones de wallet:")
+	cli.PrintCommands(os.Stderr, "wallet")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Funciones de merchant:")
+	cli.PrintCommands(os.Stderr, "merchant")
+}
+
+func main() {
+	configPath := flag.String("config", defaultConfigPath(), "archivo de perfiles")
+	profileName := flag.String("profile", "", "perfil a usar, el de por defecto si es vacio")
+	merchant := flag.String("merchant", "", "alias del comercio en el perfil o id de su chaincode")
+	output := flag.String("output", "table", "formato de salida: table o json")
+	peer := flag.String("peer", "", "URL del peer, reemplaza la del perfil")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	config, err := cli.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	profile, err := config.Profile(*profileName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+	if *peer != "" {
+		profile.Peer = *peer
+	}
+
+	transport, err := profile.NewTransport()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	err = cli.Run(transport, profile, cli.Options{Output: *output, Merchant: *merchant}, flag.Args(), os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
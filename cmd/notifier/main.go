@@ -0,0 +1,139 @@
+/*
+* Adrian Pareja
+ */
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ccamaleon5/blockchain/notifier"
+	"github.com/ccamaleon5/blockchain/notifier/eventhub"
+)
+
+//Config - Structure for the notifier configuration file
+type Config struct {
+	Events    string              `json:"events"`
+	Outbox    string              `json:"outbox"`
+	Interval  string              `json:"interval"`
+	Retry     RetryConfig         `json:"retry"`
+	EventHub  *EventHubConfig     `json:"eventhub,omitempty"`
+	Endpoints []notifier.Endpoint `json:"endpoints"`
+}
+
+//EventHubConfig - Structure for the peer event hub. Con eventhub el notifier escribe el mismo el archivo de eventos
+type EventHubConfig struct {
+	Address    string   `json:"address"`
+	Chaincodes []string `json:"chaincodes"`
+}
+
+//RetryConfig - Structure for the retry policy, with durations like "1s" or "5m"
+type RetryConfig struct {
+	Base     string `json:"base"`
+	Max      string `json:"max"`
+	Attempts int    `json:"attempts"`
+}
+
+func duration(value string, byDefault time.Duration) (time.Duration, error) {
+	if value == "" {
+		return byDefault, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	os.Exit(1)
+}
+
+func main() {
+	configPath := flag.String("config", "notifier.json", "archivo de configuracion del notifier")
+	sink := flag.String("sink", "", "levanta un receptor de webhooks de prueba en esta direccion en lugar del notifier")
+	secret := flag.String("secret", "", "secreto con el que el receptor de prueba valida las firmas")
+	flag.Parse()
+
+	if *sink != "" {
+		receiver := notifier.NewSink(*secret)
+		receiver.OnReceive = func(n notifier.Notification) {
+			data, _ := json.Marshal(n)
+			fmt.Println(string(data))
+		}
+		fmt.Println("Receptor escuchando en", *sink)
+		fail(http.ListenAndServe(*sink, receiver))
+	}
+
+	data, err := ioutil.ReadFile(*configPath)
+	if err != nil {
+		fail(err)
+	}
+	config := Config{}
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		fail(err)
+	}
+	if config.Events == "" {
+		fail(fmt.Errorf("Falta el archivo de eventos en %s", *configPath))
+	}
+
+	interval, err := duration(config.Interval, 2*time.Second)
+	if err != nil {
+		fail(err)
+	}
+	retry := notifier.DefaultRetry
+	retry.Base, err = duration(config.Retry.Base, retry.Base)
+	if err != nil {
+		fail(err)
+	}
+	retry.Max, err = duration(config.Retry.Max, retry.Max)
+	if err != nil {
+		fail(err)
+	}
+	if config.Retry.Attempts > 0 {
+		retry.Attempts = config.Retry.Attempts
+	}
+
+	outbox, err := notifier.LoadOutbox(config.Outbox)
+	if err != nil {
+		fail(err)
+	}
+	n := notifier.NewNotifier(&notifier.FileSource{Path: config.Events}, outbox, config.Endpoints)
+	n.Retry = retry
+
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+
+	if config.EventHub != nil {
+		go listen(&eventhub.Listener{Address: config.EventHub.Address, Chaincodes: config.EventHub.Chaincodes, Path: config.Events}, interval, stop)
+	}
+
+	fmt.Println("Notifier leyendo", config.Events)
+	n.Run(interval, stop)
+}
+
+//listen - Escucha el event hub y reconecta despues de interval cada vez que se pierde la conexion
+func listen(listener *eventhub.Listener, interval time.Duration, stop <-chan struct{}) {
+	for {
+		fmt.Println("Escuchando el event hub", listener.Address)
+		err := listener.Run(stop)
+		if err == nil {
+			return
+		}
+		fmt.Println("Error en el event hub: " + err.Error())
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
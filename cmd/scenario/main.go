@@ -0,0 +1,90 @@
+/*
+* Adrian Pareja
+ */
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ccamaleon5/blockchain/chaincode/merchant"
+	"github.com/ccamaleon5/blockchain/chaincode/wallet"
+	"github.com/ccamaleon5/blockchain/simulator"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//contracts - Contratos que los pasos deploy pueden desplegar
+var contracts = simulator.Contracts{
+	"wallet":   func() shim.Chaincode { return new(wallet.SimpleChaincode) },
+	"merchant": func() shim.Chaincode { return new(merchant.SimpleChaincode) },
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Uso: scenario [flags] escenario.json [escenario.json...]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Cada escenario corre en una red nueva; los pasos deploy usan los contratos wallet y merchant.")
+	fmt.Fprintln(os.Stderr, "")
+	flag.PrintDefaults()
+}
+
+//runScenario - Ejecuta un escenario en una red nueva. Los logs de los chaincodes se descartan salvo con -logs
+func runScenario(path string, logs bool, verbose bool) error {
+	scenario, err := simulator.LoadScenario(path)
+	if err != nil {
+		return err
+	}
+
+	stdout := os.Stdout
+	if !logs {
+		devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			return err
+		}
+		defer devNull.Close()
+		os.Stdout = devNull
+	}
+	results, err := scenario.Run(simulator.NewNetwork(), contracts)
+	os.Stdout = stdout
+
+	for i, result := range results {
+		status := "ok"
+		if err != nil && i == len(results)-1 {
+			status = "fallo"
+		} else if result.Err != nil {
+			status = "error esperado"
+		}
+		fmt.Printf("  %d %s %s %s: %s\n", i+1, result.Step.Chaincode, result.Step.Function, result.Step.Name, status)
+		if verbose && result.Response != nil {
+			fmt.Printf("    %s\n", result.Response)
+		}
+	}
+	return err
+}
+
+func main() {
+	logs := flag.Bool("logs", false, "muestra los logs de los chaincodes")
+	verbose := flag.Bool("v", false, "muestra la respuesta de cada paso")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, path := range flag.Args() {
+		fmt.Println(path)
+		err := runScenario(path, *logs, *verbose)
+		if err != nil {
+			fmt.Println("  FALLO:", err)
+			failed = true
+			continue
+		}
+		fmt.Println("  OK")
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
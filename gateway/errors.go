@@ -0,0 +1,68 @@
+/*
+* Adrian Pareja
+ */
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ccamaleon5/blockchain/client"
+)
+
+//ErrorResponse - Structure for every error returned by the gateway. Code es el code del chaincode,
+//1 cuando el chaincode devolvio un error en lugar de una respuesta
+type ErrorResponse struct {
+	Code   int    `json:"code"`
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+//apiError - Error con el status HTTP que le corresponde
+type apiError struct {
+	code    int
+	status  int
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func badRequest(message string) *apiError {
+	return &apiError{code: 1, status: http.StatusBadRequest, message: message}
+}
+
+//kindStatus - Status HTTP de cada clase de error del cliente; las demas son fallas del peer o del chaincode (502)
+var kindStatus = map[client.ErrorKind]int{
+	client.KindInvalidArgument:   http.StatusBadRequest,
+	client.KindForbidden:         http.StatusForbidden,
+	client.KindNotFound:          http.StatusNotFound,
+	client.KindConflict:          http.StatusConflict,
+	client.KindInsufficientFunds: http.StatusConflict,
+}
+
+//chaincodeError - Traduce el error del chaincode o del transport a un status HTTP
+func chaincodeError(function string, err error) *apiError {
+	decoded := client.DecodeError(function, err)
+	status, found := kindStatus[decoded.Kind]
+	if !found {
+		status = http.StatusBadGateway
+	}
+	return &apiError{code: decoded.Code, status: status, message: decoded.Message}
+}
+
+func writeError(w http.ResponseWriter, err *apiError) {
+	writeJSON(w, err.status, ErrorResponse{Code: err.code, Status: err.status, Error: err.message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		status = http.StatusInternalServerError
+		bytes = []byte(`{"code":1,"status":500,"error":"Error marshaling response"}`)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(bytes)
+}
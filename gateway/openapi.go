@@ -0,0 +1,451 @@
+/*
+* Adrian Pareja
+ */
+package gateway
+
+//OpenAPI - Documento OpenAPI del gateway, publicado en GET /openapi.yaml
+const OpenAPI = `openapi: 3.0.3
+info:
+  title: Loyalty gateway
+  version: 1.0.0
+  description: |
+    Recursos HTTP sobre los chaincodes wallet y merchant. Los montos en coins son numeros y los tiempos
+    milisegundos. Contra un peer, un POST devuelve 202 con el id de transaccion; contra un transport que
+    ejecuta la transaccion devuelve la respuesta del chaincode. Todo error tiene la forma Error: status 400
+    para argumentos invalidos, 403 sin permiso, 404 recurso desconocido, 409 conflicto de estado (saldo
+    insuficiente, ya existe, ya fue canjeado) y 502 para fallas del peer o respuestas con code distinto de 0.
+paths:
+  /wallets:
+    get:
+      summary: Lista los wallets
+      responses:
+        "200": {description: Wallets, content: {application/json: {schema: {type: array, items: {$ref: "#/components/schemas/Wallet"}}}}}
+        default: {$ref: "#/components/responses/Error"}
+    post:
+      summary: Crea un wallet (createwallet)
+      requestBody: {required: true, content: {application/json: {schema: {$ref: "#/components/schemas/WalletRequest"}}}}
+      responses:
+        "201": {$ref: "#/components/responses/Result"}
+        "202": {$ref: "#/components/responses/Accepted"}
+        default: {$ref: "#/components/responses/Error"}
+  /wallets/{id}:
+    parameters: [{$ref: "#/components/parameters/Wallet"}]
+    get:
+      summary: Datos del wallet sin el password (getdatos)
+      responses:
+        "200": {description: Wallet, content: {application/json: {schema: {$ref: "#/components/schemas/Wallet"}}}}
+        default: {$ref: "#/components/responses/Error"}
+  /wallets/{id}/balance:
+    parameters: [{$ref: "#/components/parameters/Wallet"}]
+    get:
+      summary: Saldo, limite y nivel (getbalance)
+      responses:
+        "200": {description: Saldo, content: {application/json: {schema: {$ref: "#/components/schemas/WalletBalance"}}}}
+        default: {$ref: "#/components/responses/Error"}
+  /wallets/{id}/tier:
+    parameters: [{$ref: "#/components/parameters/Wallet"}]
+    get:
+      summary: Nivel del wallet (gettier)
+      responses:
+        "200": {description: Nivel, content: {application/json: {schema: {$ref: "#/components/schemas/Tier"}}}}
+        default: {$ref: "#/components/responses/Error"}
+  /wallets/{id}/movements:
+    parameters: [{$ref: "#/components/parameters/Wallet"}]
+    get:
+      summary: Movimientos del wallet (getmovimientos)
+      responses:
+        "200": {description: Movimientos, content: {application/json: {schema: {type: array, items: {$ref: "#/components/schemas/Movement"}}}}}
+        default: {$ref: "#/components/responses/Error"}
+  /wallets/{id}/vouchers:
+    parameters: [{$ref: "#/components/parameters/Wallet"}]
+    post:
+      summary: Canjea un cupon de un solo uso (redeemvoucher)
+      requestBody: {required: true, content: {application/json: {schema: {$ref: "#/components/schemas/VoucherRequest"}}}}
+      responses:
+        "200": {$ref: "#/components/responses/Result"}
+        "202": {$ref: "#/components/responses/Accepted"}
+        default: {$ref: "#/components/responses/Error"}
+  /wallets/{id}/payments:
+    parameters: [{$ref: "#/components/parameters/Wallet"}]
+    post:
+      summary: Paga una intencion de pago leida de un QR (payintent)
+      requestBody: {required: true, content: {application/json: {schema: {$ref: "#/components/schemas/PaymentRequest"}}}}
+      responses:
+        "201": {description: Pago realizado, content: {application/json: {schema: {$ref: "#/components/schemas/PayIntentResult"}}}}
+        "202": {$ref: "#/components/responses/Accepted"}
+        default: {$ref: "#/components/responses/Error"}
+  /wallets/{id}/earn-vouchers:
+    parameters: [{$ref: "#/components/parameters/Wallet"}]
+    post:
+      summary: Acredita un voucher de acumulacion firmado por el comercio (redeemearnvoucher)
+      requestBody: {required: true, content: {application/json: {schema: {$ref: "#/components/schemas/EarnVoucherRequest"}}}}
+      responses:
+        "200": {description: Voucher acreditado, content: {application/json: {schema: {$ref: "#/components/schemas/EarnVoucherResult"}}}}
+        "202": {$ref: "#/components/responses/Accepted"}
+        default: {$ref: "#/components/responses/Error"}
+  /payments:
+    get:
+      summary: Estado de una intencion de pago (getpayintent)
+      parameters: [{name: intent, in: query, required: true, schema: {type: string, example: "LP1:M:cineplanet:25.5:R-1001:1792431158868:16FFA2D2"}}]
+      responses:
+        "200": {description: Estado del pago, content: {application/json: {schema: {$ref: "#/components/schemas/Payment"}}}}
+        default: {$ref: "#/components/responses/Error"}
+  /transfers:
+    post:
+      summary: Transfiere coins entre wallets (transfer)
+      requestBody: {required: true, content: {application/json: {schema: {$ref: "#/components/schemas/TransferRequest"}}}}
+      responses:
+        "201": {$ref: "#/components/responses/Result"}
+        "202": {$ref: "#/components/responses/Accepted"}
+        default: {$ref: "#/components/responses/Error"}
+  /merchants:
+    get:
+      summary: Comercios registrados en el wallet (getmerchants)
+      responses:
+        "200": {description: Comercios, content: {application/json: {schema: {type: array, items: {$ref: "#/components/schemas/Merchant"}}}}}
+        default: {$ref: "#/components/responses/Error"}
+  /merchants/{m}:
+    parameters: [{$ref: "#/components/parameters/Merchant"}]
+    get:
+      summary: Comercio registrado en el wallet (getmerchant)
+      responses:
+        "200": {description: Comercio, content: {application/json: {schema: {$ref: "#/components/schemas/Merchant"}}}}
+        default: {$ref: "#/components/responses/Error"}
+  /merchants/{m}/balance:
+    parameters: [{$ref: "#/components/parameters/Merchant"}]
+    get:
+      summary: Coins del comercio (gettotalcoin)
+      responses:
+        "200": {description: Saldo, content: {application/json: {schema: {$ref: "#/components/schemas/MerchantBalance"}}}}
+        default: {$ref: "#/components/responses/Error"}
+  /merchants/{m}/rates:
+    parameters: [{$ref: "#/components/parameters/Merchant"}]
+    get:
+      summary: Historial de tasas de acumulacion (getrates)
+      parameters: [{name: currency, in: query, schema: {type: string, example: PEN}}]
+      responses:
+        "200": {description: Tasas, content: {application/json: {schema: {type: array, items: {$ref: "#/components/schemas/Rate"}}}}}
+        default: {$ref: "#/components/responses/Error"}
+  /merchants/{m}/purchases:
+    parameters: [{$ref: "#/components/parameters/Merchant"}]
+    post:
+      summary: Registra una compra con canje y acumulacion (buy)
+      requestBody: {required: true, content: {application/json: {schema: {$ref: "#/components/schemas/PurchaseRequest"}}}}
+      responses:
+        "201": {description: Resultado de la compra, content: {application/json: {schema: {$ref: "#/components/schemas/BuyResult"}}}}
+        "202": {$ref: "#/components/responses/Accepted"}
+        default: {$ref: "#/components/responses/Error"}
+    get:
+      summary: Canjes y acumulaciones del comercio (getcanjes)
+      parameters:
+        - {name: wallet, in: query, schema: {type: string}}
+        - {$ref: "#/components/parameters/From"}
+        - {$ref: "#/components/parameters/To"}
+      responses:
+        "200": {description: Canjes, content: {application/json: {schema: {type: array, items: {$ref: "#/components/schemas/Canje"}}}}}
+        default: {$ref: "#/components/responses/Error"}
+  /merchants/{m}/purchases/{receipt}:
+    parameters:
+      - {$ref: "#/components/parameters/Merchant"}
+      - {name: receipt, in: path, required: true, schema: {type: string}}
+    get:
+      summary: Resultado de la compra de un recibo (getreceipt)
+      responses:
+        "200": {description: Recibo, content: {application/json: {schema: {$ref: "#/components/schemas/Receipt"}}}}
+        default: {$ref: "#/components/responses/Error"}
+  /merchants/{m}/totals:
+    parameters: [{$ref: "#/components/parameters/Merchant"}]
+    get:
+      summary: Totales por dia, semana o mes (getperiodtotals)
+      parameters:
+        - {name: period, in: query, schema: {type: string, enum: [day, week, month], default: day}}
+        - {name: from, in: query, required: true, description: Inicio en milisegundos, schema: {type: integer, format: int64}}
+        - {name: to, in: query, required: true, description: Fin en milisegundos, schema: {type: integer, format: int64}}
+      responses:
+        "200": {description: Totales, content: {application/json: {schema: {type: array, items: {$ref: "#/components/schemas/PeriodTotal"}}}}}
+        default: {$ref: "#/components/responses/Error"}
+  /merchants/{m}/rewards:
+    parameters: [{$ref: "#/components/parameters/Merchant"}]
+    get:
+      summary: Catalogo de premios (getrewards)
+      responses:
+        "200": {description: Premios, content: {application/json: {schema: {type: array, items: {$ref: "#/components/schemas/Reward"}}}}}
+        default: {$ref: "#/components/responses/Error"}
+  /merchants/{m}/rewards/{reward}/redemptions:
+    parameters:
+      - {$ref: "#/components/parameters/Merchant"}
+      - {name: reward, in: path, required: true, schema: {type: string}}
+    post:
+      summary: Canjea un premio con el hash del codigo que genero la app (redeemreward)
+      requestBody: {required: true, content: {application/json: {schema: {$ref: "#/components/schemas/RewardRedemptionRequest"}}}}
+      responses:
+        "201": {description: Canje registrado, content: {application/json: {schema: {$ref: "#/components/schemas/Redemption"}}}}
+        "202": {$ref: "#/components/responses/Accepted"}
+        default: {$ref: "#/components/responses/Error"}
+  /merchants/{m}/redemptions/{code}:
+    parameters:
+      - {$ref: "#/components/parameters/Merchant"}
+      - {$ref: "#/components/parameters/Code"}
+    get:
+      summary: Estado de un codigo de premio (getredemption)
+      responses:
+        "200": {description: Codigo del premio, content: {application/json: {schema: {$ref: "#/components/schemas/Redemption"}}}}
+        default: {$ref: "#/components/responses/Error"}
+  /merchants/{m}/redemptions/{code}/use:
+    parameters:
+      - {$ref: "#/components/parameters/Merchant"}
+      - {$ref: "#/components/parameters/Code"}
+    post:
+      summary: Marca el codigo del premio como usado (useredemption). Solo el administrador o el comercio
+      responses:
+        "200": {$ref: "#/components/responses/Result"}
+        "202": {$ref: "#/components/responses/Accepted"}
+        default: {$ref: "#/components/responses/Error"}
+components:
+  parameters:
+    Wallet: {name: id, in: path, required: true, schema: {type: string}}
+    Merchant: {name: m, in: path, required: true, description: Id del comercio en el wallet, schema: {type: string}}
+    Code: {name: code, in: path, required: true, schema: {type: string}}
+    From: {name: from, in: query, description: Inicio en milisegundos, schema: {type: integer, format: int64}}
+    To: {name: to, in: query, description: Fin en milisegundos, schema: {type: integer, format: int64}}
+  responses:
+    Error:
+      description: Error del gateway o del chaincode
+      content: {application/json: {schema: {$ref: "#/components/schemas/Error"}}}
+    Result:
+      description: Respuesta del chaincode
+      content: {application/json: {schema: {$ref: "#/components/schemas/Result"}}}
+    Accepted:
+      description: Transaccion enviada al peer, aun sin confirmar
+      content: {application/json: {schema: {$ref: "#/components/schemas/Accepted"}}}
+  schemas:
+    Error:
+      type: object
+      properties:
+        code: {type: integer, description: code del chaincode, 1 si devolvio un error}
+        status: {type: integer}
+        error: {type: string}
+    Result:
+      type: object
+      properties:
+        code: {type: integer, example: 0}
+        response: {}
+    Accepted:
+      type: object
+      properties:
+        code: {type: integer, example: 0}
+        txid: {type: string}
+    WalletRequest:
+      type: object
+      required: [id]
+      properties:
+        id: {type: string}
+        email: {type: string}
+        phone: {type: string}
+        document: {type: string}
+        password: {type: string}
+        amount: {type: number}
+    Wallet:
+      type: object
+      properties:
+        id: {type: string}
+        email: {type: string}
+        phone: {type: string}
+        document: {type: string}
+        amount: {type: number}
+        limit: {type: number}
+        tier: {type: string}
+    WalletBalance:
+      type: object
+      properties:
+        code: {type: integer}
+        balance: {type: string}
+        limit: {type: string}
+        tier: {type: string}
+        bonus: {type: string}
+    Tier:
+      type: object
+      properties:
+        code: {type: integer}
+        tier: {type: string}
+        earned: {type: string}
+        bonus: {type: string}
+        limit: {type: string}
+        transfer: {type: string}
+    Movement:
+      type: object
+      properties:
+        time: {type: integer, format: int64}
+        walletid: {type: string}
+        business: {type: string}
+        amount: {type: number}
+        balance: {type: number}
+        type: {type: string}
+        store: {type: string}
+        terminal: {type: string}
+        cashier: {type: string}
+        currency: {type: string}
+        minor: {type: integer, format: int64}
+    VoucherRequest:
+      type: object
+      required: [code]
+      properties:
+        code: {type: string}
+    PaymentRequest:
+      type: object
+      required: [intent]
+      properties:
+        intent: {type: string, description: "LP1:<tipo>:<beneficiario>:<monto>:<referencia>:<vencimiento>:<checksum>"}
+    Payment:
+      type: object
+      properties:
+        kind: {type: string, enum: [M, W]}
+        payee: {type: string}
+        amount: {type: number}
+        reference: {type: string}
+        expiry: {type: integer, format: int64}
+        status: {type: string, enum: [pending, paid, expired]}
+        payer: {type: string}
+        time: {type: integer, format: int64}
+    PayIntentResult:
+      type: object
+      properties:
+        code: {type: integer}
+        balance: {type: string}
+        payment: {$ref: "#/components/schemas/Payment"}
+    EarnVoucherRequest:
+      type: object
+      required: [voucher]
+      properties:
+        voucher: {type: string, description: "LV1:<comercio>:<id>:<coins>:<vencimiento>:<firma>"}
+    EarnVoucherResult:
+      type: object
+      properties:
+        code: {type: integer}
+        balance: {type: string}
+        voucher:
+          type: object
+          properties:
+            merchant: {type: string}
+            id: {type: string}
+            amount: {type: number}
+            expiry: {type: integer, format: int64}
+            wallet: {type: string}
+            time: {type: integer, format: int64}
+    TransferRequest:
+      type: object
+      required: [from, to, amount]
+      properties:
+        from: {type: string, description: Wallet que envia}
+        to: {type: string, description: Wallet que recibe}
+        amount: {type: number}
+    Merchant:
+      type: object
+      properties:
+        id: {type: string}
+        name: {type: string}
+        status: {type: string, enum: [active, suspended]}
+        caller: {type: string}
+    MerchantBalance:
+      type: object
+      properties:
+        business: {type: string}
+        balance: {type: number}
+        spend: {type: number}
+        sents: {type: number}
+    Rate:
+      type: object
+      properties:
+        currency: {type: string}
+        from: {type: integer, format: int64}
+        rate: {type: number}
+        settime: {type: integer, format: int64}
+    PurchaseRequest:
+      type: object
+      required: [wallet, amount]
+      properties:
+        wallet: {type: string}
+        amount:
+          description: Monto en la moneda del comercio, texto MONEDA:minor (BOB:1050) o canasta con precios en unidades menores
+          oneOf:
+            - {type: number}
+            - {type: string}
+            - {type: object}
+            - {type: array, items: {type: object}}
+        coins: {type: number, description: Coins a canjear}
+        store: {type: string}
+        terminal: {type: string}
+        cashier: {type: string}
+        receipt: {type: string, description: Numero de recibo del POS para reintentos idempotentes}
+    BuyResult:
+      type: object
+      properties:
+        code: {type: integer}
+        balance: {type: string}
+        limit: {type: string}
+        tier: {type: string}
+        redeemed: {type: string}
+        earned: {type: string}
+        currency: {type: string}
+        minor: {type: integer, format: int64}
+        lines: {type: array, items: {type: object}}
+    Receipt:
+      type: object
+      properties:
+        code: {type: integer}
+        receipt: {type: string}
+        time: {type: integer, format: int64}
+        result: {$ref: "#/components/schemas/BuyResult"}
+    Canje:
+      type: object
+      properties:
+        time: {type: integer, format: int64}
+        walletid: {type: string}
+        amount: {type: number}
+        type: {type: string}
+        detail: {type: string}
+        store: {type: string}
+        terminal: {type: string}
+        cashier: {type: string}
+        wallet: {type: string}
+        soles: {type: number}
+        reference: {type: string}
+        currency: {type: string}
+        minor: {type: integer, format: int64}
+    PeriodTotal:
+      type: object
+      properties:
+        start: {type: integer, format: int64}
+        redeemed: {type: number}
+        earned: {type: number}
+        redemptions: {type: integer}
+        earnings: {type: integer}
+        averageredeem: {type: number}
+    Reward:
+      type: object
+      properties:
+        id: {type: string}
+        name: {type: string}
+        price: {type: number}
+        stock: {type: integer, format: int64}
+        validfrom: {type: integer, format: int64}
+        validto: {type: integer, format: int64}
+    RewardRedemptionRequest:
+      type: object
+      required: [wallet, hash]
+      properties:
+        wallet: {type: string}
+        hash: {type: string, description: sha256 en hexadecimal del codigo en mayusculas; el codigo no se envia}
+        store: {type: string}
+        terminal: {type: string}
+        cashier: {type: string}
+    Redemption:
+      type: object
+      properties:
+        hash: {type: string}
+        reward: {type: string}
+        wallet: {type: string}
+        price: {type: number}
+        time: {type: integer, format: int64}
+        used: {type: integer, format: int64}
+`
@@ -0,0 +1,296 @@
+/*
+* Adrian Pareja
+ */
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+//WalletRequest - Structure for POST /wallets
+type WalletRequest struct {
+	Id       string  `json:"id"`
+	Email    string  `json:"email"`
+	Phone    string  `json:"phone"`
+	Document string  `json:"document"`
+	Password string  `json:"password"`
+	Amount   float64 `json:"amount"`
+}
+
+//TransferRequest - Structure for POST /transfers
+type TransferRequest struct {
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Amount float64 `json:"amount"`
+}
+
+//VoucherRequest - Structure for POST /wallets/{id}/vouchers
+type VoucherRequest struct {
+	Code string `json:"code"`
+}
+
+//PaymentRequest - Structure for POST /wallets/{id}/payments. Intent es el texto del QR
+type PaymentRequest struct {
+	Intent string `json:"intent"`
+}
+
+//EarnVoucherRequest - Structure for POST /wallets/{id}/earn-vouchers. Voucher es el texto firmado por el comercio
+type EarnVoucherRequest struct {
+	Voucher string `json:"voucher"`
+}
+
+//PurchaseRequest - Structure for POST /merchants/{m}/purchases. Amount es un monto, un texto
+//"MONEDA:minor" o una canasta, igual que el argumento de buy
+type PurchaseRequest struct {
+	Wallet   string          `json:"wallet"`
+	Amount   json.RawMessage `json:"amount"`
+	Coins    float64         `json:"coins"`
+	Store    string          `json:"store,omitempty"`
+	Terminal string          `json:"terminal,omitempty"`
+	Cashier  string          `json:"cashier,omitempty"`
+	Receipt  string          `json:"receipt,omitempty"`
+}
+
+//RewardRedemptionRequest - Structure for POST /merchants/{m}/rewards/{reward}/redemptions
+type RewardRedemptionRequest struct {
+	Wallet   string `json:"wallet"`
+	Hash     string `json:"hash"`
+	Store    string `json:"store,omitempty"`
+	Terminal string `json:"terminal,omitempty"`
+	Cashier  string `json:"cashier,omitempty"`
+}
+
+//walletPrivate - Campos del wallet que no devuelve el gateway
+var walletPrivate = []string{"password"}
+
+func decode(r *http.Request, value interface{}) *apiError {
+	if r.Body == nil {
+		return badRequest("Se esperaba un cuerpo JSON")
+	}
+	err := json.NewDecoder(r.Body).Decode(value)
+	if err != nil {
+		return badRequest("Cuerpo JSON invalido. " + err.Error())
+	}
+	return nil
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+//period - Parametros from y to de la consulta, ambos o ninguno salvo que sean obligatorios
+func period(r *http.Request, required bool) ([]string, *apiError) {
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	if from == "" && to == "" && !required {
+		return nil, nil
+	}
+	if from == "" || to == "" {
+		return nil, badRequest("Se esperan from y to en milisegundos")
+	}
+	return []string{from, to}, nil
+}
+
+func listWallets(r *http.Request, params map[string]string) (*call, *apiError) {
+	return &call{function: "getwallets", args: []string{""}, omit: walletPrivate}, nil
+}
+
+func createWallet(r *http.Request, params map[string]string) (*call, *apiError) {
+	request := WalletRequest{}
+	if err := decode(r, &request); err != nil {
+		return nil, err
+	}
+	if request.Id == "" {
+		return nil, badRequest("El id del wallet es obligatorio")
+	}
+	args := []string{request.Id, request.Email, request.Phone, request.Document, request.Password, formatAmount(request.Amount)}
+	return &call{invoke: true, function: "createwallet", args: args, created: true}, nil
+}
+
+func getWallet(r *http.Request, params map[string]string) (*call, *apiError) {
+	return &call{function: "getdatos", args: []string{params["id"]}, omit: walletPrivate}, nil
+}
+
+func getWalletBalance(r *http.Request, params map[string]string) (*call, *apiError) {
+	return &call{function: "getbalance", args: []string{params["id"]}}, nil
+}
+
+func getWalletTier(r *http.Request, params map[string]string) (*call, *apiError) {
+	return &call{function: "gettier", args: []string{params["id"]}}, nil
+}
+
+func getWalletMovements(r *http.Request, params map[string]string) (*call, *apiError) {
+	return &call{function: "getmovimientos", args: []string{"", params["id"]}}, nil
+}
+
+func redeemVoucher(r *http.Request, params map[string]string) (*call, *apiError) {
+	request := VoucherRequest{}
+	if err := decode(r, &request); err != nil {
+		return nil, err
+	}
+	if request.Code == "" {
+		return nil, badRequest("El codigo del cupon es obligatorio")
+	}
+	return &call{invoke: true, function: "redeemvoucher", args: []string{params["id"], request.Code}}, nil
+}
+
+func payIntent(r *http.Request, params map[string]string) (*call, *apiError) {
+	request := PaymentRequest{}
+	if err := decode(r, &request); err != nil {
+		return nil, err
+	}
+	if request.Intent == "" {
+		return nil, badRequest("La intencion de pago es obligatoria")
+	}
+	return &call{invoke: true, function: "payintent", args: []string{params["id"], request.Intent}, created: true}, nil
+}
+
+func redeemEarnVoucher(r *http.Request, params map[string]string) (*call, *apiError) {
+	request := EarnVoucherRequest{}
+	if err := decode(r, &request); err != nil {
+		return nil, err
+	}
+	if request.Voucher == "" {
+		return nil, badRequest("El voucher es obligatorio")
+	}
+	return &call{invoke: true, function: "redeemearnvoucher", args: []string{params["id"], request.Voucher}}, nil
+}
+
+//getPayIntent - La intencion va en la consulta porque su texto lleva ":" y "%"
+func getPayIntent(r *http.Request, params map[string]string) (*call, *apiError) {
+	intent := r.URL.Query().Get("intent")
+	if intent == "" {
+		return nil, badRequest("Se espera intent con el texto del QR")
+	}
+	return &call{function: "getpayintent", args: []string{intent}}, nil
+}
+
+//createTransfer - transfer recibe primero el receptor y luego el emisor
+func createTransfer(r *http.Request, params map[string]string) (*call, *apiError) {
+	request := TransferRequest{}
+	if err := decode(r, &request); err != nil {
+		return nil, err
+	}
+	if request.From == "" || request.To == "" {
+		return nil, badRequest("Se esperan from y to")
+	}
+	return &call{invoke: true, function: "transfer", args: []string{request.To, request.From, formatAmount(request.Amount)}, created: true}, nil
+}
+
+func listMerchants(r *http.Request, params map[string]string) (*call, *apiError) {
+	return &call{function: "getmerchants"}, nil
+}
+
+func getMerchant(r *http.Request, params map[string]string) (*call, *apiError) {
+	return &call{function: "getmerchant", args: []string{params["m"]}}, nil
+}
+
+func getMerchantBalance(r *http.Request, params map[string]string) (*call, *apiError) {
+	return &call{merchant: params["m"], function: "gettotalcoin"}, nil
+}
+
+func getMerchantRates(r *http.Request, params map[string]string) (*call, *apiError) {
+	args := []string{}
+	if currency := r.URL.Query().Get("currency"); currency != "" {
+		args = append(args, currency)
+	}
+	return &call{merchant: params["m"], function: "getrates", args: args}, nil
+}
+
+//purchaseArgument - El monto como lo recibe buy: el texto tal cual, o el numero o la canasta en JSON
+func purchaseArgument(raw json.RawMessage) (string, *apiError) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", badRequest("El monto de la compra es obligatorio")
+	}
+	if raw[0] == '"' {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return "", badRequest("Monto de compra invalido")
+		}
+		return text, nil
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return "", badRequest("Monto de compra invalido")
+	}
+	return compact.String(), nil
+}
+
+func createPurchase(r *http.Request, params map[string]string) (*call, *apiError) {
+	request := PurchaseRequest{}
+	if err := decode(r, &request); err != nil {
+		return nil, err
+	}
+	if request.Wallet == "" {
+		return nil, badRequest("El wallet es obligatorio")
+	}
+	amount, err := purchaseArgument(request.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{request.Wallet, amount, formatAmount(request.Coins)}
+	if request.Store != "" || request.Terminal != "" || request.Cashier != "" {
+		args = append(args, request.Store, request.Terminal, request.Cashier)
+	}
+	if request.Receipt != "" {
+		args = append(args, request.Receipt)
+	}
+	return &call{merchant: params["m"], invoke: true, function: "buy", args: args, created: true}, nil
+}
+
+func listPurchases(r *http.Request, params map[string]string) (*call, *apiError) {
+	dates, err := period(r, false)
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{r.URL.Query().Get("wallet")}, dates...)
+	return &call{merchant: params["m"], function: "getcanjes", args: args}, nil
+}
+
+func getPurchase(r *http.Request, params map[string]string) (*call, *apiError) {
+	return &call{merchant: params["m"], function: "getreceipt", args: []string{params["receipt"]}}, nil
+}
+
+func getMerchantTotals(r *http.Request, params map[string]string) (*call, *apiError) {
+	dates, err := period(r, true)
+	if err != nil {
+		return nil, err
+	}
+	bucket := r.URL.Query().Get("period")
+	if bucket == "" {
+		bucket = "day"
+	}
+	return &call{merchant: params["m"], function: "getperiodtotals", args: append([]string{bucket}, dates...)}, nil
+}
+
+func listRewards(r *http.Request, params map[string]string) (*call, *apiError) {
+	return &call{merchant: params["m"], function: "getrewards"}, nil
+}
+
+func redeemReward(r *http.Request, params map[string]string) (*call, *apiError) {
+	request := RewardRedemptionRequest{}
+	if err := decode(r, &request); err != nil {
+		return nil, err
+	}
+	if request.Wallet == "" || request.Hash == "" {
+		return nil, badRequest("El wallet y el hash del codigo son obligatorios")
+	}
+	args := []string{request.Wallet, params["reward"], request.Hash}
+	if request.Store != "" || request.Terminal != "" || request.Cashier != "" {
+		args = append(args, request.Store, request.Terminal, request.Cashier)
+	}
+	return &call{merchant: params["m"], invoke: true, function: "redeemreward", args: args, created: true}, nil
+}
+
+func getRedemption(r *http.Request, params map[string]string) (*call, *apiError) {
+	return &call{merchant: params["m"], function: "getredemption", args: []string{params["code"]}}, nil
+}
+
+func useRedemption(r *http.Request, params map[string]string) (*call, *apiError) {
+	return &call{merchant: params["m"], invoke: true, function: "useredemption", args: []string{params["code"]}}, nil
+}
@@ -0,0 +1,201 @@
+/*
+* Adrian Pareja
+ */
+
+//Package gateway expone por HTTP las funciones de los chaincodes wallet y merchant para la app y los POS.
+//Cada recurso se traduce a un invoke o query sobre un client.Transport.
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ccamaleon5/blockchain/client"
+)
+
+//Server - HTTP handler del gateway. Merchants relaciona el id del comercio con el id de su chaincode
+type Server struct {
+	Transport client.Transport
+	Wallet    string
+	Merchants map[string]string
+}
+
+//NewServer - Crea el gateway sobre un transport
+func NewServer(transport client.Transport, wallet string, merchants map[string]string) *Server {
+	return &Server{Transport: transport, Wallet: wallet, Merchants: merchants}
+}
+
+//call - Llamada a un chaincode resultado de una peticion
+type call struct {
+	merchant string
+	invoke   bool
+	function string
+	args     []string
+	created  bool
+	//omit son campos que no se exponen por HTTP, como el password del wallet
+	omit []string
+}
+
+//handler - Traduce una peticion a una llamada; params son los segmentos variables de la ruta
+type handler func(r *http.Request, params map[string]string) (*call, *apiError)
+
+type route struct {
+	method  string
+	pattern []string
+	handle  handler
+}
+
+var routes = []route{
+	{"GET", []string{"wallets"}, listWallets},
+	{"POST", []string{"wallets"}, createWallet},
+	{"GET", []string{"wallets", "{id}"}, getWallet},
+	{"GET", []string{"wallets", "{id}", "balance"}, getWalletBalance},
+	{"GET", []string{"wallets", "{id}", "tier"}, getWalletTier},
+	{"GET", []string{"wallets", "{id}", "movements"}, getWalletMovements},
+	{"POST", []string{"wallets", "{id}", "vouchers"}, redeemVoucher},
+	{"POST", []string{"wallets", "{id}", "payments"}, payIntent},
+	{"POST", []string{"wallets", "{id}", "earn-vouchers"}, redeemEarnVoucher},
+	{"GET", []string{"payments"}, getPayIntent},
+	{"POST", []string{"transfers"}, createTransfer},
+	{"GET", []string{"merchants"}, listMerchants},
+	{"GET", []string{"merchants", "{m}"}, getMerchant},
+	{"GET", []string{"merchants", "{m}", "balance"}, getMerchantBalance},
+	{"GET", []string{"merchants", "{m}", "rates"}, getMerchantRates},
+	{"POST", []string{"merchants", "{m}", "purchases"}, createPurchase},
+	{"GET", []string{"merchants", "{m}", "purchases"}, listPurchases},
+	{"GET", []string{"merchants", "{m}", "purchases", "{receipt}"}, getPurchase},
+	{"GET", []string{"merchants", "{m}", "totals"}, getMerchantTotals},
+	{"GET", []string{"merchants", "{m}", "rewards"}, listRewards},
+	{"POST", []string{"merchants", "{m}", "rewards", "{reward}", "redemptions"}, redeemReward},
+	{"GET", []string{"merchants", "{m}", "redemptions", "{code}"}, getRedemption},
+	{"POST", []string{"merchants", "{m}", "redemptions", "{code}", "use"}, useRedemption},
+}
+
+//match - Compara la ruta con el patron y devuelve los segmentos variables
+func match(pattern []string, parts []string) (map[string]string, bool) {
+	if len(pattern) != len(parts) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, segment := range pattern {
+		if strings.HasPrefix(segment, "{") {
+			if parts[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(segment, "{}")] = parts[i]
+		} else if segment != parts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+//ServeHTTP - Enruta la peticion, llama al chaincode y traduce la respuesta
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "openapi.yaml" && r.Method == "GET" {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write([]byte(OpenAPI))
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	pathFound := false
+	for _, route := range routes {
+		params, ok := match(route.pattern, parts)
+		if !ok {
+			continue
+		}
+		pathFound = true
+		if route.method != r.Method {
+			continue
+		}
+		c, err := route.handle(r, params)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		s.execute(w, c)
+		return
+	}
+
+	if pathFound {
+		writeError(w, &apiError{code: 1, status: http.StatusMethodNotAllowed, message: "Metodo no permitido: " + r.Method})
+		return
+	}
+	writeError(w, &apiError{code: 1, status: http.StatusNotFound, message: "Recurso desconocido: /" + path})
+}
+
+//execute - Llama al chaincode. Una respuesta con code distinto de 0 es un error, y una respuesta que no es
+//JSON es el id de transaccion que devuelve un peer para un invoke, que aun no esta confirmado (202)
+func (s *Server) execute(w http.ResponseWriter, c *call) {
+	chaincode := s.Wallet
+	if c.merchant != "" {
+		id, found := s.Merchants[c.merchant]
+		if !found {
+			writeError(w, &apiError{code: 1, status: http.StatusNotFound, message: "Comercio desconocido: " + c.merchant})
+			return
+		}
+		chaincode = id
+	}
+
+	var response []byte
+	var err error
+	if c.invoke {
+		response, err = s.Transport.Invoke(chaincode, c.function, c.args...)
+	} else {
+		response, err = s.Transport.Query(chaincode, c.function, c.args...)
+	}
+	if err != nil {
+		writeError(w, chaincodeError(c.function, err))
+		return
+	}
+
+	var value interface{}
+	if json.Unmarshal(response, &value) != nil {
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{"code": 0, "txid": strings.TrimSpace(string(response))})
+		return
+	}
+	if object, ok := value.(map[string]interface{}); ok {
+		if code, ok := object["code"].(float64); ok && code != 0 {
+			message, _ := object["response"].(string)
+			if message == "" {
+				message, _ = object["error"].(string)
+			}
+			if message == "" {
+				message = "El chaincode respondio con code distinto de 0"
+			}
+			writeJSON(w, http.StatusBadGateway, ErrorResponse{Code: int(code), Status: http.StatusBadGateway, Error: message})
+			return
+		}
+	}
+
+	status := http.StatusOK
+	if c.created {
+		status = http.StatusCreated
+	}
+	if len(c.omit) > 0 {
+		writeJSON(w, status, omitFields(value, c.omit))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(bytes.TrimSpace(response))
+}
+
+//omitFields - Quita los campos de un objeto o de los objetos de una lista
+func omitFields(value interface{}, fields []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, field := range fields {
+			delete(v, field)
+		}
+	case []interface{}:
+		for _, item := range v {
+			omitFields(item, fields)
+		}
+	}
+	return value
+}
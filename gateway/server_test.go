@@ -0,0 +1,151 @@
+/*
+* Adrian Pareja
+ */
+package gateway_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ccamaleon5/blockchain/client"
+	"github.com/ccamaleon5/blockchain/gateway"
+)
+
+//newServer - Gateway sobre un FakeTransport con el wallet y el comercio cineplanet desplegado como cine
+func newServer(t *testing.T) (*httptest.Server, *client.FakeTransport) {
+	fake := client.NewFakeTransport()
+	server := httptest.NewServer(gateway.NewServer(fake, "wallet", map[string]string{"cineplanet": "cine"}))
+	t.Cleanup(server.Close)
+	return server, fake
+}
+
+//do - Envia la peticion y devuelve el status y el cuerpo
+func do(t *testing.T, server *httptest.Server, method string, path string, body string) (int, string) {
+	t.Helper()
+	request, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := server.Client().Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	bytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response.StatusCode, string(bytes)
+}
+
+func TestRouting(t *testing.T) {
+	cases := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		call   client.FakeCall
+	}{
+		{"GET", "/wallets/w1/balance", "", http.StatusOK, client.FakeCall{Kind: "query", Chaincode: "wallet", Function: "getbalance", Args: []string{"w1"}}},
+		{"GET", "/wallets/w1/movements", "", http.StatusOK, client.FakeCall{Kind: "query", Chaincode: "wallet", Function: "getmovimientos", Args: []string{"", "w1"}}},
+		{"POST", "/transfers", `{"from":"w1","to":"w2","amount":10}`, http.StatusCreated, client.FakeCall{Kind: "invoke", Chaincode: "wallet", Function: "transfer", Args: []string{"w2", "w1", "10"}}},
+		{"POST", "/merchants/cineplanet/purchases", `{"wallet":"w1","amount":{"currency":"PEN","lines":[{"price":1050}]},"coins":5,"receipt":"R-1"}`, http.StatusCreated,
+			client.FakeCall{Kind: "invoke", Chaincode: "cine", Function: "buy", Args: []string{"w1", `{"currency":"PEN","lines":[{"price":1050}]}`, "5", "R-1"}}},
+		{"GET", "/merchants/cineplanet/purchases/R-1", "", http.StatusOK, client.FakeCall{Kind: "query", Chaincode: "cine", Function: "getreceipt", Args: []string{"R-1"}}},
+		{"POST", "/merchants/cineplanet/rewards/entrada/redemptions", `{"wallet":"w1","hash":"ab12"}`, http.StatusCreated,
+			client.FakeCall{Kind: "invoke", Chaincode: "cine", Function: "redeemreward", Args: []string{"w1", "entrada", "ab12"}}},
+		{"POST", "/merchants/cineplanet/redemptions/ABC/use", "", http.StatusOK, client.FakeCall{Kind: "invoke", Chaincode: "cine", Function: "useredemption", Args: []string{"ABC"}}},
+	}
+	for _, c := range cases {
+		server, fake := newServer(t)
+		fake.Respond(c.call.Chaincode, c.call.Function, `{"code":0,"response":null}`)
+
+		status, body := do(t, server, c.method, c.path, c.body)
+		if status != c.status {
+			t.Fatalf("%s %s: status %d, se esperaba %d (%s)", c.method, c.path, status, c.status, body)
+		}
+		if len(fake.Calls) != 1 || !reflect.DeepEqual(fake.Calls[0], c.call) {
+			t.Fatalf("%s %s: llamada %+v, se esperaba %+v", c.method, c.path, fake.Calls, c.call)
+		}
+	}
+}
+
+func TestNotFoundAndMethodNotAllowed(t *testing.T) {
+	server, fake := newServer(t)
+
+	if status, body := do(t, server, "GET", "/nada", ""); status != http.StatusNotFound || !strings.Contains(body, "Recurso desconocido") {
+		t.Fatalf("ruta desconocida: %d %s", status, body)
+	}
+	//La ruta existe con otro metodo
+	if status, body := do(t, server, "DELETE", "/wallets/w1", ""); status != http.StatusMethodNotAllowed || !strings.Contains(body, `"status":405`) {
+		t.Fatalf("metodo no permitido: %d %s", status, body)
+	}
+	if status, body := do(t, server, "GET", "/merchants/otro/balance", ""); status != http.StatusNotFound || !strings.Contains(body, "Comercio desconocido: otro") {
+		t.Fatalf("comercio sin chaincode: %d %s", status, body)
+	}
+	if status, _ := do(t, server, "POST", "/merchants/cineplanet/purchases", `{"amount":10}`); status != http.StatusBadRequest {
+		t.Fatalf("compra sin wallet: %d", status)
+	}
+	if len(fake.Calls) != 0 {
+		t.Fatalf("una peticion rechazada llego al chaincode: %+v", fake.Calls)
+	}
+}
+
+//Los mensajes son los que devuelven los chaincodes, con los prefijos que agregan el peer e InvokeChaincode
+func TestChaincodeErrorStatus(t *testing.T) {
+	cases := []struct {
+		message string
+		status  int
+	}{
+		{"Solo el administrador puede devolver coins al pool central", http.StatusForbidden},
+		{"No autorizado para entregar premios del comercio cineplanet", http.StatusForbidden},
+		{"Wallet desconocido: w9", http.StatusNotFound},
+		{"Failed to invoke chaincode. Got error: Error retrieving balance", http.StatusNotFound},
+		{"El cliente no cuenta con coins suficientes", http.StatusConflict},
+		{"El voucher ya fue canjeado: R-1", http.StatusConflict},
+		{"El wallet alcanzo el tope de su limite, disponible: 10.000000", http.StatusConflict},
+		{"Error: Monto invalido: NaN", http.StatusBadRequest},
+		{"Numero incorrecto de argumentos.Se espera 2 para payIntent", http.StatusBadRequest},
+		{"connection refused", http.StatusBadGateway},
+	}
+	for _, c := range cases {
+		server, fake := newServer(t)
+		fake.Fail("wallet", "transfer", c.message)
+
+		status, body := do(t, server, "POST", "/transfers", `{"from":"w1","to":"w2","amount":10}`)
+		if status != c.status {
+			t.Fatalf("%q: status %d, se esperaba %d", c.message, status, c.status)
+		}
+		if !strings.Contains(body, `"code":1`) || strings.Contains(body, "Failed to invoke") || strings.Contains(body, `"error":"Error:`) {
+			t.Fatalf("%q: cuerpo incorrecto %s", c.message, body)
+		}
+	}
+
+	//Una respuesta con code distinto de 0 es una falla del chaincode
+	server, fake := newServer(t)
+	fake.Respond("wallet", "getbalance", `{"code":3,"response":"Wallet bloqueado"}`)
+	if status, body := do(t, server, "GET", "/wallets/w1/balance", ""); status != http.StatusBadGateway || !strings.Contains(body, `"code":3`) || !strings.Contains(body, "Wallet bloqueado") {
+		t.Fatalf("code distinto de 0: %d %s", status, body)
+	}
+}
+
+func TestInvokeTxID(t *testing.T) {
+	server, fake := newServer(t)
+	//Un peer responde a un invoke con el id de transaccion, sin el resultado
+	fake.Respond("wallet", "createwallet", "5f0c2a1e-txid\n")
+
+	status, body := do(t, server, "POST", "/wallets", `{"id":"w1","email":"w1@mail.com","phone":"999","document":"123","password":"pw"}`)
+	if status != http.StatusAccepted || body != `{"code":0,"txid":"5f0c2a1e-txid"}` {
+		t.Fatalf("invoke sin confirmar: %d %s", status, body)
+	}
+
+	//El gateway no devuelve el password del wallet
+	fake.Respond("wallet", "getdatos", `{"id":"w1","password":"pw","amount":10}`)
+	if status, body = do(t, server, "GET", "/wallets/w1", ""); status != http.StatusOK || strings.Contains(body, "password") {
+		t.Fatalf("wallet: %d %s", status, body)
+	}
+}
@@ -0,0 +1,20 @@
+/*
+  Merchant Smart Contract
+  Adrian Pareja
+*/
+package main
+
+import (
+	"fmt"
+
+	"github.com/ccamaleon5/blockchain/chaincode/merchant"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+func main() {
+	fmt.Printf("Iniciandooo Contrato Merchant....")
+	err := shim.Start(new(merchant.SimpleChaincode))
+	if err != nil {
+		fmt.Printf("Error Iniciando Merchant Smart Contract: %s", err)
+	}
+}
@@ -0,0 +1,19 @@
+/*
+* Adrian Pareja
+ */
+package main
+
+import (
+	"fmt"
+
+	"github.com/ccamaleon5/blockchain/chaincode/wallet"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+func main() {
+	fmt.Printf("Iniciandooo Contrato Wallet....")
+	err := shim.Start(new(wallet.SimpleChaincode))
+	if err != nil {
+		fmt.Printf("Error Iniciando Wallet Smart Contract: %s", err)
+	}
+}
@@ -0,0 +1,118 @@
+/*
+* Adrian Pareja
+ */
+
+//Package notifier envia webhooks firmados a comercios y apps cuando el chaincode wallet crea un wallet o
+//cambia saldos. Las entregas pasan por un outbox persistente y se reintentan con backoff.
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+//Eventos del chaincode wallet que generan notificaciones
+const (
+	eventCreateWallet = "createWallet"
+	eventBalance      = "balanceEvent"
+)
+
+//Tipos de notificacion
+const (
+	TypeWalletCreated = "wallet.created"
+	TypeCoinsReceived = "coins.received"
+	TypeCoinsSpent    = "coins.spent"
+)
+
+//Event - Structure for a chaincode event as delivered by the peer
+type Event struct {
+	TxID      string `json:"txid"`
+	Chaincode string `json:"chaincode"`
+	Name      string `json:"name"`
+	Payload   string `json:"payload"`
+}
+
+//Notification - Structure for the body of a webhook. Id es unico por movimiento y sirve para descartar
+//entregas repetidas
+type Notification struct {
+	Id        string  `json:"id"`
+	Type      string  `json:"type"`
+	Wallet    string  `json:"wallet"`
+	Business  string  `json:"business,omitempty"`
+	Amount    float64 `json:"amount,omitempty"`
+	Balance   float64 `json:"balance"`
+	Movement  string  `json:"movement,omitempty"`
+	Store     string  `json:"store,omitempty"`
+	Terminal  string  `json:"terminal,omitempty"`
+	Cashier   string  `json:"cashier,omitempty"`
+	Currency  string  `json:"currency,omitempty"`
+	Minor     int64   `json:"minor,omitempty"`
+	Time      int64   `json:"time,omitempty"`
+	TxID      string  `json:"txid"`
+	Chaincode string  `json:"chaincode"`
+}
+
+//movement - Movimiento en el payload de balanceEvent
+type movement struct {
+	Time     int64   `json:"time"`
+	WalletId string  `json:"walletid"`
+	Business string  `json:"business"`
+	Amount   float64 `json:"amount"`
+	Balance  float64 `json:"balance"`
+	Type     string  `json:"type"`
+	Store    string  `json:"store"`
+	Terminal string  `json:"terminal"`
+	Cashier  string  `json:"cashier"`
+	Currency string  `json:"currency"`
+	Minor    int64   `json:"minor"`
+}
+
+type balanceEvent struct {
+	Movements []movement `json:"movements"`
+}
+
+//Notifications - Notificaciones de un evento. Los eventos que no interesan no generan ninguna
+func Notifications(event Event) ([]Notification, error) {
+	switch event.Name {
+	case eventCreateWallet:
+		//createWallet:OK:<wallet>
+		parts := strings.SplitN(event.Payload, ":", 3)
+		if len(parts) != 3 || parts[2] == "" {
+			return nil, nil
+		}
+		return []Notification{{Id: event.TxID + ":0", Type: TypeWalletCreated, Wallet: parts[2], TxID: event.TxID, Chaincode: event.Chaincode}}, nil
+	case eventBalance:
+		payload := balanceEvent{}
+		err := json.Unmarshal([]byte(event.Payload), &payload)
+		if err != nil {
+			return nil, fmt.Errorf("Evento %s invalido en la transaccion %s. %s", event.Name, event.TxID, err)
+		}
+		notifications := []Notification{}
+		for i, m := range payload.Movements {
+			kind := TypeCoinsReceived
+			if m.Type == "D" {
+				kind = TypeCoinsSpent
+			}
+			notifications = append(notifications, Notification{
+				Id:        fmt.Sprintf("%s:%d", event.TxID, i),
+				Type:      kind,
+				Wallet:    m.WalletId,
+				Business:  m.Business,
+				Amount:    m.Amount,
+				Balance:   m.Balance,
+				Movement:  m.Type,
+				Store:     m.Store,
+				Terminal:  m.Terminal,
+				Cashier:   m.Cashier,
+				Currency:  m.Currency,
+				Minor:     m.Minor,
+				Time:      m.Time,
+				TxID:      event.TxID,
+				Chaincode: event.Chaincode,
+			})
+		}
+		return notifications, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,133 @@
+/*
+* Adrian Pareja
+ */
+
+//Package eventhub escucha los eventos de chaincode en el event hub del peer y los agrega, un evento JSON por
+//linea, al archivo que lee notifier.FileSource. Usa el cliente de eventos de fabric, por eso esta fuera del
+//paquete notifier
+package eventhub
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ccamaleon5/blockchain/notifier"
+	"github.com/hyperledger/fabric/events/consumer"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+//Listener - Escucha los eventos de Chaincodes en el event hub Address (por ejemplo localhost:7053) y los agrega
+//al archivo Path. El event hub no reenvia lo emitido mientras no hay conexion
+type Listener struct {
+	Address    string
+	Chaincodes []string
+	Path       string
+	Timeout    time.Duration
+
+	mu           sync.Mutex
+	file         *os.File
+	disconnected chan error
+}
+
+//GetInterestedEvents - Todos los eventos de cada chaincode configurado
+func (l *Listener) GetInterestedEvents() ([]*pb.Interest, error) {
+	if len(l.Chaincodes) == 0 {
+		return nil, errors.New("No hay chaincodes que escuchar")
+	}
+	interests := []*pb.Interest{}
+	for _, chaincode := range l.Chaincodes {
+		interests = append(interests, &pb.Interest{
+			EventType: pb.EventType_CHAINCODE,
+			RegInfo:   &pb.Interest_ChaincodeRegInfo{ChaincodeRegInfo: &pb.ChaincodeReg{ChaincodeID: chaincode, EventName: ""}},
+		})
+	}
+	return interests, nil
+}
+
+//Recv - Agrega al archivo cada evento de chaincode recibido
+func (l *Listener) Recv(msg *pb.Event) (bool, error) {
+	event, ok := msg.Event.(*pb.Event_ChaincodeEvent)
+	if !ok || event.ChaincodeEvent == nil {
+		return true, nil
+	}
+	e := event.ChaincodeEvent
+	err := l.write(notifier.Event{TxID: e.TxID, Chaincode: e.ChaincodeID, Name: e.EventName, Payload: string(e.Payload)})
+	if err != nil {
+		fmt.Println("Error guardando el evento " + e.TxID + ": " + err.Error())
+		return false, err
+	}
+	return true, nil
+}
+
+//Disconnected - Termina Run para que quien lo llama reconecte
+func (l *Listener) Disconnected(err error) {
+	if err == nil {
+		err = errors.New("Desconectado del event hub " + l.Address)
+	}
+	select {
+	case l.disconnected <- err:
+	default:
+	}
+}
+
+//write - Agrega el evento como una linea con una sola escritura, asi FileSource nunca lee media linea como completa
+func (l *Listener) write(event notifier.Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		l.file, err = os.OpenFile(l.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = l.file.Write(append(line, '\n'))
+	if err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+//Run - Se conecta al event hub y escucha hasta que se cierre stop, o devuelve error si se pierde la conexion
+func (l *Listener) Run(stop <-chan struct{}) error {
+	timeout := l.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	l.disconnected = make(chan error, 1)
+	defer l.close()
+
+	client, err := consumer.NewEventsClient(l.Address, timeout, l)
+	if err != nil {
+		return err
+	}
+	err = client.Start()
+	if err != nil {
+		client.Stop()
+		return fmt.Errorf("No se pudo conectar al event hub %s. %s", l.Address, err)
+	}
+	defer client.Stop()
+
+	select {
+	case <-stop:
+		return nil
+	case err = <-l.disconnected:
+		return err
+	}
+}
+
+func (l *Listener) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+}
@@ -0,0 +1,48 @@
+/*
+* Adrian Pareja
+ */
+package eventhub_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/ccamaleon5/blockchain/notifier"
+	"github.com/ccamaleon5/blockchain/notifier/eventhub"
+	pb "github.com/hyperledger/fabric/protos"
+)
+
+//El archivo que escribe el listener es el que lee FileSource
+func TestListenerWritesFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	listener := &eventhub.Listener{Chaincodes: []string{"wallet"}, Path: path}
+
+	interests, err := listener.GetInterestedEvents()
+	if err != nil || len(interests) != 1 || interests[0].EventType != pb.EventType_CHAINCODE {
+		t.Fatalf("interes incorrecto: %v %v", interests, err)
+	}
+
+	for _, txid := range []string{"tx1", "tx2"} {
+		ok, err := listener.Recv(&pb.Event{Event: &pb.Event_ChaincodeEvent{ChaincodeEvent: &pb.ChaincodeEvent{
+			ChaincodeID: "wallet",
+			TxID:        txid,
+			EventName:   "createWallet",
+			Payload:     []byte("createWallet:OK:" + txid),
+		}}})
+		if !ok || err != nil {
+			t.Fatalf("Recv %s: %v", txid, err)
+		}
+	}
+
+	source := &notifier.FileSource{Path: path}
+	events, cursor, err := source.Poll("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[1].TxID != "tx2" || events[1].Chaincode != "wallet" || events[1].Payload != "createWallet:OK:tx2" {
+		t.Fatalf("eventos incorrectos: %+v", events)
+	}
+	if events, _, _ = source.Poll(cursor); len(events) != 0 {
+		t.Fatalf("el cursor no avanzo: %+v", events)
+	}
+}
@@ -0,0 +1,276 @@
+/*
+* Adrian Pareja
+ */
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//Cabeceras de cada webhook
+const (
+	HeaderId        = "X-Loyalty-Id"
+	HeaderEvent     = "X-Loyalty-Event"
+	HeaderTimestamp = "X-Loyalty-Timestamp"
+	HeaderSignature = "X-Loyalty-Signature"
+)
+
+//Endpoint - Structure for a webhook receiver. Con Merchants recibe solo los movimientos de esos comercios
+//(endpoint de comercio); sin Merchants recibe todos (endpoint de app). Types filtra por tipo de notificacion
+type Endpoint struct {
+	Name      string   `json:"name"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret"`
+	Merchants []string `json:"merchants,omitempty"`
+	Types     []string `json:"types,omitempty"`
+}
+
+//Accepts - Indica si la notificacion va a este endpoint
+func (e Endpoint) Accepts(notification Notification) bool {
+	return (len(e.Merchants) == 0 || contains(e.Merchants, notification.Business)) &&
+		(len(e.Types) == 0 || contains(e.Types, notification.Type))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+//Retry - Structure for the retry policy: the delay doubles from Base up to Max, and after Attempts failed
+//attempts the delivery moves to the dead list of the outbox
+type Retry struct {
+	Base     time.Duration
+	Max      time.Duration
+	Attempts int
+}
+
+//DefaultRetry - Politica de reintentos por defecto
+var DefaultRetry = Retry{Base: time.Second, Max: 5 * time.Minute, Attempts: 10}
+
+//Backoff - Espera antes del reintento siguiente a attempts intentos fallidos
+func (r Retry) Backoff(attempts int) time.Duration {
+	delay := r.Base
+	for i := 1; i < attempts && delay < r.Max; i++ {
+		delay *= 2
+	}
+	if delay > r.Max {
+		delay = r.Max
+	}
+	return delay
+}
+
+//Sign - Firma HMAC-SHA256 de timestamp + "." + body con el secreto del endpoint
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+//Verify - Valida la firma de un webhook recibido. Rechaza timestamps a mas de tolerance de now, para que
+//no se pueda reenviar un webhook capturado
+func Verify(secret string, header http.Header, body []byte, now time.Time, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return errors.New("Timestamp del webhook invalido")
+	}
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(timestamp, 0))
+		if age > tolerance || age < -tolerance {
+			return errors.New("Timestamp del webhook fuera de tolerancia")
+		}
+	}
+	if !hmac.Equal([]byte(header.Get(HeaderSignature)), []byte(Sign(secret, timestamp, body))) {
+		return errors.New("Firma del webhook invalida")
+	}
+	return nil
+}
+
+//Notifier - Lee eventos del Source, los guarda como entregas en el Outbox y las envia a los Endpoints.
+//La entrega es al menos una vez: el receptor descarta repetidos por la cabecera X-Loyalty-Id
+type Notifier struct {
+	Source    Source
+	Outbox    *Outbox
+	Endpoints []Endpoint
+	Retry     Retry
+	Client    *http.Client
+	Now       func() time.Time
+}
+
+//NewNotifier - Notifier con la politica de reintentos por defecto
+func NewNotifier(source Source, outbox *Outbox, endpoints []Endpoint) *Notifier {
+	return &Notifier{
+		Source:    source,
+		Outbox:    outbox,
+		Endpoints: endpoints,
+		Retry:     DefaultRetry,
+		Client:    &http.Client{Timeout: 10 * time.Second},
+		Now:       time.Now,
+	}
+}
+
+func (n *Notifier) now() time.Time {
+	if n.Now == nil {
+		return time.Now()
+	}
+	return n.Now()
+}
+
+func (n *Notifier) endpoint(name string) (Endpoint, bool) {
+	for _, e := range n.Endpoints {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return Endpoint{}, false
+}
+
+//Step - Lee los eventos nuevos y envia las entregas que ya toca intentar
+func (n *Notifier) Step() error {
+	err := n.Poll()
+	if err != nil {
+		return err
+	}
+	return n.Deliver()
+}
+
+//Poll - Pasa los eventos nuevos del Source al Outbox. El cursor y las entregas se guardan juntos
+func (n *Notifier) Poll() error {
+	events, cursor, err := n.Source.Poll(n.Outbox.Cursor)
+	if err != nil {
+		return err
+	}
+	if cursor == n.Outbox.Cursor && len(events) == 0 {
+		return nil
+	}
+
+	now := n.now().UnixNano() / int64(time.Millisecond)
+	for _, event := range events {
+		notifications, err := Notifications(event)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		for _, notification := range notifications {
+			body, err := json.Marshal(notification)
+			if err != nil {
+				return err
+			}
+			for _, endpoint := range n.Endpoints {
+				if !endpoint.Accepts(notification) {
+					continue
+				}
+				n.Outbox.Pending = append(n.Outbox.Pending, Delivery{
+					Id:          notification.Id,
+					Endpoint:    endpoint.Name,
+					Body:        body,
+					NextAttempt: now,
+				})
+			}
+		}
+	}
+	n.Outbox.Cursor = cursor
+	return n.Outbox.Save()
+}
+
+//Deliver - Envia las entregas pendientes que ya toca intentar. Una entrega fallida se reprograma con
+//backoff o, agotados los intentos, pasa a Dead
+func (n *Notifier) Deliver() error {
+	pending := []Delivery{}
+	changed := false
+	for _, delivery := range n.Outbox.Pending {
+		now := n.now()
+		if delivery.NextAttempt > now.UnixNano()/int64(time.Millisecond) {
+			pending = append(pending, delivery)
+			continue
+		}
+		changed = true
+		endpoint, ok := n.endpoint(delivery.Endpoint)
+		if !ok {
+			delivery.LastError = "Endpoint desconocido: " + delivery.Endpoint
+			n.Outbox.Dead = append(n.Outbox.Dead, delivery)
+			continue
+		}
+		err := n.send(endpoint, delivery, now)
+		if err == nil {
+			continue
+		}
+		delivery.Attempts++
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= n.Retry.Attempts {
+			fmt.Println("Entrega " + delivery.Id + " a " + delivery.Endpoint + " descartada: " + delivery.LastError)
+			n.Outbox.Dead = append(n.Outbox.Dead, delivery)
+			continue
+		}
+		delivery.NextAttempt = now.Add(n.Retry.Backoff(delivery.Attempts)).UnixNano() / int64(time.Millisecond)
+		pending = append(pending, delivery)
+	}
+	n.Outbox.Pending = pending
+	if !changed {
+		return nil
+	}
+	return n.Outbox.Save()
+}
+
+func (n *Notifier) send(endpoint Endpoint, delivery Delivery, now time.Time) error {
+	request, err := http.NewRequest("POST", endpoint.URL, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return err
+	}
+	notification := Notification{}
+	json.Unmarshal(delivery.Body, &notification)
+
+	timestamp := now.Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderId, delivery.Id)
+	request.Header.Set(HeaderEvent, notification.Type)
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, delivery.Body))
+
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("El endpoint respondio %s", response.Status)
+	}
+	return nil
+}
+
+//Run - Repite Step cada interval hasta que se cierre stop. Los errores se registran y el ciclo sigue
+func (n *Notifier) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := n.Step()
+		if err != nil {
+			fmt.Println("Error en el notifier: " + err.Error())
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,159 @@
+/*
+* Adrian Pareja
+ */
+package notifier_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ccamaleon5/blockchain/notifier"
+)
+
+var start = time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+func balanceEvent(txid string, wallet string, business string, amount string) notifier.Event {
+	return notifier.Event{
+		TxID:      txid,
+		Chaincode: "wallet",
+		Name:      "balanceEvent",
+		Payload:   `{"movements":[{"walletid":"` + wallet + `","business":"` + business + `","amount":` + amount + `,"balance":` + amount + `,"type":"C"}]}`,
+	}
+}
+
+//clock - Reloj del notifier que el test adelanta a mano
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"id":"tx1:0"}`)
+	header := http.Header{}
+	header.Set(notifier.HeaderTimestamp, strconv.FormatInt(start.Unix(), 10))
+	header.Set(notifier.HeaderSignature, notifier.Sign("secreto", start.Unix(), body))
+
+	if err := notifier.Verify("secreto", header, body, start, time.Minute); err != nil {
+		t.Fatalf("firma valida rechazada: %s", err)
+	}
+	if err := notifier.Verify("otro", header, body, start, time.Minute); err == nil {
+		t.Fatal("se acepto la firma con otro secreto")
+	}
+	if err := notifier.Verify("secreto", header, []byte(`{"id":"tx2:0"}`), start, time.Minute); err == nil {
+		t.Fatal("se acepto un body modificado")
+	}
+	if err := notifier.Verify("secreto", header, body, start.Add(2*time.Minute), time.Minute); err == nil {
+		t.Fatal("se acepto un timestamp fuera de tolerancia")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	retry := notifier.Retry{Base: time.Second, Max: 5 * time.Second, Attempts: 5}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, delay := range expected {
+		if backoff := retry.Backoff(i + 1); backoff != delay {
+			t.Fatalf("backoff de %d intentos: %s, se esperaba %s", i+1, backoff, delay)
+		}
+	}
+}
+
+func TestRetryToDead(t *testing.T) {
+	sink := notifier.NewSink("secreto")
+	sink.Tolerance = 0
+	sink.FailNext = 100
+	server := httptest.NewServer(sink)
+	defer server.Close()
+
+	source := &notifier.MemorySource{}
+	source.Push(balanceEvent("tx1", "w1", "cineplanet", "10"))
+	outbox, _ := notifier.LoadOutbox("")
+	c := &clock{now: start}
+	n := notifier.NewNotifier(source, outbox, []notifier.Endpoint{{Name: "app", URL: server.URL, Secret: "secreto"}})
+	n.Now = c.Now
+	n.Retry = notifier.Retry{Base: time.Second, Max: time.Minute, Attempts: 3}
+
+	//Primer intento y dos reintentos, cada uno despues de su espera
+	for _, wait := range []time.Duration{0, time.Second, 2 * time.Second} {
+		c.now = c.now.Add(wait)
+		if err := n.Step(); err != nil {
+			t.Fatal(err)
+		}
+		//Antes de la espera siguiente no se reintenta
+		if err := n.Step(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(outbox.Pending) != 0 || len(outbox.Dead) != 1 {
+		t.Fatalf("la entrega no paso a dead: pending %d dead %d", len(outbox.Pending), len(outbox.Dead))
+	}
+	if dead := outbox.Dead[0]; dead.Attempts != 3 || dead.Id != "tx1:0" || dead.LastError == "" {
+		t.Fatalf("entrega descartada incorrecta: %+v", dead)
+	}
+	if sink.FailNext != 97 {
+		t.Fatalf("se esperaban 3 intentos y hubo %d", 100-sink.FailNext)
+	}
+}
+
+func TestOutboxPersistence(t *testing.T) {
+	sink := notifier.NewSink("secreto")
+	sink.Tolerance = 0
+	sink.FailNext = 1
+	server := httptest.NewServer(sink)
+	defer server.Close()
+	endpoints := []notifier.Endpoint{
+		{Name: "app", URL: server.URL, Secret: "secreto"},
+		{Name: "otro", URL: server.URL, Secret: "secreto", Merchants: []string{"otro"}},
+	}
+
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	source := &notifier.MemorySource{}
+	source.Push(balanceEvent("tx1", "w1", "cineplanet", "10"))
+
+	outbox, err := notifier.LoadOutbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &clock{now: start}
+	n := notifier.NewNotifier(source, outbox, endpoints)
+	n.Now = c.Now
+	if err = n.Step(); err != nil {
+		t.Fatal(err)
+	}
+
+	//Un reinicio retoma el cursor y la entrega pendiente sin volver a leer el evento
+	reloaded, err := notifier.LoadOutbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Cursor != "1" || len(reloaded.Pending) != 1 || reloaded.Pending[0].Attempts != 1 {
+		t.Fatalf("outbox guardado incorrecto: %+v", reloaded)
+	}
+
+	source.Push(balanceEvent("tx2", "w1", "cineplanet", "5"))
+	c.now = c.now.Add(time.Minute)
+	n = notifier.NewNotifier(source, reloaded, endpoints)
+	n.Now = c.Now
+	if err = n.Step(); err != nil {
+		t.Fatal(err)
+	}
+
+	received := sink.Received()
+	if len(received) != 2 || received[0].Id != "tx1:0" || received[1].Id != "tx2:0" {
+		t.Fatalf("entregas incorrectas: %+v", received)
+	}
+	final, err := notifier.LoadOutbox(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.Cursor != "2" || len(final.Pending) != 0 || len(final.Dead) != 0 {
+		t.Fatalf("outbox final incorrecto: %+v", final)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+* Adrian Pareja
+ */
+package notifier
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+//Delivery - Structure for a webhook pending delivery to an endpoint
+type Delivery struct {
+	Id          string          `json:"id"`
+	Endpoint    string          `json:"endpoint"`
+	Body        json.RawMessage `json:"body"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt int64           `json:"nextAttempt"`
+	LastError   string          `json:"lastError,omitempty"`
+}
+
+//Outbox - Estado del notifier: el cursor del source, las entregas pendientes y las descartadas despues del
+//ultimo reintento. Con Path se guarda en disco en cada cambio, asi un reinicio no pierde ni repite eventos
+type Outbox struct {
+	Path    string     `json:"-"`
+	Cursor  string     `json:"cursor"`
+	Pending []Delivery `json:"pending"`
+	Dead    []Delivery `json:"dead"`
+}
+
+//LoadOutbox - Lee el outbox de path; si el archivo no existe devuelve uno vacio. Con path vacio el outbox
+//queda solo en memoria
+func LoadOutbox(path string) (*Outbox, error) {
+	outbox := &Outbox{Path: path, Pending: []Delivery{}, Dead: []Delivery{}}
+	if path == "" {
+		return outbox, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return outbox, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, outbox)
+	if err != nil {
+		return nil, err
+	}
+	outbox.Path = path
+	return outbox, nil
+}
+
+//Save - Escribe el outbox en un archivo temporal y lo renombra, para no dejar un archivo a medias
+func (o *Outbox) Save() error {
+	if o.Path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(o.Path), filepath.Base(o.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), o.Path)
+}
@@ -0,0 +1,41 @@
+/*
+* Adrian Pareja
+ */
+
+//Package simsource conecta el notifier con los eventos de una red del paquete simulator. Esta aparte porque el
+//simulador importa el shim de fabric, que el binario del notifier no necesita
+package simsource
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/ccamaleon5/blockchain/notifier"
+	"github.com/ccamaleon5/blockchain/simulator"
+)
+
+//NetworkSource - Source sobre los eventos de una red del paquete simulator; el cursor es la posicion en la lista
+type NetworkSource struct {
+	Network *simulator.Network
+}
+
+//Poll - Eventos de la red desde el cursor
+func (s *NetworkSource) Poll(cursor string) ([]notifier.Event, string, error) {
+	index := 0
+	if cursor != "" {
+		var err error
+		index, err = strconv.Atoi(cursor)
+		if err != nil || index < 0 {
+			return nil, cursor, errors.New("Cursor invalido: " + cursor)
+		}
+	}
+	all := s.Network.Events
+	if index > len(all) {
+		index = len(all)
+	}
+	events := []notifier.Event{}
+	for _, e := range all[index:] {
+		events = append(events, notifier.Event{TxID: e.TxID, Chaincode: e.Chaincode, Name: e.Name, Payload: string(e.Payload)})
+	}
+	return events, strconv.Itoa(len(all)), nil
+}
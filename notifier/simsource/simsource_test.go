@@ -0,0 +1,37 @@
+/*
+* Adrian Pareja
+ */
+package simsource_test
+
+import (
+	"testing"
+
+	"github.com/ccamaleon5/blockchain/chaincode/wallet"
+	"github.com/ccamaleon5/blockchain/notifier"
+	"github.com/ccamaleon5/blockchain/notifier/simsource"
+	"github.com/ccamaleon5/blockchain/simulator"
+)
+
+func TestNetworkSource(t *testing.T) {
+	n := simulator.NewNetwork()
+	n.Attributes = map[string]string{"role": "admin"}
+	if _, err := n.Deploy("wallet", new(wallet.SimpleChaincode), "init", "1000000"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := n.Invoke("wallet", "createwallet", "w1", "w1@mail.com", "999", "123", "pw", "0"); err != nil {
+		t.Fatal(err)
+	}
+
+	source := &simsource.NetworkSource{Network: n}
+	events, cursor, err := source.Poll("")
+	if err != nil || len(events) != 1 {
+		t.Fatalf("eventos incorrectos: %+v %v", events, err)
+	}
+	notifications, err := notifier.Notifications(events[0])
+	if err != nil || len(notifications) != 1 || notifications[0].Type != notifier.TypeWalletCreated || notifications[0].Wallet != "w1" {
+		t.Fatalf("notificaciones incorrectas: %+v %v", notifications, err)
+	}
+	if events, _, _ = source.Poll(cursor); len(events) != 0 {
+		t.Fatalf("el cursor no avanzo: %+v", events)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+* Adrian Pareja
+ */
+package notifier
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//Sink - Receptor local de webhooks para pruebas. Valida la firma, descarta repetidos por X-Loyalty-Id y
+//guarda las notificaciones recibidas. FailNext hace fallar las siguientes entregas para probar reintentos
+type Sink struct {
+	Secret    string
+	Tolerance time.Duration
+	FailNext  int
+	OnReceive func(Notification)
+
+	mu       sync.Mutex
+	seen     map[string]bool
+	received []Notification
+}
+
+//NewSink - Sink con el secreto del endpoint
+func NewSink(secret string) *Sink {
+	return &Sink{Secret: secret, Tolerance: 5 * time.Minute}
+}
+
+//Received - Notificaciones recibidas, sin repetidos
+func (s *Sink) Received() []Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Notification{}, s.received...)
+}
+
+func (s *Sink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = Verify(s.Secret, r.Header, body, time.Now(), s.Tolerance)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	notification := Notification{}
+	err = json.Unmarshal(body, &notification)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	if s.FailNext > 0 {
+		s.FailNext--
+		s.mu.Unlock()
+		http.Error(w, "Fallo simulado", http.StatusServiceUnavailable)
+		return
+	}
+	if s.seen == nil {
+		s.seen = map[string]bool{}
+	}
+	id := r.Header.Get(HeaderId)
+	duplicate := s.seen[id]
+	if !duplicate {
+		s.seen[id] = true
+		s.received = append(s.received, notification)
+	}
+	s.mu.Unlock()
+
+	if !duplicate && s.OnReceive != nil {
+		s.OnReceive(notification)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,108 @@
+/*
+* Adrian Pareja
+ */
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+)
+
+//Source - Origen de eventos. Poll devuelve los eventos posteriores al cursor y el cursor siguiente;
+//el cursor vacio es el inicio. El notifier guarda el cursor en el outbox
+type Source interface {
+	Poll(cursor string) ([]Event, string, error)
+}
+
+func indexCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	index, err := strconv.Atoi(cursor)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("Cursor invalido: %s", cursor)
+	}
+	return index, nil
+}
+
+//MemorySource - Source en memoria; el cursor es la posicion en la lista
+type MemorySource struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+//Push - Agrega eventos
+func (m *MemorySource) Push(events ...Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, events...)
+}
+
+//Poll - Eventos desde el cursor
+func (m *MemorySource) Poll(cursor string) ([]Event, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	index, err := indexCursor(cursor)
+	if err != nil {
+		return nil, cursor, err
+	}
+	if index > len(m.events) {
+		index = len(m.events)
+	}
+	events := append([]Event{}, m.events[index:]...)
+	return events, strconv.Itoa(len(m.events)), nil
+}
+
+//FileSource - Source sobre un archivo con un evento JSON por linea, como el que escribe eventhub.Listener con
+//los eventos del peer. El cursor es la posicion en bytes; una linea sin terminar se lee en el siguiente Poll
+type FileSource struct {
+	Path string
+}
+
+//Poll - Eventos desde la posicion del cursor
+func (f *FileSource) Poll(cursor string) ([]Event, string, error) {
+	offset, err := indexCursor(cursor)
+	if err != nil {
+		return nil, cursor, err
+	}
+	file, err := os.Open(f.Path)
+	if os.IsNotExist(err) {
+		return nil, cursor, nil
+	}
+	if err != nil {
+		return nil, cursor, err
+	}
+	defer file.Close()
+
+	_, err = file.Seek(int64(offset), io.SeekStart)
+	if err != nil {
+		return nil, cursor, err
+	}
+	var buffer bytes.Buffer
+	_, err = buffer.ReadFrom(file)
+	if err != nil {
+		return nil, cursor, err
+	}
+
+	data := buffer.Bytes()
+	complete := bytes.LastIndexByte(data, '\n') + 1
+	events := []Event{}
+	for _, line := range bytes.Split(data[:complete], []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		event := Event{}
+		err = json.Unmarshal(line, &event)
+		if err != nil {
+			return nil, cursor, fmt.Errorf("Evento invalido en %s: %s", f.Path, line)
+		}
+		events = append(events, event)
+	}
+	return events, strconv.Itoa(offset + complete), nil
+}
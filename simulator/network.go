@@ -0,0 +1,203 @@
+/*
+* Adrian Pareja
+ */
+
+//Package simulator ejecuta chaincodes en memoria, sin peer, para pruebas y escenarios.
+//Un Network agrupa chaincodes desplegados por id; las llamadas entre chaincodes se enrutan
+//por ese id y una transaccion que devuelve error se descarta completa en todos los chaincodes.
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//Event - Structure for an event emitted by a committed transaction
+type Event struct {
+	TxID      string
+	Chaincode string
+	Name      string
+	Payload   []byte
+}
+
+//ledger - Estado y tablas de un chaincode desplegado
+type ledger struct {
+	chaincode shim.Chaincode
+	state     map[string][]byte
+	tables    map[string]*table
+}
+
+//Network - Red en memoria con los chaincodes desplegados
+type Network struct {
+	//Attributes son los atributos del certificado de quien invoca, los lee ReadCertAttribute
+	Attributes map[string]string
+	//Now da el tiempo de las transacciones, time.Now si es nil. Los chaincodes lo leen con GetTxTimestamp
+	Now func() time.Time
+	//Events tiene los eventos de las transacciones confirmadas, en orden
+	Events []Event
+
+	ledgers map[string]*ledger
+	txCount int
+	last    time.Time
+}
+
+//txSpacing - Separacion minima entre transacciones. Los chaincodes usan el tiempo de la transaccion, y unos
+//milisegundos mas, como clave de sus movimientos
+const txSpacing = 10 * time.Millisecond
+
+//NewNetwork - Crea una red vacia
+func NewNetwork() *Network {
+	return &Network{
+		Attributes: map[string]string{},
+		ledgers:    map[string]*ledger{},
+	}
+}
+
+//Deploy - Despliega un chaincode con el id indicado y ejecuta su Init
+func (n *Network) Deploy(id string, chaincode shim.Chaincode, function string, args ...string) ([]byte, error) {
+	if _, found := n.ledgers[id]; found {
+		return nil, errors.New("El chaincode ya esta desplegado: " + id)
+	}
+	n.ledgers[id] = &ledger{chaincode: chaincode, state: map[string][]byte{}, tables: map[string]*table{}}
+
+	response, err := n.run(id, txInit, function, args)
+	if err != nil {
+		delete(n.ledgers, id)
+		return nil, err
+	}
+	return response, nil
+}
+
+//Invoke - Ejecuta una transaccion; si devuelve error no queda ningun cambio en ningun chaincode
+func (n *Network) Invoke(id string, function string, args ...string) ([]byte, error) {
+	return n.run(id, txInvoke, function, args)
+}
+
+//Query - Ejecuta una consulta de solo lectura
+func (n *Network) Query(id string, function string, args ...string) ([]byte, error) {
+	return n.run(id, txQuery, function, args)
+}
+
+//SetTime - Fija el tiempo de la red en t; las transacciones siguientes avanzan desde ahi de txSpacing en txSpacing
+func (n *Network) SetTime(t time.Time) {
+	n.Now = func() time.Time { return t }
+	n.last = time.Time{}
+}
+
+//Advance - Adelanta el tiempo de la red en d desde la ultima transaccion y lo deja fijo ahi
+func (n *Network) Advance(d time.Duration) {
+	now := time.Now()
+	if n.Now != nil {
+		now = n.Now()
+	}
+	if now.Before(n.last) {
+		now = n.last
+	}
+	n.SetTime(now.Add(d))
+}
+
+//GetState - Lee el estado confirmado de un chaincode, para verificar resultados
+func (n *Network) GetState(id string, key string) []byte {
+	ledger, found := n.ledgers[id]
+	if !found {
+		return nil
+	}
+	return ledger.state[key]
+}
+
+type txKind int
+
+const (
+	txInit txKind = iota
+	txInvoke
+	txQuery
+)
+
+//tx - Transaccion en curso, compartida por las llamadas entre chaincodes
+type tx struct {
+	network *Network
+	id      string
+	time    time.Time
+	event   *Event
+}
+
+func (n *Network) run(id string, kind txKind, function string, args []string) ([]byte, error) {
+	if _, found := n.ledgers[id]; !found {
+		return nil, errors.New("Chaincode desconocido: " + id)
+	}
+
+	n.txCount = n.txCount + 1
+	now := time.Now()
+	if n.Now != nil {
+		now = n.Now()
+	}
+	if !n.last.IsZero() && now.Before(n.last.Add(txSpacing)) {
+		now = n.last.Add(txSpacing)
+	}
+	n.last = now
+	t := &tx{network: n, id: "tx" + strconv.Itoa(n.txCount), time: now}
+
+	//Las consultas no escriben, no hace falta poder descartarlas
+	var snapshot map[string]*ledger
+	if kind != txQuery {
+		snapshot = n.snapshot()
+	}
+
+	response, err := t.call(id, kind, function, args)
+	if err != nil {
+		if snapshot != nil {
+			n.ledgers = snapshot
+		}
+		return nil, err
+	}
+
+	if t.event != nil {
+		n.Events = append(n.Events, *t.event)
+	}
+	return response, nil
+}
+
+//call - Ejecuta una funcion del chaincode dentro de la transaccion
+func (t *tx) call(id string, kind txKind, function string, args []string) (response []byte, err error) {
+	ledger, found := t.network.ledgers[id]
+	if !found {
+		return nil, errors.New("Chaincode desconocido: " + id)
+	}
+
+	//Un panic del chaincode se reporta como error y descarta la transaccion
+	defer func() {
+		if r := recover(); r != nil {
+			response = nil
+			err = fmt.Errorf("El chaincode %s fallo: %v", id, r)
+		}
+	}()
+
+	stub := &Stub{tx: t, chaincode: id, ledger: ledger, readOnly: kind == txQuery, function: function, args: args}
+	switch kind {
+	case txInit:
+		return ledger.chaincode.Init(stub, function, args)
+	case txInvoke:
+		return ledger.chaincode.Invoke(stub, function, args)
+	}
+	return ledger.chaincode.Query(stub, function, args)
+}
+
+//snapshot - Copia el estado y las tablas de todos los chaincodes
+func (n *Network) snapshot() map[string]*ledger {
+	ledgers := map[string]*ledger{}
+	for id, l := range n.ledgers {
+		copied := &ledger{chaincode: l.chaincode, state: map[string][]byte{}, tables: map[string]*table{}}
+		for key, value := range l.state {
+			copied.state[key] = value
+		}
+		for name, t := range l.tables {
+			copied.tables[name] = t.copy()
+		}
+		ledgers[id] = copied
+	}
+	return ledgers
+}
@@ -0,0 +1,147 @@
+/*
+* Adrian Pareja
+ */
+package simulator_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ccamaleon5/blockchain/chaincode/merchant"
+	"github.com/ccamaleon5/blockchain/chaincode/wallet"
+	"github.com/ccamaleon5/blockchain/simulator"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+var start = time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+//newNetwork - Red con el wallet, el comercio cineplanet desplegado como cine y el wallet w1
+func newNetwork(t *testing.T) *simulator.Network {
+	n := simulator.NewNetwork()
+	n.SetTime(start)
+	n.Attributes = map[string]string{"role": "admin", "merchant": "cineplanet"}
+	must(t)(n.Deploy("wallet", new(wallet.SimpleChaincode), "init", "1000000"))
+	must(t)(n.Invoke("wallet", "registermerchant", "cineplanet", "Cineplanet", "cineplanet"))
+	must(t)(n.Deploy("cine", new(merchant.SimpleChaincode), "init", "10000", "Cineplanet", "cineplanet", "1", "wallet"))
+	must(t)(n.Invoke("wallet", "createwallet", "w1", "w1@mail.com", "999", "123", "pw", "0"))
+	return n
+}
+
+func must(t *testing.T) func([]byte, error) []byte {
+	return func(response []byte, err error) []byte {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return response
+	}
+}
+
+type buyResult struct {
+	Balance  string `json:"balance"`
+	Redeemed string `json:"redeemed"`
+	Earned   string `json:"earned"`
+}
+
+func buy(t *testing.T, n *simulator.Network, args ...string) buyResult {
+	t.Helper()
+	response := must(t)(n.Invoke("cine", "buy", args...))
+	result := buyResult{}
+	if err := json.Unmarshal(response, &result); err != nil {
+		t.Fatalf("respuesta invalida %s: %s", response, err)
+	}
+	return result
+}
+
+func TestBuy(t *testing.T) {
+	n := newNetwork(t)
+
+	result := buy(t, n, "w1", "100", "0")
+	if result.Earned != "100.000000" || result.Balance != "100.000000" {
+		t.Fatalf("acumulacion incorrecta: %+v", result)
+	}
+
+	result = buy(t, n, "w1", "100", "5")
+	if result.Redeemed != "5.000000" || result.Earned != "95.000000" || result.Balance != "190.000000" {
+		t.Fatalf("canje incorrecto: %+v", result)
+	}
+
+	balance := must(t)(n.Query("wallet", "getbalance", "w1"))
+	if string(balance) != `{"code":0,"balance":"190.000000","limit":"95.000000","tier":"Classic","bonus":"1.000000"}` {
+		t.Fatalf("saldo incorrecto: %s", balance)
+	}
+}
+
+func TestBuyRollback(t *testing.T) {
+	n := newNetwork(t)
+	buy(t, n, "w1", "100", "0")
+
+	walletBefore := string(n.GetState("wallet", "w1"))
+	coinsBefore := string(n.GetState("cine", "Cineplanet"))
+	events := len(n.Events)
+
+	//El canje excede el monto canjeable, el comercio falla despues de llamar al wallet
+	_, err := n.Invoke("cine", "buy", "w1", "50", "60")
+	if err == nil {
+		t.Fatal("se esperaba un error")
+	}
+	if string(n.GetState("wallet", "w1")) != walletBefore || string(n.GetState("cine", "Cineplanet")) != coinsBefore {
+		t.Fatal("la compra fallida dejo cambios en el ledger")
+	}
+	if len(n.Events) != events {
+		t.Fatal("la compra fallida publico un evento")
+	}
+}
+
+func TestTxTime(t *testing.T) {
+	n := newNetwork(t)
+
+	type canje struct {
+		Time int64 `json:"time"`
+	}
+	canjes := func() []canje {
+		list := []canje{}
+		response := must(t)(n.Query("cine", "getcanjes", "w1"))
+		if err := json.Unmarshal(response, &list); err != nil {
+			t.Fatalf("respuesta invalida %s: %s", response, err)
+		}
+		return list
+	}
+	ms := func(at time.Time) int64 {
+		return at.UnixNano() / int64(time.Millisecond)
+	}
+
+	buy(t, n, "w1", "100", "0")
+	n.Advance(48 * time.Hour)
+	buy(t, n, "w1", "100", "0")
+
+	list := canjes()
+	if len(list) != 2 {
+		t.Fatalf("se esperaban 2 canjes: %+v", list)
+	}
+	if list[0].Time < ms(start) || list[0].Time > ms(start.Add(time.Second)) {
+		t.Fatalf("el canje no usa el tiempo de la red: %d", list[0].Time)
+	}
+	if list[1].Time < ms(start.Add(48*time.Hour)) {
+		t.Fatalf("el canje no usa el tiempo adelantado: %d", list[1].Time)
+	}
+}
+
+func TestScenario(t *testing.T) {
+	scenario, err := simulator.LoadScenario("testdata/buy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	contracts := simulator.Contracts{
+		"wallet":   func() shim.Chaincode { return new(wallet.SimpleChaincode) },
+		"merchant": func() shim.Chaincode { return new(merchant.SimpleChaincode) },
+	}
+	results, err := scenario.Run(simulator.NewNetwork(), contracts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(scenario.Steps) {
+		t.Fatalf("se ejecutaron %d de %d pasos", len(results), len(scenario.Steps))
+	}
+}
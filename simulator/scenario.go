@@ -0,0 +1,118 @@
+/*
+* Adrian Pareja
+ */
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//Step - Structure for a scenario step: a deploy, invoke or query with its expected outcome.
+//Un paso deploy despliega en Chaincode el contrato Contract y ejecuta su Init con Function y Args.
+//Error y Expect se comparan como subcadenas del error y de la respuesta. Time (RFC 3339) fija el tiempo de la red y
+//Advance (duracion de Go, por ejemplo 720h) lo adelanta antes de ejecutar el paso
+type Step struct {
+	Name       string            `json:"name"`
+	Kind       string            `json:"kind"`
+	Chaincode  string            `json:"chaincode"`
+	Contract   string            `json:"contract,omitempty"`
+	Function   string            `json:"function"`
+	Args       []string          `json:"args"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Time       string            `json:"time,omitempty"`
+	Advance    string            `json:"advance,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Expect     string            `json:"expect,omitempty"`
+}
+
+//Scenario - Structure for a list of steps run in order against a Network
+type Scenario struct {
+	Name  string `json:"name"`
+	Steps []Step `json:"steps"`
+}
+
+//Contracts - Constructores de los chaincodes que un paso deploy puede desplegar, por nombre de contrato
+type Contracts map[string]func() shim.Chaincode
+
+//StepResult - Structure for the outcome of a scenario step
+type StepResult struct {
+	Step     Step
+	Response []byte
+	Err      error
+}
+
+//LoadScenario - Lee un escenario JSON
+func LoadScenario(path string) (*Scenario, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	scenario := Scenario{}
+	err = json.Unmarshal(bytes, &scenario)
+	if err != nil {
+		return nil, fmt.Errorf("Escenario invalido %s. %s", path, err)
+	}
+	return &scenario, nil
+}
+
+//Run - Ejecuta los pasos en orden y se detiene en el primero que no cumple lo esperado
+func (s *Scenario) Run(n *Network, contracts Contracts) ([]StepResult, error) {
+	results := []StepResult{}
+	for i, step := range s.Steps {
+		if step.Attributes != nil {
+			n.Attributes = step.Attributes
+		}
+		if step.Time != "" {
+			t, err := time.Parse(time.RFC3339, step.Time)
+			if err != nil {
+				return results, fmt.Errorf("Paso %d %s: tiempo invalido %s", i+1, step.Name, step.Time)
+			}
+			n.SetTime(t)
+		}
+		if step.Advance != "" {
+			d, err := time.ParseDuration(step.Advance)
+			if err != nil {
+				return results, fmt.Errorf("Paso %d %s: duracion invalida %s", i+1, step.Name, step.Advance)
+			}
+			n.Advance(d)
+		}
+
+		var response []byte
+		var err error
+		switch step.Kind {
+		case "deploy":
+			contract, found := contracts[step.Contract]
+			if !found {
+				return results, fmt.Errorf("Paso %d %s: contrato desconocido %s", i+1, step.Name, step.Contract)
+			}
+			response, err = n.Deploy(step.Chaincode, contract(), step.Function, step.Args...)
+		case "invoke", "":
+			response, err = n.Invoke(step.Chaincode, step.Function, step.Args...)
+		case "query":
+			response, err = n.Query(step.Chaincode, step.Function, step.Args...)
+		default:
+			return results, fmt.Errorf("Paso %d %s: tipo invalido %s", i+1, step.Name, step.Kind)
+		}
+		results = append(results, StepResult{Step: step, Response: response, Err: err})
+
+		if step.Error != "" {
+			if err == nil || !strings.Contains(err.Error(), step.Error) {
+				return results, fmt.Errorf("Paso %d %s: se esperaba el error %q y se obtuvo %v", i+1, step.Name, step.Error, err)
+			}
+			continue
+		}
+		if err != nil {
+			return results, fmt.Errorf("Paso %d %s: %s", i+1, step.Name, err)
+		}
+		if step.Expect != "" && !strings.Contains(string(response), step.Expect) {
+			return results, fmt.Errorf("Paso %d %s: se esperaba %q en la respuesta %s", i+1, step.Name, step.Expect, response)
+		}
+	}
+	return results, nil
+}
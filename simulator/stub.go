@@ -0,0 +1,327 @@
+/*
+* Adrian Pareja
+ */
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/chaincode/shim/crypto/attr"
+)
+
+//Stub - ChaincodeStubInterface en memoria para una llamada a un chaincode
+type Stub struct {
+	tx        *tx
+	chaincode string
+	ledger    *ledger
+	readOnly  bool
+	function  string
+	args      []string
+}
+
+//GetArgs - Funcion y argumentos de la llamada
+func (s *Stub) GetArgs() [][]byte {
+	args := [][]byte{[]byte(s.function)}
+	for _, arg := range s.args {
+		args = append(args, []byte(arg))
+	}
+	return args
+}
+
+//GetStringArgs - Funcion y argumentos de la llamada
+func (s *Stub) GetStringArgs() []string {
+	return append([]string{s.function}, s.args...)
+}
+
+//GetTxID - Id de la transaccion, el mismo para las llamadas entre chaincodes
+func (s *Stub) GetTxID() string {
+	return s.tx.id
+}
+
+//InvokeChaincode - Llama a otro chaincode desplegado dentro de la misma transaccion
+func (s *Stub) InvokeChaincode(chaincodeName string, args [][]byte) ([]byte, error) {
+	if s.readOnly {
+		return nil, errors.New("No se puede invocar un chaincode desde una consulta")
+	}
+	function, callArgs, err := splitArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.tx.call(chaincodeName, txInvoke, function, callArgs)
+}
+
+//QueryChaincode - Consulta otro chaincode desplegado dentro de la misma transaccion
+func (s *Stub) QueryChaincode(chaincodeName string, args [][]byte) ([]byte, error) {
+	function, callArgs, err := splitArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.tx.call(chaincodeName, txQuery, function, callArgs)
+}
+
+func splitArgs(args [][]byte) (string, []string, error) {
+	if len(args) == 0 {
+		return "", nil, errors.New("Falta la funcion a invocar")
+	}
+	callArgs := []string{}
+	for _, arg := range args[1:] {
+		callArgs = append(callArgs, string(arg))
+	}
+	return string(args[0]), callArgs, nil
+}
+
+func (s *Stub) checkWrite() error {
+	if s.readOnly {
+		return errors.New("Una consulta no puede modificar el ledger")
+	}
+	return nil
+}
+
+//GetState - Lee una clave del estado, nil si no existe
+func (s *Stub) GetState(key string) ([]byte, error) {
+	value, found := s.ledger.state[key]
+	if !found {
+		return nil, nil
+	}
+	return append([]byte(nil), value...), nil
+}
+
+//PutState - Escribe una clave del estado
+func (s *Stub) PutState(key string, value []byte) error {
+	if err := s.checkWrite(); err != nil {
+		return err
+	}
+	if key == "" {
+		return errors.New("La clave no puede ser vacia")
+	}
+	s.ledger.state[key] = append([]byte(nil), value...)
+	return nil
+}
+
+//DelState - Borra una clave del estado
+func (s *Stub) DelState(key string) error {
+	if err := s.checkWrite(); err != nil {
+		return err
+	}
+	delete(s.ledger.state, key)
+	return nil
+}
+
+//RangeQueryState - Recorre en orden las claves en [startKey, endKey), endKey vacio no tiene limite
+func (s *Stub) RangeQueryState(startKey, endKey string) (shim.StateRangeQueryIteratorInterface, error) {
+	var keys []string
+	for key := range s.ledger.state {
+		if key >= startKey && (endKey == "" || key < endKey) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	iterator := &rangeIterator{}
+	for _, key := range keys {
+		iterator.keys = append(iterator.keys, key)
+		iterator.values = append(iterator.values, append([]byte(nil), s.ledger.state[key]...))
+	}
+	return iterator, nil
+}
+
+//rangeIterator - Resultado de RangeQueryState
+type rangeIterator struct {
+	keys   []string
+	values [][]byte
+	next   int
+}
+
+func (r *rangeIterator) HasNext() bool {
+	return r.next < len(r.keys)
+}
+
+func (r *rangeIterator) Next() (string, []byte, error) {
+	if !r.HasNext() {
+		return "", nil, errors.New("No hay mas claves en el rango")
+	}
+	r.next = r.next + 1
+	return r.keys[r.next-1], r.values[r.next-1], nil
+}
+
+func (r *rangeIterator) Close() error {
+	return nil
+}
+
+//CreateTable - Crea una tabla, error si ya existe
+func (s *Stub) CreateTable(name string, columnDefinitions []*shim.ColumnDefinition) error {
+	if err := s.checkWrite(); err != nil {
+		return err
+	}
+	if _, found := s.ledger.tables[name]; found {
+		return fmt.Errorf("CreateTable operation failed. Table %s already exists.", name)
+	}
+	t, err := newTable(name, columnDefinitions)
+	if err != nil {
+		return err
+	}
+	s.ledger.tables[name] = t
+	return nil
+}
+
+func (s *Stub) getTable(tableName string) (*table, error) {
+	t, found := s.ledger.tables[tableName]
+	if !found {
+		return nil, fmt.Errorf("Table %s does not exist", tableName)
+	}
+	return t, nil
+}
+
+//GetTable - Obtiene la definicion de una tabla
+func (s *Stub) GetTable(tableName string) (*shim.Table, error) {
+	t, err := s.getTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return &shim.Table{Name: t.name, ColumnDefinitions: t.definitions}, nil
+}
+
+//DeleteTable - Borra una tabla con todas sus filas
+func (s *Stub) DeleteTable(tableName string) error {
+	if err := s.checkWrite(); err != nil {
+		return err
+	}
+	delete(s.ledger.tables, tableName)
+	return nil
+}
+
+//InsertRow - Inserta una fila, false si ya existe una fila con la misma clave
+func (s *Stub) InsertRow(tableName string, row shim.Row) (bool, error) {
+	if err := s.checkWrite(); err != nil {
+		return false, err
+	}
+	t, err := s.getTable(tableName)
+	if err != nil {
+		return false, err
+	}
+	return t.put(row, false)
+}
+
+//ReplaceRow - Reemplaza una fila, false si no existe una fila con la misma clave
+func (s *Stub) ReplaceRow(tableName string, row shim.Row) (bool, error) {
+	if err := s.checkWrite(); err != nil {
+		return false, err
+	}
+	t, err := s.getTable(tableName)
+	if err != nil {
+		return false, err
+	}
+	return t.put(row, true)
+}
+
+//GetRow - Obtiene la fila con la clave completa, una fila sin columnas si no existe
+func (s *Stub) GetRow(tableName string, key []shim.Column) (shim.Row, error) {
+	t, err := s.getTable(tableName)
+	if err != nil {
+		return shim.Row{}, err
+	}
+	return t.get(key)
+}
+
+//GetRows - Obtiene en orden las filas cuya clave empieza con las columnas indicadas
+func (s *Stub) GetRows(tableName string, key []shim.Column) (<-chan shim.Row, error) {
+	t, err := s.getTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := t.scan(key)
+	if err != nil {
+		return nil, err
+	}
+
+	rowChannel := make(chan shim.Row, len(rows))
+	for _, row := range rows {
+		rowChannel <- row
+	}
+	close(rowChannel)
+	return rowChannel, nil
+}
+
+//DeleteRow - Borra la fila con la clave completa
+func (s *Stub) DeleteRow(tableName string, key []shim.Column) error {
+	if err := s.checkWrite(); err != nil {
+		return err
+	}
+	t, err := s.getTable(tableName)
+	if err != nil {
+		return err
+	}
+	return t.delete(key)
+}
+
+//ReadCertAttribute - Lee un atributo de Network.Attributes, error si no esta
+func (s *Stub) ReadCertAttribute(attributeName string) ([]byte, error) {
+	value, found := s.tx.network.Attributes[attributeName]
+	if !found {
+		return nil, fmt.Errorf("Attribute '%s' not found", attributeName)
+	}
+	return []byte(value), nil
+}
+
+//VerifyAttribute - Indica si el atributo tiene el valor indicado
+func (s *Stub) VerifyAttribute(attributeName string, attributeValue []byte) (bool, error) {
+	value, found := s.tx.network.Attributes[attributeName]
+	return found && value == string(attributeValue), nil
+}
+
+//VerifyAttributes - Indica si todos los atributos tienen los valores indicados
+func (s *Stub) VerifyAttributes(attrs ...*attr.Attribute) (bool, error) {
+	for _, a := range attrs {
+		ok, _ := s.VerifyAttribute(a.Name, a.Value)
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+//VerifySignature - El simulador no tiene certificados
+func (s *Stub) VerifySignature(certificate, signature, message []byte) (bool, error) {
+	return false, errors.New("VerifySignature no esta soportado en el simulador")
+}
+
+//GetCallerCertificate - El simulador no tiene certificados
+func (s *Stub) GetCallerCertificate() ([]byte, error) {
+	return nil, nil
+}
+
+//GetCallerMetadata - El simulador no tiene metadata
+func (s *Stub) GetCallerMetadata() ([]byte, error) {
+	return nil, nil
+}
+
+//GetBinding - El simulador no tiene binding
+func (s *Stub) GetBinding() ([]byte, error) {
+	return nil, nil
+}
+
+//GetPayload - El simulador no tiene payload
+func (s *Stub) GetPayload() ([]byte, error) {
+	return nil, nil
+}
+
+//GetTxTimestamp - Tiempo de la transaccion segun Network.Now
+func (s *Stub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return &timestamp.Timestamp{Seconds: s.tx.time.Unix(), Nanos: int32(s.tx.time.Nanosecond())}, nil
+}
+
+//SetEvent - Registra el evento de la transaccion, se publica solo si la transaccion se confirma
+func (s *Stub) SetEvent(name string, payload []byte) error {
+	if err := s.checkWrite(); err != nil {
+		return err
+	}
+	if name == "" {
+		return errors.New("El nombre del evento no puede ser vacio")
+	}
+	s.tx.event = &Event{TxID: s.tx.id, Chaincode: s.chaincode, Name: name, Payload: append([]byte(nil), payload...)}
+	return nil
+}
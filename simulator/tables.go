@@ -0,0 +1,265 @@
+/*
+* Adrian Pareja
+ */
+package simulator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/chaincode/shim"
+)
+
+//table - Tabla en memoria con las filas indexadas por sus columnas clave
+type table struct {
+	name        string
+	definitions []*shim.ColumnDefinition
+	keys        []int
+	rows        map[string]shim.Row
+}
+
+func newTable(name string, definitions []*shim.ColumnDefinition) (*table, error) {
+	if name == "" {
+		return nil, errors.New("El nombre de la tabla no puede ser vacio")
+	}
+	t := &table{name: name, rows: map[string]shim.Row{}}
+	for i, definition := range definitions {
+		if definition == nil || definition.Name == "" {
+			return nil, fmt.Errorf("Columna %d invalida en la tabla %s", i, name)
+		}
+		copied := *definition
+		t.definitions = append(t.definitions, &copied)
+		if definition.Key {
+			t.keys = append(t.keys, i)
+		}
+	}
+	if len(t.keys) == 0 {
+		return nil, errors.New("La tabla " + name + " necesita al menos una columna clave")
+	}
+	return t, nil
+}
+
+func (t *table) copy() *table {
+	copied := &table{name: t.name, definitions: t.definitions, keys: t.keys, rows: map[string]shim.Row{}}
+	for key, row := range t.rows {
+		copied.rows[key] = row
+	}
+	return copied
+}
+
+//columnType - Tipo de la columna segun su valor, -1 si no tiene valor
+func columnType(column *shim.Column) shim.ColumnDefinition_Type {
+	switch column.GetValue().(type) {
+	case *shim.Column_String_:
+		return shim.ColumnDefinition_STRING
+	case *shim.Column_Int32:
+		return shim.ColumnDefinition_INT32
+	case *shim.Column_Int64:
+		return shim.ColumnDefinition_INT64
+	case *shim.Column_Uint32:
+		return shim.ColumnDefinition_UINT32
+	case *shim.Column_Uint64:
+		return shim.ColumnDefinition_UINT64
+	case *shim.Column_Bytes:
+		return shim.ColumnDefinition_BYTES
+	case *shim.Column_Bool:
+		return shim.ColumnDefinition_BOOL
+	}
+	return -1
+}
+
+//encodeColumn - Representacion de una columna clave que conserva el tipo
+func encodeColumn(column *shim.Column) string {
+	switch value := column.GetValue().(type) {
+	case *shim.Column_String_:
+		return fmt.Sprintf("%d:%s", len(value.String_), value.String_)
+	case *shim.Column_Int32:
+		return fmt.Sprintf("i%d", value.Int32)
+	case *shim.Column_Int64:
+		return fmt.Sprintf("i%d", value.Int64)
+	case *shim.Column_Uint32:
+		return fmt.Sprintf("u%d", value.Uint32)
+	case *shim.Column_Uint64:
+		return fmt.Sprintf("u%d", value.Uint64)
+	case *shim.Column_Bytes:
+		return fmt.Sprintf("%d:%x", len(value.Bytes), value.Bytes)
+	case *shim.Column_Bool:
+		return fmt.Sprintf("b%t", value.Bool)
+	}
+	return ""
+}
+
+//compareColumns - Orden de dos columnas del mismo tipo
+func compareColumns(a *shim.Column, b *shim.Column) int {
+	switch value := a.GetValue().(type) {
+	case *shim.Column_String_:
+		return compareStrings(value.String_, b.GetString_())
+	case *shim.Column_Int32:
+		return compareInts(int64(value.Int32), int64(b.GetInt32()))
+	case *shim.Column_Int64:
+		return compareInts(value.Int64, b.GetInt64())
+	case *shim.Column_Uint32:
+		return compareInts(int64(value.Uint32), int64(b.GetUint32()))
+	case *shim.Column_Uint64:
+		if value.Uint64 == b.GetUint64() {
+			return 0
+		} else if value.Uint64 < b.GetUint64() {
+			return -1
+		}
+		return 1
+	case *shim.Column_Bytes:
+		return bytes.Compare(value.Bytes, b.GetBytes())
+	case *shim.Column_Bool:
+		return compareInts(boolInt(value.Bool), boolInt(b.GetBool()))
+	}
+	return 0
+}
+
+func compareStrings(a string, b string) int {
+	if a == b {
+		return 0
+	} else if a < b {
+		return -1
+	}
+	return 1
+}
+
+func compareInts(a int64, b int64) int {
+	if a == b {
+		return 0
+	} else if a < b {
+		return -1
+	}
+	return 1
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+//checkKey - Valida las columnas clave recibidas, partial permite solo las primeras
+func (t *table) checkKey(key []shim.Column, partial bool) error {
+	if len(key) > len(t.keys) || (!partial && len(key) != len(t.keys)) {
+		return fmt.Errorf("La tabla %s tiene %d columnas clave, se recibieron %d", t.name, len(t.keys), len(key))
+	}
+	for i := range key {
+		definition := t.definitions[t.keys[i]]
+		if columnType(&key[i]) != definition.Type {
+			return fmt.Errorf("Tipo invalido para la columna clave %s de la tabla %s", definition.Name, t.name)
+		}
+	}
+	return nil
+}
+
+func encodeKey(key []shim.Column) string {
+	var buffer bytes.Buffer
+	for i := range key {
+		buffer.WriteString(encodeColumn(&key[i]))
+		buffer.WriteString("|")
+	}
+	return buffer.String()
+}
+
+//rowKey - Columnas clave de una fila
+func (t *table) rowKey(row shim.Row) []shim.Column {
+	key := []shim.Column{}
+	for _, i := range t.keys {
+		key = append(key, *row.Columns[i])
+	}
+	return key
+}
+
+//put - Inserta o reemplaza una fila validando columnas y tipos
+func (t *table) put(row shim.Row, replace bool) (bool, error) {
+	if len(row.Columns) != len(t.definitions) {
+		return false, fmt.Errorf("La tabla %s tiene %d columnas, la fila tiene %d", t.name, len(t.definitions), len(row.Columns))
+	}
+	for i, column := range row.Columns {
+		if column == nil || columnType(column) != t.definitions[i].Type {
+			return false, fmt.Errorf("Tipo invalido para la columna %s de la tabla %s", t.definitions[i].Name, t.name)
+		}
+	}
+
+	encoded := encodeKey(t.rowKey(row))
+	_, found := t.rows[encoded]
+	if found != replace {
+		return false, nil
+	}
+
+	stored := shim.Row{}
+	for _, column := range row.Columns {
+		copied := *column
+		stored.Columns = append(stored.Columns, &copied)
+	}
+	t.rows[encoded] = stored
+	return true, nil
+}
+
+//get - Obtiene una fila por su clave completa
+func (t *table) get(key []shim.Column) (shim.Row, error) {
+	if err := t.checkKey(key, false); err != nil {
+		return shim.Row{}, err
+	}
+	row, found := t.rows[encodeKey(key)]
+	if !found {
+		return shim.Row{}, nil
+	}
+	return copyRow(row), nil
+}
+
+//scan - Filas cuya clave empieza con las columnas indicadas, ordenadas por clave
+func (t *table) scan(key []shim.Column) ([]shim.Row, error) {
+	if err := t.checkKey(key, true); err != nil {
+		return nil, err
+	}
+
+	rows := []shim.Row{}
+	for _, row := range t.rows {
+		rowKey := t.rowKey(row)
+		matches := true
+		for i := range key {
+			if compareColumns(&key[i], &rowKey[i]) != 0 {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			rows = append(rows, copyRow(row))
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		a := t.rowKey(rows[i])
+		b := t.rowKey(rows[j])
+		for k := range a {
+			if c := compareColumns(&a[k], &b[k]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return rows, nil
+}
+
+//delete - Borra una fila por su clave completa
+func (t *table) delete(key []shim.Column) error {
+	if err := t.checkKey(key, false); err != nil {
+		return err
+	}
+	delete(t.rows, encodeKey(key))
+	return nil
+}
+
+func copyRow(row shim.Row) shim.Row {
+	copied := shim.Row{}
+	for _, column := range row.Columns {
+		c := *column
+		copied.Columns = append(copied.Columns, &c)
+	}
+	return copied
+}